@@ -55,7 +55,7 @@ func main() {
 		fmt.Printf("  Temperature range: %d°C - %d°C\n", model.MinTemperature, model.MaxTemperature)
 
 		// Lookup "off" command
-		offCode, err := db.LookupOffCode(ctx, modelID)
+		offCode, _, err := db.LookupOffCode(ctx, modelID)
 		if err != nil {
 			log.Printf("  No off code: %v", err)
 		} else {
@@ -63,7 +63,7 @@ func main() {
 		}
 
 		// Lookup a cool mode command
-		code, err := db.LookupCode(ctx, modelID, "cool", 21, "low")
+		code, _, err := db.LookupCode(ctx, modelID, "cool", 21, "low")
 		if err != nil {
 			fmt.Printf("  Cool 21°C (low fan): not available\n")
 		} else {
@@ -80,14 +80,15 @@ func main() {
 		temp := os.Args[3]
 		fan := os.Args[4]
 
-		var tempInt int
-		fmt.Sscanf(temp, "%d", &tempInt)
+		var tempValue float64
+		fmt.Sscanf(temp, "%g", &tempValue)
 
-		fmt.Printf("\nLookup: Model=%s Mode=%s Temp=%d°C Fan=%s\n", modelID, mode, tempInt, fan)
-		code, err := db.LookupCode(ctx, modelID, mode, tempInt, fan)
+		fmt.Printf("\nLookup: Model=%s Mode=%s Temp=%g°C Fan=%s\n", modelID, mode, tempValue, fan)
+		code, result, err := db.LookupCode(ctx, modelID, mode, tempValue, fan)
 		if err != nil {
 			log.Printf("Error: %v", err)
 		} else {
+			fmt.Printf("Matched: %s\n", result)
 			fmt.Printf("IR Code: %s\n", code)
 		}
 	}
@@ -3,7 +3,6 @@ package main
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -12,13 +11,24 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/diogoaguiar/hvac-manager/internal/api"
+	"github.com/diogoaguiar/hvac-manager/internal/audit"
 	"github.com/diogoaguiar/hvac-manager/internal/database"
-	"github.com/diogoaguiar/hvac-manager/internal/homeassistant"
+	"github.com/diogoaguiar/hvac-manager/internal/device"
+	"github.com/diogoaguiar/hvac-manager/internal/health"
 	"github.com/diogoaguiar/hvac-manager/internal/integration"
 	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/metrics"
 	"github.com/diogoaguiar/hvac-manager/internal/mqtt"
-	"github.com/diogoaguiar/hvac-manager/internal/state"
+	"github.com/diogoaguiar/hvac-manager/internal/preset"
+	"github.com/diogoaguiar/hvac-manager/internal/reload"
+	"github.com/diogoaguiar/hvac-manager/internal/schedule"
+	"github.com/diogoaguiar/hvac-manager/internal/sensor"
+	"github.com/diogoaguiar/hvac-manager/internal/thermostat"
+	"github.com/diogoaguiar/hvac-manager/internal/timer"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
 )
 
 const (
@@ -27,15 +37,16 @@ const (
 )
 
 // loadEnv loads environment variables from .env file if it exists
-func loadEnv() {
+// Returns the loaded keys for printing once the logger is configured
+func loadEnv() []string {
 	file, err := os.Open(".env")
 	if err != nil {
 		// .env file is optional, so don't error if it doesn't exist
-		return
+		return nil
 	}
 	defer file.Close()
 
-	fmt.Println("📄 Loading .env file...")
+	var loaded []string
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -66,26 +77,36 @@ func loadEnv() {
 		// Set environment variable (overwrite if from .env)
 		os.Setenv(key, value)
 		if key == "MQTT_PASSWORD" {
-			fmt.Printf("   ✓ %s=***\n", key)
+			loaded = append(loaded, fmt.Sprintf("%s=***", key))
 		} else {
-			fmt.Printf("   ✓ %s=%s\n", key, value)
+			loaded = append(loaded, fmt.Sprintf("%s=%s", key, value))
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		logger.Warn("Error reading .env file: %v", err)
 	}
+	return loaded
 }
 
 func main() {
 	// Load .env file if it exists
-	loadEnv()
+	loadedEnv := loadEnv()
 
-	// Initialize logger from environment (LOG_LEVEL)
+	// Initialize logger from environment (LOG_LEVEL, LOG_FORMAT)
 	logger.InitFromEnv()
 
-	fmt.Println("🌡️  HVAC Manager - E2E POC")
-	fmt.Println("=" + string(make([]byte, 50)) + "=")
+	// Console banners only in human-readable mode; JSON output must stay one object per line
+	if logger.IsText() {
+		if loadedEnv != nil {
+			fmt.Println("📄 Loaded .env file:")
+			for _, entry := range loadedEnv {
+				fmt.Printf("   ✓ %s\n", entry)
+			}
+		}
+		fmt.Println("🌡️  HVAC Manager - E2E POC")
+		fmt.Println("=" + string(make([]byte, 50)) + "=")
+	}
 
 	// Configuration from environment or defaults
 	broker := getEnv("MQTT_BROKER", defaultBroker)
@@ -100,6 +121,16 @@ func main() {
 	modelID := getEnv("AC_MODEL_ID", "1109")
 	irBlasterID := getEnv("IR_BLASTER_ID", "ir-blaster")
 
+	// Topic configuration
+	topicBuilder := topics.New(
+		getEnv("MQTT_DISCOVERY_PREFIX", topics.DefaultDiscoveryPrefix),
+		getEnv("Z2M_BASE_TOPIC", topics.DefaultZ2MBaseTopic),
+		getEnv("MQTT_BASE_TOPIC", topics.DefaultBaseTopic),
+	)
+
+	// Optional room sensor for current_temperature/current_humidity
+	sensorConfig := loadSensorConfig(topicBuilder)
+
 	// Initialize database
 	logger.Info("📦 Initializing IR code database...")
 	db, err := database.New(dbPath)
@@ -115,18 +146,69 @@ func main() {
 	}
 
 	// Load SmartIR IR codes for configured model
-	smartirFile := filepath.Join("docs", "smartir", "reference", fmt.Sprintf("%s_tuya.json", modelID))
+	codesDir := getEnv("CODES_DIR", filepath.Join("docs", "smartir", "reference"))
+	smartirFile, err := database.ModelFile(codesDir, modelID)
+	if err != nil {
+		log.Fatalf("Failed to find IR codes: %v", err)
+	}
 	if err := db.LoadFromJSON(ctx, modelID, smartirFile); err != nil {
 		log.Fatalf("Failed to load IR codes from %s: %v", smartirFile, err)
 	}
 	logger.Info("✅ Database ready with model: %s", modelID)
 
+	// IR code fallback policies per model (built-in order unless a policy file is given)
+	if policyFile := getEnv("FALLBACK_POLICY_FILE", ""); policyFile != "" {
+		policies, err := database.LoadFallbackPolicies(policyFile)
+		if err != nil {
+			log.Fatalf("Invalid fallback policies: %v", err)
+		}
+		for model, policy := range policies {
+			db.SetFallbackPolicy(model, policy)
+		}
+		logger.Info("↪️  Loaded %d fallback policies from %s", len(policies), policyFile)
+	}
+
+	// Serve lookups from memory; the cache follows model reloads
+	codes := database.NewCache(db)
+	if err := codes.Preload(ctx, modelID); err != nil {
+		log.Fatalf("Failed to preload IR codes: %v", err)
+	}
+
+	// On code before the state code when the AC turns on (optional)
+	powerOn, err := loadPowerOnConfig()
+	if err != nil {
+		log.Fatalf("Invalid power-on configuration: %v", err)
+	}
+	if powerOn.Enabled {
+		if _, _, err := codes.LookupOnCode(ctx, modelID); err != nil {
+			log.Fatalf("POWER_ON_SEQUENCE requires an on code in model %s: %v", modelID, err)
+		}
+		logger.Info("🔌 Power-on sequence enabled (on code, then %s)", powerOn.Delay)
+	}
+
+	// Repeats, delays and extra codes of each transmission (optional)
+	var sequence integration.Sequence
+	if sequenceFile := getEnv("IR_SEQUENCE_FILE", ""); sequenceFile != "" {
+		sequences, err := integration.LoadSequences(sequenceFile)
+		if err != nil {
+			log.Fatalf("Invalid IR sequences: %v", err)
+		}
+		sequence = integration.SequenceFor(sequences, modelID)
+		logger.Info("🔁 IR sequence for model %s: state code x%d, %d extra codes", modelID, max(sequence.Repeat, 1), len(sequence.After))
+	}
+
 	// Create MQTT client
 	mqttConfig := mqtt.Config{
 		Broker:   broker,
 		ClientID: fmt.Sprintf("hvac-manager-%s", deviceID),
 		Username: username,
 		Password: password,
+
+		CACertFile:         getEnv("MQTT_TLS_CA", ""),
+		ClientCertFile:     getEnv("MQTT_TLS_CERT", ""),
+		ClientKeyFile:      getEnv("MQTT_TLS_KEY", ""),
+		ServerName:         getEnv("MQTT_TLS_SERVER_NAME", ""),
+		InsecureSkipVerify: getEnv("MQTT_TLS_INSECURE", "false") == "true",
 	}
 
 	client, err := mqtt.NewClient(mqttConfig)
@@ -140,257 +222,287 @@ func main() {
 	}
 	defer client.Disconnect()
 
-	// Initialize AC state
-	acState := state.NewACState()
-	logger.Info("Initial state: %s", acState.String())
+	// Closed-loop control using the room sensor (optional)
+	thermostatConfig, err := loadThermostatConfig()
+	if err != nil {
+		log.Fatalf("Invalid thermostat configuration: %v", err)
+	}
+	if thermostatConfig.Enabled() && !sensorConfig.Enabled() {
+		log.Fatalf("THERMOSTAT_STRATEGY=%s requires a room sensor (ROOM_SENSOR_Z2M or ROOM_SENSOR_TOPIC)", thermostatConfig.Strategy)
+	}
+
+	// HA preset modes (disabled unless a presets file is given)
+	var presets []preset.Preset
+	if presetsFile := getEnv("PRESETS_FILE", ""); presetsFile != "" {
+		if presets, err = preset.Load(presetsFile); err != nil {
+			log.Fatalf("Invalid presets: %v", err)
+		}
+		logger.Info("🎚️  Loaded %d presets from %s", len(presets), presetsFile)
+	}
+
+	// Initialize device (owns the AC state and the command path)
+	dev := device.New(device.Config{
+		ID:         deviceID,
+		Name:       getEnv("DEVICE_NAME", "Living Room AC"),
+		ModelID:    modelID,
+		BlasterID:  irBlasterID,
+		Sensor:     sensorConfig,
+		Thermostat: thermostatConfig,
+		Presets:    presets,
+		PowerOn:    powerOn,
+		Sequence:   sequence,
+		CommandLog: db,
+	}, codes, client, topicBuilder)
+	initialState := dev.State()
+	logger.Info("Initial state: %s", initialState.String())
 
 	// Publish Home Assistant MQTT Discovery
-	if err := publishDiscovery(client, deviceID); err != nil {
+	if err := dev.PublishDiscovery(); err != nil {
 		log.Fatalf("Failed to publish discovery: %v", err)
 	}
 
 	// Publish availability (online)
-	availTopic := fmt.Sprintf("homeassistant/climate/%s/availability", deviceID)
-	if err := client.Publish(availTopic, 1, true, "online"); err != nil {
+	if err := dev.PublishAvailability(true); err != nil {
 		logger.Warn("Failed to publish availability: %v", err)
 	}
 
 	// Publish initial state
-	if err := publishState(client, deviceID, acState); err != nil {
+	if err := dev.PublishState(); err != nil {
 		logger.Warn("Failed to publish initial state: %v", err)
 	}
 
+	// Cancelled on shutdown: stops the control loop, scheduler and timers, and interrupts IR sequences
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Subscribe to command topic
-	cmdTopic := fmt.Sprintf("homeassistant/climate/%s/set", deviceID)
+	cmdTopic := topicBuilder.Command(deviceID)
 	if err := client.Subscribe(cmdTopic, 1, func(topic string, payload []byte) {
-		handleCommand(client, db, modelID, irBlasterID, deviceID, acState, payload)
+		dev.HandleCommand(runCtx, payload)
 	}); err != nil {
 		log.Fatalf("Failed to subscribe to command topic: %v", err)
 	}
 
-	fmt.Println("\n✅ Phase 4 Integration Active!")
-	fmt.Printf("   📡 MQTT Broker: %s\n", broker)
-	fmt.Printf("   🏠 HA Device ID: %s\n", deviceID)
-	fmt.Printf("   🎛️  AC Model: %s\n", modelID)
-	fmt.Printf("   📡 IR Blaster: %s\n", irBlasterID)
-	fmt.Printf("   📥 Listening on: %s\n", cmdTopic)
-	fmt.Printf("   📤 State topic: homeassistant/climate/%s/state\n", deviceID)
-	fmt.Println("📡 IR codes will be transmitted via Zigbee2MQTT")
-	fmt.Println("   Press Ctrl+C to stop")
-
-	// Wait for interrupt signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
-
-	logger.Info("\n🛑 Shutting down...")
-	// Publish offline status
-	if err := client.Publish(availTopic, 1, true, "offline"); err != nil {
-		logger.Warn("Failed to publish offline status: %v", err)
+	// Subscribe to room sensor topics
+	for _, sensorTopic := range sensorConfig.Topics() {
+		if err := client.Subscribe(sensorTopic, 0, func(topic string, payload []byte) {
+			dev.HandleSensorReading(topic, payload)
+		}); err != nil {
+			log.Fatalf("Failed to subscribe to room sensor topic: %v", err)
+		}
 	}
-}
 
-// publishDiscovery publishes the Home Assistant MQTT Discovery payload
-func publishDiscovery(client *mqtt.Client, deviceID string) error {
-	discovery := homeassistant.NewClimateDiscovery(deviceID, "Living Room AC")
-	payload, err := discovery.ToJSON()
-	if err != nil {
-		return fmt.Errorf("failed to marshal discovery: %w", err)
+	// Track blaster availability (reported in the HTTP API)
+	if err := client.Subscribe(dev.BlasterAvailabilityTopic(), 0, func(topic string, payload []byte) {
+		dev.HandleBlasterAvailability(payload)
+	}); err != nil {
+		logger.Warn("Failed to subscribe to blaster availability: %v", err)
 	}
 
-	topic := discovery.ConfigTopic(deviceID)
-	if err := client.Publish(topic, 2, true, payload); err != nil {
-		return fmt.Errorf("failed to publish discovery: %w", err)
+	// Weekly schedules (stored in the database, edited over MQTT)
+	scheduler := schedule.New(db, client, topicBuilder, dev)
+	if err := scheduler.Publish(ctx, deviceID); err != nil {
+		logger.Warn("Failed to publish schedule: %v", err)
 	}
-
-	logger.Info("✅ Published discovery to: %s", topic)
-	return nil
-}
-
-// publishState publishes the current AC state to Home Assistant
-func publishState(client *mqtt.Client, deviceID string, acState *state.ACState) error {
-	haState := &homeassistant.ClimateState{
-		Temperature: acState.Temperature,
-		Mode:        acState.Mode,
-		FanMode:     acState.FanMode,
+	scheduleTopic := topicBuilder.ScheduleSet(deviceID)
+	if err := client.Subscribe(scheduleTopic, 1, func(topic string, payload []byte) {
+		scheduler.HandleEdit(deviceID, payload)
+	}); err != nil {
+		log.Fatalf("Failed to subscribe to schedule topic: %v", err)
 	}
 
-	payload, err := homeassistant.StateToJSON(haState)
-	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
+	// Sleep/off-delay timer (persisted, exposed as an HA number entity)
+	timers := timer.New(db, client, topicBuilder, dev)
+	if err := timers.PublishDiscovery(deviceID, dev.Config().Name); err != nil {
+		logger.Warn("Failed to publish timer discovery: %v", err)
+	}
+	if err := timers.Restore(ctx); err != nil {
+		logger.Warn("Failed to restore timers: %v", err)
+	}
+	timerTopic := topicBuilder.TimerSet(deviceID)
+	if err := client.Subscribe(timerTopic, 1, func(topic string, payload []byte) {
+		timers.HandleRequest(deviceID, payload)
+	}); err != nil {
+		log.Fatalf("Failed to subscribe to timer topic: %v", err)
 	}
 
-	topic := fmt.Sprintf("homeassistant/climate/%s/state", deviceID)
-
-	// Log what we're about to publish
-	logger.Info("📤 Publishing HA state to: %s", topic)
-	logger.Info("   JSON: %s", string(payload))
+	// Start the control loop (no-op unless a thermostat strategy is set), the scheduler and timers
+	go dev.Run(runCtx)
+	go scheduler.Run(runCtx, schedule.DefaultInterval)
+	go timers.Run(runCtx, timer.DefaultInterval)
 
-	if err := client.Publish(topic, 0, true, payload); err != nil {
-		return fmt.Errorf("failed to publish state: %w", err)
+	// Command audit log retention (AUDIT_RETENTION=0 keeps records forever)
+	retention, err := time.ParseDuration(getEnv("AUDIT_RETENTION", audit.DefaultRetention.String()))
+	if err != nil || retention < 0 {
+		log.Fatalf("Invalid AUDIT_RETENTION: %q", os.Getenv("AUDIT_RETENTION"))
 	}
+	go audit.RunPruner(runCtx, db, retention, audit.DefaultPruneInterval)
 
-	return nil
-}
-
-// handleCommand processes commands received from Home Assistant
-func handleCommand(client *mqtt.Client, db *database.DB, modelID, irBlasterID, deviceID string, acState *state.ACState, payload []byte) {
-	fmt.Println("\n" + strings.Repeat("─", 60))
-	logger.Info("📥 Received command: %s", string(payload))
+	// Re-import SmartIR files edited in CODES_DIR (CODES_RELOAD_INTERVAL=0 disables it)
+	reloadInterval, err := time.ParseDuration(getEnv("CODES_RELOAD_INTERVAL", reload.DefaultInterval.String()))
+	if err != nil || reloadInterval < 0 {
+		log.Fatalf("Invalid CODES_RELOAD_INTERVAL: %q", os.Getenv("CODES_RELOAD_INTERVAL"))
+	}
+	if reloadInterval > 0 {
+		watcher := reload.New(db, client, topicBuilder, codesDir)
+		go watcher.Run(runCtx, reloadInterval)
+		logger.Info("👀 Watching %s for IR code changes every %s", codesDir, reloadInterval)
+	}
 
-	// Try to parse as JSON first
-	cmd, err := homeassistant.ParseCommand(payload)
-	if err != nil {
-		// If JSON parsing fails, treat as plain text (temperature or mode value)
-		payloadStr := string(payload)
-		logger.Debug("📋 Plain text command: %s", payloadStr)
-
-		// Try to parse as temperature (numeric)
-		if temp, err := strconv.ParseFloat(payloadStr, 64); err == nil {
-			// Save original state before modification
-			originalTemp := acState.Temperature
-
-			if err := acState.SetTemperature(temp); err != nil {
-				logger.Error("Invalid temperature: %v", err)
-				return
-			}
-			logger.Info("🌡️  Temperature set to: %.1f°C", temp)
-
-			// Try to send IR code
-			ctx := context.Background()
-			if err := integration.SendIRCode(ctx, db, client, modelID, irBlasterID, acState); err != nil {
-				logger.Error("❌ Failed to send IR code: %v", err)
-				// Revert to original state on failure
-				acState.Temperature = originalTemp
-				logger.Warn("⏪ Reverted temperature to: %.1f°C", originalTemp)
-			} else {
-				logger.Info("✅ IR code sent successfully")
+	// Embedded HTTP API, off unless HTTP_ADDR is set: it has no authentication
+	httpAddr := getEnv("HTTP_ADDR", "")
+	maxCommandAge, err := time.ParseDuration(getEnv("READY_MAX_COMMAND_AGE", "0"))
+	if err != nil || maxCommandAge < 0 {
+		log.Fatalf("Invalid READY_MAX_COMMAND_AGE: %q", os.Getenv("READY_MAX_COMMAND_AGE"))
+	}
+	if httpAddr != "" {
+		server := api.New(api.Config{
+			Devices:   []*device.Device{dev},
+			Codes:     db,
+			Schedules: scheduler,
+			Timers:    timers,
+		})
+
+		// Liveness and readiness probes for Docker/Kubernetes
+		checker := health.New(health.Config{
+			DB:            db,
+			MQTT:          client,
+			Devices:       []health.Device{dev},
+			MaxCommandAge: maxCommandAge,
+		})
+		server.Handle("GET /healthz", checker.LivenessHandler())
+		server.Handle("GET /readyz", checker.ReadinessHandler())
+		server.Handle("GET /metrics", metrics.Handler())
+
+		go func() {
+			if err := server.ListenAndServe(runCtx, httpAddr); err != nil {
+				logger.Error("❌ %v", err)
 			}
+		}()
+	}
 
-			// Always publish actual state (new if success, reverted if failure)
-			if err := publishState(client, deviceID, acState); err != nil {
-				logger.Error("Failed to publish state: %v", err)
-			}
-			fmt.Println(strings.Repeat("─", 60))
-			return
+	if logger.IsText() {
+		fmt.Println("\n✅ Phase 4 Integration Active!")
+		fmt.Printf("   📡 MQTT Broker: %s\n", broker)
+		fmt.Printf("   🏠 HA Device ID: %s\n", deviceID)
+		fmt.Printf("   🎛️  AC Model: %s\n", modelID)
+		fmt.Printf("   📡 IR Blaster: %s\n", irBlasterID)
+		fmt.Printf("   📥 Listening on: %s\n", cmdTopic)
+		fmt.Printf("   📤 State topic: %s\n", topicBuilder.State(deviceID))
+		fmt.Printf("   📅 Schedule topic: %s\n", scheduleTopic)
+		fmt.Printf("   ⏲️  Timer topic: %s\n", timerTopic)
+		fmt.Printf("   📡 IR topic: %s\n", dev.IRTopic())
+		if httpAddr != "" {
+			fmt.Printf("   🌐 HTTP API: %s\n", httpAddr)
 		}
-
-		// Otherwise treat as mode or fan mode
-		originalMode := acState.Mode
-		if err := acState.SetMode(payloadStr); err == nil {
-			logger.Info("🔄 Mode set to: %s", payloadStr)
-
-			// Try to send IR code
-			ctx := context.Background()
-			if err := integration.SendIRCode(ctx, db, client, modelID, irBlasterID, acState); err != nil {
-				logger.Error("❌ Failed to send IR code: %v", err)
-				// Revert to original state on failure
-				acState.Mode = originalMode
-				logger.Warn("⏪ Reverted mode to: %s", originalMode)
-			} else {
-				logger.Info("✅ IR code sent successfully")
-			}
-
-			// Always publish actual state (new if success, reverted if failure)
-			if err := publishState(client, deviceID, acState); err != nil {
-				logger.Error("Failed to publish state: %v", err)
-			}
-			fmt.Println(strings.Repeat("─", 60))
-			return
+		if sensorConfig.Enabled() {
+			fmt.Printf("   🌡️  Room sensor: %s\n", strings.Join(sensorConfig.Topics(), ", "))
 		}
-
-		originalFan := acState.FanMode
-		if err := acState.SetFanMode(payloadStr); err == nil {
-			logger.Info("💨 Fan mode set to: %s", payloadStr)
-
-			// Try to send IR code
-			ctx := context.Background()
-			if err := integration.SendIRCode(ctx, db, client, modelID, irBlasterID, acState); err != nil {
-				logger.Error("❌ Failed to send IR code: %v", err)
-				// Revert to original state on failure
-				acState.FanMode = originalFan
-				logger.Warn("⏪ Reverted fan mode to: %s", originalFan)
-			} else {
-				logger.Info("✅ IR code sent successfully")
-			}
-
-			// Always publish actual state (new if success, reverted if failure)
-			if err := publishState(client, deviceID, acState); err != nil {
-				logger.Error("Failed to publish state: %v", err)
-			}
-			fmt.Println(strings.Repeat("─", 60))
-			return
+		if thermostatConfig.Enabled() {
+			fmt.Printf("   🎛️  Thermostat: %s (±%.1f°C)\n", thermostatConfig.Strategy, thermostatConfig.Hysteresis)
 		}
+		fmt.Println("📡 IR codes will be transmitted via Zigbee2MQTT")
+		fmt.Println("   Press Ctrl+C to stop")
+	} else {
+		logger.With(logger.FieldDeviceID, deviceID, logger.FieldModelID, modelID, logger.FieldTopic, cmdTopic).
+			Info("Service started (broker %s, blaster %s)", broker, irBlasterID)
+	}
 
-		logger.Error("Could not parse command as JSON or plain text: %s", payloadStr)
-		return
+	// Wait for interrupt signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+
+	logger.Info("\n🛑 Shutting down...")
+	cancel()
+	// Publish offline status
+	if err := dev.PublishAvailability(false); err != nil {
+		logger.Warn("Failed to publish offline status: %v", err)
 	}
+}
 
-	// Pretty print the command for visibility
-	cmdJSON, _ := json.MarshalIndent(cmd, "", "  ")
-	logger.Debug("📋 Parsed command:\n%s", string(cmdJSON))
+// loadSensorConfig reads the room sensor binding from the environment
+// ROOM_SENSOR_Z2M binds a Zigbee2MQTT sensor by friendly name (JSON payload),
+// ROOM_SENSOR_TOPIC binds any topic (plain numeric payload unless a path is set)
+func loadSensorConfig(topicBuilder topics.Builder) sensor.Config {
+	cfg := sensor.Config{
+		TemperatureTopic: getEnv("ROOM_SENSOR_TOPIC", ""),
+		TemperaturePath:  getEnv("ROOM_SENSOR_TEMPERATURE_PATH", ""),
+		HumidityTopic:    getEnv("ROOM_SENSOR_HUMIDITY_TOPIC", ""),
+		HumidityPath:     getEnv("ROOM_SENSOR_HUMIDITY_PATH", ""),
+	}
 
-	// Save original state before any modifications
-	originalState := state.ACState{
-		Temperature: acState.Temperature,
-		Mode:        acState.Mode,
-		FanMode:     acState.FanMode,
+	// Z2M sensors publish JSON; humidity is only read with ROOM_SENSOR_HUMIDITY_PATH,
+	// since many sensors report temperature alone
+	if z2mSensor := getEnv("ROOM_SENSOR_Z2M", ""); z2mSensor != "" {
+		cfg.TemperatureTopic = topicBuilder.Z2MDevice(z2mSensor)
+		cfg.TemperaturePath = getEnv("ROOM_SENSOR_TEMPERATURE_PATH", "temperature")
 	}
 
-	// Apply changes to state
-	stateChanged := false
+	return cfg
+}
 
-	if cmd.Temperature != nil {
-		if err := acState.SetTemperature(*cmd.Temperature); err != nil {
-			logger.Error("Invalid temperature: %v", err)
-			return
+// loadPowerOnConfig reads the power-on sequence settings from the environment
+func loadPowerOnConfig() (integration.PowerOn, error) {
+	cfg := integration.PowerOn{
+		Enabled: getEnv("POWER_ON_SEQUENCE", "false") == "true",
+		Delay:   integration.DefaultPowerOnDelay,
+	}
+	if value := getEnv("POWER_ON_DELAY", ""); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("invalid POWER_ON_DELAY: %q", value)
 		}
-		stateChanged = true
-		logger.Info("🌡️  Temperature set to: %.1f°C", *cmd.Temperature)
+		cfg.Delay = d
 	}
+	return cfg, nil
+}
 
-	if cmd.Mode != nil {
-		if err := acState.SetMode(*cmd.Mode); err != nil {
-			logger.Error("Invalid mode: %v", err)
-			return
-		}
-		stateChanged = true
-		logger.Info("🔄 Mode set to: %s", *cmd.Mode)
+// loadThermostatConfig reads the closed-loop control settings from the environment
+func loadThermostatConfig() (thermostat.Config, error) {
+	cfg := thermostat.DefaultConfig()
+
+	strategy, err := thermostat.ParseStrategy(getEnv("THERMOSTAT_STRATEGY", "off"))
+	if err != nil {
+		return cfg, err
 	}
+	cfg.Strategy = strategy
 
-	if cmd.FanMode != nil {
-		if err := acState.SetFanMode(*cmd.FanMode); err != nil {
-			logger.Error("Invalid fan mode: %v", err)
-			return
+	floats := map[string]*float64{
+		"THERMOSTAT_HYSTERESIS": &cfg.Hysteresis,
+		"THERMOSTAT_MAX_OFFSET": &cfg.MaxOffset,
+	}
+	for key, target := range floats {
+		if value := getEnv(key, ""); value != "" {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil || f < 0 {
+				return cfg, fmt.Errorf("invalid %s: %q", key, value)
+			}
+			*target = f
 		}
-		stateChanged = true
-		logger.Info("💨 Fan mode set to: %s", *cmd.FanMode)
 	}
 
-	if !stateChanged {
-		logger.Warn("⚠️  No valid state changes in command")
-		return
+	durations := map[string]*time.Duration{
+		"THERMOSTAT_MIN_ON_TIME":     &cfg.MinOnTime,
+		"THERMOSTAT_MIN_OFF_TIME":    &cfg.MinOffTime,
+		"THERMOSTAT_ADJUST_INTERVAL": &cfg.AdjustInterval,
+		"THERMOSTAT_INTERVAL":        &cfg.Interval,
+		"THERMOSTAT_MAX_SENSOR_AGE":  &cfg.MaxSensorAge,
 	}
-
-	// Try to send IR code to IR blaster
-	ctx := context.Background()
-	if err := integration.SendIRCode(ctx, db, client, modelID, irBlasterID, acState); err != nil {
-		logger.Error("❌ Failed to send IR code: %v", err)
-		// Revert to original state on failure
-		acState.Temperature = originalState.Temperature
-		acState.Mode = originalState.Mode
-		acState.FanMode = originalState.FanMode
-		logger.Warn("⏪ Reverted to original state: %s", originalState.String())
-	} else {
-		logger.Info("✅ IR code sent successfully")
+	for key, target := range durations {
+		if value := getEnv(key, ""); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return cfg, fmt.Errorf("invalid %s: %q", key, value)
+			}
+			*target = d
+		}
 	}
-
-	// Always publish actual state (new if success, reverted if failure)
-	if err := publishState(client, deviceID, acState); err != nil {
-		logger.Error("Failed to publish state: %v", err)
+	if cfg.Interval <= 0 {
+		return cfg, fmt.Errorf("THERMOSTAT_INTERVAL must be positive")
 	}
 
-	fmt.Println(strings.Repeat("─", 60))
+	return cfg, nil
 }
 
 // getEnv retrieves an environment variable or returns a default value
@@ -0,0 +1,427 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/audit"
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/device"
+	"github.com/diogoaguiar/hvac-manager/internal/homeassistant"
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/schedule"
+	"github.com/diogoaguiar/hvac-manager/internal/timer"
+)
+
+// CodeStore provides model metadata and IR codes (implemented by *database.DB)
+type CodeStore interface {
+	GetModel(ctx context.Context, modelID string) (*database.Model, error)
+	ListCodes(ctx context.Context, modelID string) ([]database.IRCode, error)
+}
+
+// Config holds the dependencies of the HTTP API
+// Schedules and Timers are optional; their endpoints are only registered when set
+type Config struct {
+	Devices   []*device.Device
+	Codes     CodeStore
+	Schedules *schedule.Scheduler
+	Timers    *timer.Manager
+}
+
+// Server is the embedded HTTP API
+// Commands go through device.Apply, the same path as MQTT commands
+type Server struct {
+	cfg     Config
+	devices map[string]*device.Device
+	mux     *http.ServeMux
+	run     context.Context // Cancelled on shutdown (see ListenAndServe)
+}
+
+// New creates the HTTP API and registers its routes
+func New(cfg Config) *Server {
+	s := &Server{
+		cfg:     cfg,
+		devices: make(map[string]*device.Device),
+		mux:     http.NewServeMux(),
+		run:     context.Background(),
+	}
+	for _, d := range cfg.Devices {
+		s.devices[d.ID()] = d
+	}
+
+	s.mux.HandleFunc("GET /devices", s.listDevices)
+	s.mux.HandleFunc("GET /devices/{id}", s.getDevice)
+	s.mux.HandleFunc("POST /devices/{id}/state", s.setState)
+	s.mux.HandleFunc("GET /models/{id}/codes", s.listCodes)
+
+	if cfg.Schedules != nil {
+		s.mux.HandleFunc("GET /devices/{id}/schedule", s.getSchedule)
+		s.mux.HandleFunc("POST /devices/{id}/schedule", s.editSchedule)
+		s.mux.HandleFunc("DELETE /devices/{id}/schedule/{slot}", s.deleteSchedule)
+	}
+	if cfg.Timers != nil {
+		s.mux.HandleFunc("GET /devices/{id}/timer", s.getTimer)
+		s.mux.HandleFunc("POST /devices/{id}/timer", s.startTimer)
+		s.mux.HandleFunc("DELETE /devices/{id}/timer", s.cancelTimer)
+	}
+
+	return s
+}
+
+// Handle registers an additional handler (e.g., health checks, metrics)
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Handler returns the HTTP handler with request logging
+// Each request gets a correlation ID (X-Request-ID if the client sent one),
+// echoed in the response and attached to every log line of the request
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = logger.NewCorrelationID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		r = r.WithContext(logger.WithContext(r.Context(), logger.FieldCorrelationID, requestID))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		s.mux.ServeHTTP(rec, r)
+		logger.FromContext(r.Context()).With(logger.FieldDuration, time.Since(start)).
+			Debug("🌐 %s %s → %d", r.Method, r.URL.Path, rec.status)
+	})
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled
+// Cancelling ctx also interrupts the IR sequences of commands in progress.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	s.run = ctx
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("HTTP server failed: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// listDevices handles GET /devices
+func (s *Server) listDevices(w http.ResponseWriter, r *http.Request) {
+	statuses := make([]device.Status, 0, len(s.cfg.Devices))
+	for _, d := range s.cfg.Devices {
+		statuses = append(statuses, d.Status())
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// getDevice handles GET /devices/{id}
+func (s *Server) getDevice(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.device(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, d.Status())
+}
+
+// setState handles POST /devices/{id}/state with a ClimateCommand body
+func (s *Server) setState(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.device(w, r)
+	if !ok {
+		return
+	}
+
+	// A client that disconnects or times out does not interrupt the command, only shutdown does
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+	stop := context.AfterFunc(s.run, cancel)
+	defer stop()
+	ctx = audit.WithSource(ctx, audit.SourceHTTP)
+	logger.FromContext(ctx).Info("🌐 HTTP command for %s", d.ID())
+
+	// Commands refused here never reach Apply, so they are recorded with the raw body
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		err = fmt.Errorf("failed to read body: %w", err)
+		d.Reject(ctx, body, err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var cmd homeassistant.ClimateCommand
+	if err := unmarshalStrict(body, &cmd); err != nil {
+		err = fmt.Errorf("invalid JSON body: %w", err)
+		d.Reject(ctx, body, err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if cmd.Empty() {
+		d.Reject(ctx, body, homeassistant.ErrEmptyCommand)
+		writeError(w, http.StatusBadRequest, homeassistant.ErrEmptyCommand.Error())
+		return
+	}
+
+	if err := d.Apply(ctx, &cmd); err != nil {
+		status := http.StatusBadGateway // IR lookup or send failed
+		if errors.Is(err, device.ErrInvalidCommand) {
+			status = http.StatusBadRequest
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, d.Status())
+}
+
+// codeResponse is the JSON form of a stored IR code
+type codeResponse struct {
+	ID          int      `json:"id"`
+	Mode        string   `json:"mode"`
+	Temperature *float64 `json:"temperature"`
+	FanSpeed    *string  `json:"fan_speed"`
+	Swing       *string  `json:"swing,omitempty"`
+	IRCode      string   `json:"ir_code"`
+}
+
+// listCodes handles GET /models/{id}/codes
+func (s *Server) listCodes(w http.ResponseWriter, r *http.Request) {
+	modelID := r.PathValue("id")
+
+	model, err := s.cfg.Codes.GetModel(r.Context(), modelID)
+	if err != nil {
+		if errors.Is(err, database.ErrModelNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	codes, err := s.cfg.Codes.ListCodes(r.Context(), modelID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := struct {
+		ModelID        string         `json:"model_id"`
+		Manufacturer   string         `json:"manufacturer"`
+		MinTemperature int            `json:"min_temperature"`
+		MaxTemperature int            `json:"max_temperature"`
+		Precision      float64        `json:"precision"`
+		Codes          []codeResponse `json:"codes"`
+	}{
+		ModelID:        model.ModelID,
+		Manufacturer:   model.Manufacturer,
+		MinTemperature: model.MinTemperature,
+		MaxTemperature: model.MaxTemperature,
+		Precision:      model.Precision,
+		Codes:          make([]codeResponse, 0, len(codes)),
+	}
+	for _, c := range codes {
+		resp.Codes = append(resp.Codes, codeResponse{
+			ID:          c.ID,
+			Mode:        c.Mode,
+			Temperature: c.Temperature,
+			FanSpeed:    c.FanSpeed,
+			Swing:       c.Swing,
+			IRCode:      c.IRCode,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// getSchedule handles GET /devices/{id}/schedule
+func (s *Server) getSchedule(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.device(w, r)
+	if !ok {
+		return
+	}
+
+	slots, err := s.cfg.Schedules.List(r.Context(), d.ID())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, slots)
+}
+
+// editSchedule handles POST /devices/{id}/schedule (add or update a slot)
+func (s *Server) editSchedule(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.device(w, r)
+	if !ok {
+		return
+	}
+
+	edit := schedule.Edit{Schedule: database.Schedule{Enabled: true}}
+	if !decodeJSON(w, r, &edit) {
+		return
+	}
+
+	slot, err := s.cfg.Schedules.ApplyEdit(r.Context(), d.ID(), edit)
+	if err != nil {
+		writeError(w, scheduleErrorStatus(err), err.Error())
+		return
+	}
+	if slot == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, slot)
+}
+
+// deleteSchedule handles DELETE /devices/{id}/schedule/{slot}
+func (s *Server) deleteSchedule(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.device(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("slot"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid slot id: %s", r.PathValue("slot")))
+		return
+	}
+
+	edit := schedule.Edit{Schedule: database.Schedule{ID: id}, Delete: true}
+	if _, err := s.cfg.Schedules.ApplyEdit(r.Context(), d.ID(), edit); err != nil {
+		writeError(w, scheduleErrorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scheduleErrorStatus maps a schedule.Scheduler.ApplyEdit error to an HTTP status
+func scheduleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, database.ErrScheduleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, schedule.ErrInvalidEdit):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// getTimer handles GET /devices/{id}/timer
+func (s *Server) getTimer(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.device(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.cfg.Timers.Status(d.ID(), time.Now()))
+}
+
+// startTimer handles POST /devices/{id}/timer with a timer.Request body
+func (s *Server) startTimer(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.device(w, r)
+	if !ok {
+		return
+	}
+
+	var req timer.Request
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	var err error
+	if req.Cancel || req.Minutes == 0 {
+		err = s.cfg.Timers.Cancel(r.Context(), d.ID())
+	} else {
+		_, err = s.cfg.Timers.Start(r.Context(), d.ID(), req, time.Now())
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s.cfg.Timers.Status(d.ID(), time.Now()))
+}
+
+// cancelTimer handles DELETE /devices/{id}/timer
+func (s *Server) cancelTimer(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.device(w, r)
+	if !ok {
+		return
+	}
+	if err := s.cfg.Timers.Cancel(r.Context(), d.ID()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// device resolves the {id} path value, answering 404 if unknown
+func (s *Server) device(w http.ResponseWriter, r *http.Request) (*device.Device, bool) {
+	id := r.PathValue("id")
+	d, ok := s.devices[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown device: %s", id))
+	}
+	return d, ok
+}
+
+// maxBodySize is the largest request body accepted
+const maxBodySize = 64 << 10
+
+// decodeJSON decodes the request body into v, answering 400 on failure
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %v", err))
+		return false
+	}
+	return true
+}
+
+// unmarshalStrict decodes a request body already read, rejecting unknown fields like decodeJSON
+func unmarshalStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("Failed to write HTTP response: %v", err)
+	}
+}
+
+// writeError writes {"error": msg}
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// statusRecorder captures the response status for logging
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
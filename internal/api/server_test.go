@@ -0,0 +1,337 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/audit"
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/device"
+	"github.com/diogoaguiar/hvac-manager/internal/integration"
+	"github.com/diogoaguiar/hvac-manager/internal/mocks"
+	"github.com/diogoaguiar/hvac-manager/internal/schedule"
+	"github.com/diogoaguiar/hvac-manager/internal/timer"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
+)
+
+// fakeCodes implements CodeStore for a single model
+type fakeCodes struct{}
+
+func (fakeCodes) GetModel(ctx context.Context, modelID string) (*database.Model, error) {
+	if modelID != "1109" {
+		return nil, fmt.Errorf("%w: %s", database.ErrModelNotFound, modelID)
+	}
+	return &database.Model{ModelID: "1109", Manufacturer: "Daikin", MinTemperature: 16, MaxTemperature: 30, Precision: 1}, nil
+}
+
+func (fakeCodes) ListCodes(ctx context.Context, modelID string) ([]database.IRCode, error) {
+	temp, fan := 22.0, "auto"
+	return []database.IRCode{
+		{ID: 1, ModelID: modelID, Mode: "off", IRCode: "OFF"},
+		{ID: 2, ModelID: modelID, Mode: "cool", Temperature: &temp, FanSpeed: &fan, IRCode: "COOL22"},
+	}, nil
+}
+
+func setupServer(t *testing.T) (*httptest.Server, *mocks.MockMQTT, *database.DB) {
+	t.Helper()
+
+	store, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.InitSchema(context.Background()); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
+	}
+
+	db := &mocks.MockDatabase{
+		Codes:    map[string]string{"1109:cool:22:auto": "COOL22", "1109:heat:22:auto": "HEAT22"},
+		OffCodes: map[string]string{"1109": "OFF"},
+	}
+	mqtt := &mocks.MockMQTT{Connected: true}
+	dev := device.New(device.Config{ID: "living_room", Name: "Living Room AC", ModelID: "1109", BlasterID: "ir-blaster", CommandLog: store},
+		db, mqtt, topics.Default())
+
+	s := New(Config{
+		Devices:   []*device.Device{dev},
+		Codes:     fakeCodes{},
+		Schedules: schedule.New(store, mqtt, topics.Default(), dev),
+		Timers:    timer.New(store, mqtt, topics.Default(), dev),
+	})
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return srv, mqtt, store
+}
+
+// do sends a request and decodes the JSON response into out (if not nil)
+func do(t *testing.T, method, url, body string, out interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestDevices(t *testing.T) {
+	srv, _, _ := setupServer(t)
+
+	var devices []device.Status
+	if code := do(t, "GET", srv.URL+"/devices", "", &devices); code != http.StatusOK {
+		t.Fatalf("GET /devices = %d", code)
+	}
+	if len(devices) != 1 || devices[0].ID != "living_room" || devices[0].ModelID != "1109" {
+		t.Errorf("Unexpected devices: %+v", devices)
+	}
+
+	var status device.Status
+	if code := do(t, "GET", srv.URL+"/devices/living_room", "", &status); code != http.StatusOK {
+		t.Fatalf("GET /devices/living_room = %d", code)
+	}
+	if status.State.Mode != "off" || status.LastIRSend != "" || status.BlasterAvailable != nil {
+		t.Errorf("Unexpected initial status: %+v", status)
+	}
+
+	if code := do(t, "GET", srv.URL+"/devices/kitchen", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET unknown device = %d, want 404", code)
+	}
+}
+
+func TestSetState(t *testing.T) {
+	srv, mqtt, _ := setupServer(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"Valid", `{"mode": "cool", "temperature": 22}`, http.StatusOK},
+		{"Validation error", `{"temperature": 45}`, http.StatusBadRequest},
+		{"Empty command", `{}`, http.StatusBadRequest},
+		{"Unknown field", `{"temprature": 22}`, http.StatusBadRequest},
+		{"Not JSON", `cool`, http.StatusBadRequest},
+		{"No IR code", `{"mode": "dry"}`, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := do(t, "POST", srv.URL+"/devices/living_room/state", tt.body, nil); code != tt.wantCode {
+				t.Errorf("POST state = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+
+	// The valid command went through the shared command path: IR sent and state published
+	var status device.Status
+	do(t, "GET", srv.URL+"/devices/living_room", "", &status)
+	if status.State.Mode != "cool" || status.LastIRSend == "" {
+		t.Errorf("Unexpected status after command: %+v", status)
+	}
+
+	published := map[string]bool{}
+	for _, pub := range mqtt.Published {
+		published[pub.Topic] = true
+	}
+	if !published["zigbee2mqtt/ir-blaster/set"] || !published["homeassistant/climate/living_room/state"] {
+		t.Errorf("Expected IR and state publishes, got %v", published)
+	}
+}
+
+// TestSetState_RecordsRejected tests that commands refused before Apply reach the command log
+func TestSetState_RecordsRejected(t *testing.T) {
+	srv, _, store := setupServer(t)
+
+	for _, body := range []string{`{}`, `cool`} {
+		if code := do(t, "POST", srv.URL+"/devices/living_room/state", body, nil); code != http.StatusBadRequest {
+			t.Errorf("POST state %s = %d, want 400", body, code)
+		}
+	}
+
+	records, err := store.CommandHistory(context.Background(), "living_room", 10)
+	if err != nil {
+		t.Fatalf("CommandHistory failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	// Newest first
+	for i, want := range []string{`cool`, `{}`} {
+		r := records[i]
+		if r.Command != want || r.Success || r.Error == "" || r.Source != audit.SourceHTTP {
+			t.Errorf("Unexpected record for %s: %+v", want, r)
+		}
+	}
+}
+
+// TestSetState_ClientDisconnect tests that a client giving up does not interrupt the IR sequence
+func TestSetState_ClientDisconnect(t *testing.T) {
+	store, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.InitSchema(context.Background()); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
+	}
+
+	db := &mocks.MockDatabase{Codes: map[string]string{"1109:cool:22:auto": "COOL22"}}
+	mqtt := &mocks.MockMQTT{Connected: true}
+	dev := device.New(device.Config{ID: "living_room", ModelID: "1109", BlasterID: "ir-blaster", CommandLog: store,
+		Sequence: integration.Sequence{Repeat: 3, DelayMS: 100}}, db, mqtt, topics.Default())
+	srv := httptest.NewServer(New(Config{Devices: []*device.Device{dev}}).Handler())
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	if resp, err := client.Post(srv.URL+"/devices/living_room/state", "application/json", strings.NewReader(`{"mode": "cool"}`)); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected the client to time out")
+	}
+
+	// The command is recorded once its sequence is done
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		records, err := store.CommandHistory(context.Background(), "living_room", 10)
+		if err != nil {
+			t.Fatalf("CommandHistory failed: %v", err)
+		}
+		if len(records) == 1 {
+			if !records[0].Success {
+				t.Errorf("Expected the command to succeed, got %+v", records[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Command was not recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	sent := 0
+	for _, p := range mqtt.Published {
+		if p.Topic == dev.IRTopic() {
+			sent++
+		}
+	}
+	if sent != 3 {
+		t.Errorf("Expected 3 transmissions, got %d", sent)
+	}
+}
+
+func TestListCodes(t *testing.T) {
+	srv, _, _ := setupServer(t)
+
+	var resp struct {
+		ModelID string         `json:"model_id"`
+		Codes   []codeResponse `json:"codes"`
+	}
+	if code := do(t, "GET", srv.URL+"/models/1109/codes", "", &resp); code != http.StatusOK {
+		t.Fatalf("GET codes = %d", code)
+	}
+	if resp.ModelID != "1109" || len(resp.Codes) != 2 || resp.Codes[0].Temperature != nil {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	if code := do(t, "GET", srv.URL+"/models/9999/codes", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET unknown model = %d, want 404", code)
+	}
+}
+
+func TestScheduleAndTimer(t *testing.T) {
+	srv, _, _ := setupServer(t)
+	base := srv.URL + "/devices/living_room"
+
+	var slot database.Schedule
+	if code := do(t, "POST", base+"/schedule", `{"days": "weekdays", "time": "07:00", "mode": "heat"}`, &slot); code != http.StatusOK {
+		t.Fatalf("POST schedule = %d", code)
+	}
+	if slot.ID == 0 || !slot.Enabled || slot.Days != "mon,tue,wed,thu,fri" {
+		t.Errorf("Unexpected slot: %+v", slot)
+	}
+
+	var slots []database.Schedule
+	do(t, "GET", base+"/schedule", "", &slots)
+	if len(slots) != 1 {
+		t.Errorf("Expected 1 slot, got %d", len(slots))
+	}
+
+	if code := do(t, "DELETE", fmt.Sprintf("%s/schedule/%d", base, slot.ID), "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE schedule = %d", code)
+	}
+	if code := do(t, "DELETE", base+"/schedule/999", "", nil); code != http.StatusNotFound {
+		t.Errorf("DELETE unknown slot = %d, want 404", code)
+	}
+
+	var status timer.Status
+	if code := do(t, "POST", base+"/timer", `{"minutes": 60}`, &status); code != http.StatusOK {
+		t.Fatalf("POST timer = %d", code)
+	}
+	if status.Remaining != 60 || status.Action != "off" {
+		t.Errorf("Unexpected timer status: %+v", status)
+	}
+	if code := do(t, "DELETE", base+"/timer", "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE timer = %d", code)
+	}
+	do(t, "GET", base+"/timer", "", &status)
+	if status.Remaining != 0 {
+		t.Errorf("Expected no timer, got %+v", status)
+	}
+}
+
+// TestScheduleErrors tests that schedule errors map to 400, 404 or 500
+func TestScheduleErrors(t *testing.T) {
+	srv, _, store := setupServer(t)
+	base := srv.URL + "/devices/living_room"
+
+	if code := do(t, "POST", base+"/schedule", `{"days": "someday", "time": "07:00", "mode": "heat"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("POST invalid slot = %d, want 400", code)
+	}
+	if code := do(t, "POST", base+"/schedule", `{"id": 999, "days": "weekdays", "time": "07:00", "mode": "heat"}`, nil); code != http.StatusNotFound {
+		t.Errorf("POST unknown slot = %d, want 404", code)
+	}
+
+	// Storage failures are not the client's fault
+	store.Close()
+	if code := do(t, "DELETE", base+"/schedule/1", "", nil); code != http.StatusInternalServerError {
+		t.Errorf("DELETE with a closed database = %d, want 500", code)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	srv, _, _ := setupServer(t)
+
+	req, _ := http.NewRequest("GET", srv.URL+"/devices", nil)
+	req.Header.Set("X-Request-ID", "trace-42")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("X-Request-ID"); got != "trace-42" {
+		t.Errorf("X-Request-ID = %q, want trace-42", got)
+	}
+
+	resp, err = http.Get(srv.URL + "/devices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Request-ID") == "" {
+		t.Error("Expected a generated X-Request-ID")
+	}
+}
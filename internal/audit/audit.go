@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+)
+
+// Command sources recorded in the command log
+const (
+	SourceMQTT     = "mqtt"     // Home Assistant command topic
+	SourceHTTP     = "http"     // HTTP API
+	SourceSchedule = "schedule" // Weekly schedule slot
+	SourceTimer    = "timer"    // Sleep/off-delay timer
+	SourceUnknown  = "unknown"
+)
+
+// DefaultRetention is how long command records are kept
+const DefaultRetention = 90 * 24 * time.Hour
+
+// DefaultPruneInterval is how often expired records are removed
+const DefaultPruneInterval = time.Hour
+
+// sourceKey is the context key carrying the command source
+type sourceKey struct{}
+
+// WithSource tags ctx with the source of the command being applied
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFrom returns the source set by WithSource (SourceUnknown if none)
+func SourceFrom(ctx context.Context) string {
+	if source, ok := ctx.Value(sourceKey{}).(string); ok && source != "" {
+		return source
+	}
+	return SourceUnknown
+}
+
+// Pruner removes command records older than a cutoff (implemented by *database.DB)
+type Pruner interface {
+	PruneCommandLog(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RunPruner removes records older than retention every interval until ctx is cancelled
+// A retention of 0 keeps records forever
+func RunPruner(ctx context.Context, store Pruner, retention, interval time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	prune := func(now time.Time) {
+		removed, err := store.PruneCommandLog(ctx, now.Add(-retention))
+		if err != nil {
+			logger.Error("Failed to prune command log: %v", err)
+			return
+		}
+		if removed > 0 {
+			logger.Info("🧹 Pruned %d command log records older than %s", removed, retention)
+		}
+	}
+
+	prune(time.Now())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			prune(now)
+		}
+	}
+}
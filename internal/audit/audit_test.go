@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePruner records the cutoffs it is asked to prune before
+type fakePruner struct {
+	mu      sync.Mutex
+	cutoffs []time.Time
+	err     error
+}
+
+func (f *fakePruner) PruneCommandLog(ctx context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cutoffs = append(f.cutoffs, before)
+	return 1, f.err
+}
+
+func (f *fakePruner) calls() []time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Time(nil), f.cutoffs...)
+}
+
+func TestRunPruner(t *testing.T) {
+	store := &fakePruner{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		RunPruner(ctx, store, time.Hour, 5*time.Millisecond)
+		close(done)
+	}()
+
+	// Prunes at startup, then every interval
+	deadline := time.After(time.Second)
+	for len(store.calls()) < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("Expected at least 3 prunes, got %d", len(store.calls()))
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPruner did not return after cancellation")
+	}
+
+	// Records newer than the retention are kept
+	for _, cutoff := range store.calls() {
+		if age := start.Sub(cutoff); age < time.Hour-time.Second || age > time.Hour+time.Second {
+			t.Errorf("Cutoff %s is %s before start, want about 1h", cutoff, age)
+		}
+	}
+
+	// No further prunes after cancellation
+	after := len(store.calls())
+	time.Sleep(20 * time.Millisecond)
+	if n := len(store.calls()); n != after {
+		t.Errorf("Pruned %d times after cancellation", n-after)
+	}
+}
+
+func TestRunPruner_KeepForever(t *testing.T) {
+	store := &fakePruner{}
+	done := make(chan struct{})
+	go func() {
+		RunPruner(context.Background(), store, 0, time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPruner should return immediately with a retention of 0")
+	}
+	if n := len(store.calls()); n != 0 {
+		t.Errorf("Expected no prunes, got %d", n)
+	}
+}
+
+func TestRunPruner_Error(t *testing.T) {
+	// A failing prune is logged and retried at the next interval
+	store := &fakePruner{err: errors.New("database is locked")}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	RunPruner(ctx, store, time.Hour, 5*time.Millisecond)
+
+	if n := len(store.calls()); n < 2 {
+		t.Errorf("Expected retries after a failure, got %d prunes", n)
+	}
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
+)
+
+// Cache is an in-memory IRDatabase backed by a DB
+// Each model's code table is loaded once (by Preload or on its first lookup) and
+// indexed so that every fallback candidate is a single map access. Tables are
+// dropped when the model is reloaded through the DB, or explicitly with Invalidate.
+type Cache struct {
+	db *DB
+
+	mu     sync.RWMutex
+	tables map[string]*codeTable // By model ID
+}
+
+// codeTable indexes the codes of one model
+type codeTable struct {
+	codes map[codeKey]IRCode // Lowest-ID code for every candidate shape
+	modes map[string]int     // Number of codes per mode
+}
+
+// codeKey identifies a candidate: mode plus optional temperature and fan speed
+type codeKey struct {
+	mode string
+	temp tempKey
+	fan  fanKey
+}
+
+// tempKey matches any temperature (zero value), a stored temperature (set) or a stored NULL (null)
+type tempKey struct {
+	value     float64
+	set, null bool
+}
+
+// fanKey matches any fan speed (zero value), a stored fan speed (set) or a stored NULL (null)
+type fanKey struct {
+	value     string
+	set, null bool
+}
+
+// NewCache returns a cache over db, invalidated whenever db reloads a model
+func NewCache(db *DB) *Cache {
+	c := &Cache{db: db, tables: make(map[string]*codeTable)}
+	db.onModelChange(c.Invalidate)
+	return c
+}
+
+// Preload loads the code tables of the given models (all models in the database if none)
+func (c *Cache) Preload(ctx context.Context, modelIDs ...string) error {
+	if len(modelIDs) == 0 {
+		var err error
+		if modelIDs, err = c.db.ListModels(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, modelID := range modelIDs {
+		if _, err := c.table(ctx, modelID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Invalidate drops the cached table of a model; it is reloaded on its next lookup
+func (c *Cache) Invalidate(modelID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.tables[modelID]; ok {
+		delete(c.tables, modelID)
+		logger.Debug("Cache invalidated for model=%s", modelID)
+	}
+}
+
+// LookupCode implements interfaces.IRDatabase with the same fallback policy as DB.LookupCode
+func (c *Cache) LookupCode(ctx context.Context, modelID, mode string, temperature float64, fanSpeed string) (string, lookup.Result, error) {
+	logger.Debug("Cache LookupCode: model=%s mode=%s temp=%g fan=%s", modelID, mode, temperature, fanSpeed)
+	return c.db.lookupCode(ctx, c, modelID, mode, temperature, fanSpeed)
+}
+
+// LookupOffCode implements interfaces.IRDatabase
+func (c *Cache) LookupOffCode(ctx context.Context, modelID string) (string, lookup.Result, error) {
+	logger.Debug("Cache LookupOffCode: model=%s", modelID)
+	return c.db.lookupOffCode(ctx, c, modelID)
+}
+
+// LookupOnCode implements interfaces.IRDatabase
+func (c *Cache) LookupOnCode(ctx context.Context, modelID string) (string, lookup.Result, error) {
+	logger.Debug("Cache LookupOnCode: model=%s", modelID)
+	return c.db.lookupOnCode(ctx, c, modelID)
+}
+
+// LookupPrecision implements interfaces.IRDatabase
+func (c *Cache) LookupPrecision(ctx context.Context, modelID string) (float64, error) {
+	return c.db.LookupPrecision(ctx, modelID)
+}
+
+// findCode implements codeSource from the model's table
+func (c *Cache) findCode(ctx context.Context, modelID, mode string, candidate Candidate) (string, lookup.Result, error) {
+	t, err := c.table(ctx, modelID)
+	if err != nil {
+		return "", lookup.Result{}, err
+	}
+
+	// Like DB.findCode, codes stored with the requested values come before those stored without
+	temps := []tempKey{{}}
+	if candidate.Temperature != nil {
+		temps = []tempKey{{value: *candidate.Temperature, set: true}, {null: true}}
+	}
+	fans := []fanKey{{}}
+	if candidate.FanSpeed != nil {
+		fans = []fanKey{{value: *candidate.FanSpeed, set: true}, {null: true}}
+	}
+
+	for _, temp := range temps {
+		for _, fan := range fans {
+			if code, ok := t.codes[codeKey{mode: mode, temp: temp, fan: fan}]; ok {
+				return code.IRCode, lookup.Result{
+					CodeID:      int64(code.ID),
+					Mode:        code.Mode,
+					Temperature: code.Temperature,
+					FanSpeed:    code.FanSpeed,
+					Swing:       code.Swing,
+				}, nil
+			}
+		}
+	}
+	return "", lookup.Result{}, sql.ErrNoRows
+}
+
+// countCodes implements codeSource from the model's table
+func (c *Cache) countCodes(ctx context.Context, modelID, mode string) int {
+	t, err := c.table(ctx, modelID)
+	if err != nil {
+		return 0
+	}
+	return t.modes[mode]
+}
+
+// table returns the cached table of a model, loading it if needed
+func (c *Cache) table(ctx context.Context, modelID string) (*codeTable, error) {
+	c.mu.RLock()
+	t, ok := c.tables[modelID]
+	c.mu.RUnlock()
+	if ok {
+		return t, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.tables[modelID]; ok {
+		return t, nil // Loaded while waiting for the lock
+	}
+
+	codes, err := c.db.ListCodes(ctx, modelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load codes of model %s: %w", modelID, err)
+	}
+	t = newCodeTable(codes)
+	c.tables[modelID] = t
+	logger.Debug("Cache loaded %d codes for model=%s", len(codes), modelID)
+	return t, nil
+}
+
+// newCodeTable indexes codes under every candidate shape they can match
+// Each field is indexed both as a wildcard and as its stored value, or NULL
+func newCodeTable(codes []IRCode) *codeTable {
+	t := &codeTable{codes: make(map[codeKey]IRCode), modes: make(map[string]int)}
+	for _, code := range codes {
+		t.modes[code.Mode]++
+
+		temp := tempKey{null: code.Temperature == nil}
+		if code.Temperature != nil {
+			temp = tempKey{value: *code.Temperature, set: true}
+		}
+		fan := fanKey{null: code.FanSpeed == nil}
+		if code.FanSpeed != nil {
+			fan = fanKey{value: *code.FanSpeed, set: true}
+		}
+
+		for _, key := range []codeKey{
+			{mode: code.Mode},
+			{mode: code.Mode, temp: temp},
+			{mode: code.Mode, fan: fan},
+			{mode: code.Mode, temp: temp, fan: fan},
+		} {
+			if existing, ok := t.codes[key]; !ok || code.ID < existing.ID {
+				t.codes[key] = code
+			}
+		}
+	}
+	return t
+}
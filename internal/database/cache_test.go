@@ -0,0 +1,203 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
+)
+
+var referenceFile = filepath.Join("..", "..", "docs", "smartir", "reference", "1109.json")
+
+// setupCacheDB loads the 1109 reference model plus a model with NULL temperatures and fan speeds
+func setupCacheDB(t *testing.T) *DB {
+	t.Helper()
+	if _, err := os.Stat(referenceFile); os.IsNotExist(err) {
+		t.Skipf("Reference file not found: %s", referenceFile)
+	}
+
+	db := setupTestDB(t)
+	ctx := context.Background()
+	if err := db.LoadFromJSON(ctx, "1109", referenceFile); err != nil {
+		t.Fatalf("LoadFromJSON failed: %v", err)
+	}
+
+	if _, err := db.conn.ExecContext(ctx, `
+		INSERT INTO models (model_id, manufacturer, supported_models, commands_encoding,
+			supported_controller, min_temperature, max_temperature, precision, operation_modes, fan_modes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, "test-model", "Test", "[]", "Raw", "MQTT", 16, 30, 1.0, "[]", "[]"); err != nil {
+		t.Fatalf("Failed to insert model: %v", err)
+	}
+	codes := []IRCode{
+		{ModelID: "test-model", Mode: "fan_only", FanSpeed: strPtr("high"), IRCode: "fan-high"},
+		{ModelID: "test-model", Mode: "fan_only", FanSpeed: strPtr("low"), IRCode: "fan-low"},
+		{ModelID: "test-model", Mode: "dry", Temperature: tempPtr(24), IRCode: "dry-24"},
+		{ModelID: "test-model", Mode: "heat", Temperature: tempPtr(22), FanSpeed: strPtr("low"), IRCode: "heat-22-low"},
+	}
+	for i := range codes {
+		if err := db.InsertCode(ctx, &codes[i]); err != nil {
+			t.Fatalf("Failed to insert test code: %v", err)
+		}
+	}
+	return db
+}
+
+// TestCache_MatchesDB tests that the cache resolves every state like the SQLite queries
+func TestCache_MatchesDB(t *testing.T) {
+	db := setupCacheDB(t)
+	defer db.Close()
+	logger.SetLevel(logger.ERROR)
+	defer logger.SetLevel(logger.INFO)
+
+	ctx := context.Background()
+	cache := NewCache(db)
+	if err := cache.Preload(ctx); err != nil {
+		t.Fatalf("Preload failed: %v", err)
+	}
+
+	policies := []FallbackPolicy{
+		{},
+		{Strict: true},
+		{Fan: FanFallbackNearest, TemperatureRange: 2},
+	}
+	modes := []string{"cool", "heat", "dry", "fan_only", "auto"}
+	fans := []string{"auto", "low", "medium", "high", "quiet", ""}
+
+	for _, policy := range policies {
+		db.SetFallbackPolicy(DefaultPolicyModel, policy)
+		for _, modelID := range []string{"1109", "test-model", "unknown"} {
+			for _, mode := range modes {
+				for temp := 14.0; temp <= 34; temp++ {
+					for _, fan := range fans {
+						wantCode, wantResult, wantErr := db.LookupCode(ctx, modelID, mode, temp, fan)
+						code, result, err := cache.LookupCode(ctx, modelID, mode, temp, fan)
+						if code != wantCode || !reflect.DeepEqual(result, wantResult) || (err == nil) != (wantErr == nil) {
+							t.Fatalf("%+v %s/%s/%g/%s: cache %+v (err %v), db %+v (err %v)",
+								policy, modelID, mode, temp, fan, result, err, wantResult, wantErr)
+						}
+					}
+				}
+			}
+
+			wantCode, wantResult, wantErr := db.LookupOffCode(ctx, modelID)
+			code, result, err := cache.LookupOffCode(ctx, modelID)
+			if code != wantCode || !reflect.DeepEqual(result, wantResult) || (err == nil) != (wantErr == nil) {
+				t.Errorf("%s off: cache %+v (err %v), db %+v (err %v)", modelID, result, err, wantResult, wantErr)
+			}
+		}
+	}
+}
+
+// TestCache_Invalidate tests that reloading a model through the DB drops its cached table
+func TestCache_Invalidate(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO models (model_id, manufacturer, supported_models, commands_encoding,
+			supported_controller, min_temperature, max_temperature, precision, operation_modes, fan_modes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, "test-model", "Test", "[]", "Raw", "MQTT", 16, 30, 1.0, "[]", "[]")
+	if err != nil {
+		t.Fatalf("Failed to insert model: %v", err)
+	}
+
+	cache := NewCache(db)
+	if err := cache.Preload(ctx, "test-model"); err != nil {
+		t.Fatalf("Preload failed: %v", err)
+	}
+	if _, _, err := cache.LookupOffCode(ctx, "test-model"); err == nil {
+		t.Fatal("Expected no off code before insert")
+	}
+
+	// Bypassing the DB methods leaves the cache stale until invalidated
+	if _, err := db.conn.ExecContext(ctx, `INSERT INTO ir_codes (model_id, mode, ir_code) VALUES ('test-model', 'off', 'OFF')`); err != nil {
+		t.Fatalf("Failed to insert code: %v", err)
+	}
+	if _, _, err := cache.LookupOffCode(ctx, "test-model"); err == nil {
+		t.Error("Expected stale cache before Invalidate")
+	}
+	cache.Invalidate("test-model")
+	if code, _, err := cache.LookupOffCode(ctx, "test-model"); err != nil || code != "OFF" {
+		t.Errorf("Expected OFF after Invalidate, got %q (err %v)", code, err)
+	}
+
+	// Changes through the DB invalidate automatically
+	if err := db.InsertCode(ctx, &IRCode{ModelID: "test-model", Mode: "cool", Temperature: tempPtr(22), FanSpeed: strPtr("auto"), IRCode: "COOL22"}); err != nil {
+		t.Fatalf("InsertCode failed: %v", err)
+	}
+	if code, _, err := cache.LookupCode(ctx, "test-model", "cool", 22, "auto"); err != nil || code != "COOL22" {
+		t.Errorf("Expected COOL22 after insert, got %q (err %v)", code, err)
+	}
+}
+
+// benchmarkLookups is a mix of exact matches, fallbacks and misses on the 1109 model
+var benchmarkLookups = []struct {
+	mode string
+	temp float64
+	fan  string
+}{
+	{"cool", 22, "low"},    // Exact
+	{"heat", 24, "high"},   // Exact
+	{"cool", 22, "auto"},   // Fan fallback
+	{"dry", 20, "auto"},    // Mode only
+	{"cool", 40, "medium"}, // Miss
+}
+
+func benchmarkLookup(b *testing.B, find func(ctx context.Context, modelID, mode string, temp float64, fan string) (string, lookup.Result, error)) {
+	logger.SetLevel(logger.ERROR)
+	b.Cleanup(func() { logger.SetLevel(logger.INFO) })
+	ctx := context.Background()
+
+	for _, l := range benchmarkLookups {
+		b.Run(fmt.Sprintf("%s_%g_%s", l.mode, l.temp, l.fan), func(b *testing.B) {
+			for b.Loop() {
+				find(ctx, "1109", l.mode, l.temp, l.fan)
+			}
+		})
+	}
+}
+
+func BenchmarkLookupCode_SQLite(b *testing.B) {
+	db := setupBenchDB(b)
+	defer db.Close()
+	benchmarkLookup(b, db.LookupCode)
+}
+
+func BenchmarkLookupCode_Cache(b *testing.B) {
+	db := setupBenchDB(b)
+	defer db.Close()
+	cache := NewCache(db)
+	if err := cache.Preload(context.Background(), "1109"); err != nil {
+		b.Fatalf("Preload failed: %v", err)
+	}
+	benchmarkLookup(b, cache.LookupCode)
+}
+
+// setupBenchDB loads the 1109 reference model
+func setupBenchDB(b *testing.B) *DB {
+	b.Helper()
+	if _, err := os.Stat(referenceFile); os.IsNotExist(err) {
+		b.Skipf("Reference file not found: %s", referenceFile)
+	}
+
+	db, err := New(":memory:")
+	if err != nil {
+		b.Fatalf("failed to create: %v", err)
+	}
+	ctx := context.Background()
+	if err := db.InitSchema(ctx); err != nil {
+		b.Fatalf("failed to initialize schema: %v", err)
+	}
+	if err := db.LoadFromJSON(ctx, "1109", referenceFile); err != nil {
+		b.Fatalf("LoadFromJSON failed: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CommandRecord is one entry of the append-only command log
+// Every command received for a device is recorded, whatever its source and outcome
+type CommandRecord struct {
+	ID            int64     `json:"id"`
+	DeviceID      string    `json:"device_id"`
+	Time          time.Time `json:"time"`
+	Source        string    `json:"source"`               // mqtt, http, schedule, timer
+	Command       string    `json:"command"`              // Received command as JSON
+	Mode          string    `json:"mode"`                 // Resulting state
+	Temperature   float64   `json:"temperature"`          // Resulting state
+	FanMode       string    `json:"fan_mode"`             // Resulting state
+	Preset        string    `json:"preset,omitempty"`     // Resulting state
+	IRCodeID      *int64    `json:"ir_code_id,omitempty"` // ir_codes.id sent (nil if no lookup code was sent)
+	Strategy      string    `json:"strategy,omitempty"`   // Lookup strategy, "preset" for dedicated preset codes
+	Success       bool      `json:"success"`
+	Error         string    `json:"error,omitempty"`          // Failure reason
+	CorrelationID string    `json:"correlation_id,omitempty"` // Ties the record to log lines
+}
+
+// LogCommand appends a record to the command log (ID and Time are set if empty)
+func (db *DB) LogCommand(ctx context.Context, r *CommandRecord) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	query := `
+		INSERT INTO command_log (device_id, created_at, source, command, mode, temperature, fan_mode,
+			preset, ir_code_id, strategy, success, error, correlation_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := db.conn.ExecContext(ctx, query, r.DeviceID, r.Time.UnixMilli(), r.Source, r.Command,
+		r.Mode, r.Temperature, r.FanMode, nullString(r.Preset), r.IRCodeID, nullString(r.Strategy),
+		r.Success, nullString(r.Error), nullString(r.CorrelationID))
+	if err != nil {
+		return fmt.Errorf("failed to log command: %w", err)
+	}
+
+	if r.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get command log ID: %w", err)
+	}
+	return nil
+}
+
+// CommandHistory returns the most recent records of a device, newest first
+// A limit of 0 returns all records
+func (db *DB) CommandHistory(ctx context.Context, deviceID string, limit int) ([]CommandRecord, error) {
+	query := `
+		SELECT id, device_id, created_at, source, command, mode, temperature, fan_mode,
+			preset, ir_code_id, strategy, success, error, correlation_id
+		FROM command_log
+		WHERE device_id = ?
+		ORDER BY id DESC
+	`
+	args := []interface{}{deviceID}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query command log: %w", err)
+	}
+	defer rows.Close()
+
+	var records []CommandRecord
+	for rows.Next() {
+		var r CommandRecord
+		var createdAt int64
+		var preset, strategy, errMsg, correlationID sql.NullString
+		var codeID sql.NullInt64
+		if err := rows.Scan(&r.ID, &r.DeviceID, &createdAt, &r.Source, &r.Command, &r.Mode, &r.Temperature,
+			&r.FanMode, &preset, &codeID, &strategy, &r.Success, &errMsg, &correlationID); err != nil {
+			return nil, fmt.Errorf("failed to scan command log: %w", err)
+		}
+		r.Time = time.UnixMilli(createdAt)
+		r.Preset = preset.String
+		r.Strategy = strategy.String
+		r.Error = errMsg.String
+		r.CorrelationID = correlationID.String
+		if codeID.Valid {
+			r.IRCodeID = &codeID.Int64
+		}
+		records = append(records, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating command log: %w", err)
+	}
+
+	return records, nil
+}
+
+// PruneCommandLog removes records older than before and returns how many were removed
+// This is the only way records leave the log; updates are rejected by a trigger
+func (db *DB) PruneCommandLog(ctx context.Context, before time.Time) (int64, error) {
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM command_log WHERE created_at < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune command log: %w", err)
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pruned records: %w", err)
+	}
+	return removed, nil
+}
+
+// nullString maps "" to NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
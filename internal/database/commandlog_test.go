@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCommandLog(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Millisecond)
+	codeID := int64(42)
+	records := []*CommandRecord{
+		{DeviceID: "living_room", Time: now.Add(-100 * 24 * time.Hour), Source: "schedule", Command: `{"mode":"heat"}`,
+			Mode: "heat", Temperature: 21, FanMode: "auto", Success: true},
+		{DeviceID: "living_room", Time: now.Add(-time.Hour), Source: "mqtt", Command: `{"mode":"cool"}`,
+			Mode: "cool", Temperature: 22, FanMode: "auto", IRCodeID: &codeID, Strategy: "exact", Success: true, CorrelationID: "abc"},
+		{DeviceID: "living_room", Time: now, Source: "http", Command: `{"mode":"dry"}`,
+			Mode: "cool", Temperature: 22, FanMode: "auto", Error: "no IR code", CorrelationID: "def"},
+		{DeviceID: "bedroom", Time: now, Source: "timer", Command: `{"mode":"off"}`, Mode: "off", FanMode: "auto", Success: true},
+	}
+	for _, r := range records {
+		if err := db.LogCommand(ctx, r); err != nil {
+			t.Fatalf("LogCommand failed: %v", err)
+		}
+		if r.ID == 0 {
+			t.Fatal("Expected ID to be set")
+		}
+	}
+
+	// Newest first, filtered by device
+	history, err := db.CommandHistory(ctx, "living_room", 0)
+	if err != nil {
+		t.Fatalf("CommandHistory failed: %v", err)
+	}
+	if len(history) != 3 || history[0].Source != "http" || history[2].Source != "schedule" {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+
+	failed := history[0]
+	if failed.Success || failed.Error != "no IR code" || failed.IRCodeID != nil || !failed.Time.Equal(now) {
+		t.Errorf("Unexpected failed record: %+v", failed)
+	}
+	sent := history[1]
+	if !sent.Success || sent.IRCodeID == nil || *sent.IRCodeID != 42 || sent.Strategy != "exact" || sent.CorrelationID != "abc" {
+		t.Errorf("Unexpected sent record: %+v", sent)
+	}
+
+	if history, _ = db.CommandHistory(ctx, "living_room", 2); len(history) != 2 || history[0].Source != "http" {
+		t.Errorf("Limit not applied: %+v", history)
+	}
+
+	// The log is append-only
+	if _, err := db.conn.ExecContext(ctx, `UPDATE command_log SET success = 1 WHERE id = ?`, failed.ID); err == nil {
+		t.Error("Expected UPDATE to be rejected")
+	}
+
+	// Pruning removes only expired records
+	removed, err := db.PruneCommandLog(ctx, now.Add(-90*24*time.Hour))
+	if err != nil {
+		t.Fatalf("PruneCommandLog failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 pruned record, got %d", removed)
+	}
+	if history, _ = db.CommandHistory(ctx, "living_room", 0); len(history) != 2 {
+		t.Errorf("Expected 2 records after prune, got %d", len(history))
+	}
+}
@@ -195,7 +195,7 @@ func TestLoadFromJSON_BroadlinkFormat(t *testing.T) {
 	// Verify that codes were loaded and converted
 	testCases := []struct {
 		mode string
-		temp int
+		temp float64
 		fan  string
 	}{
 		{"cool", 21, "low"},
@@ -218,12 +218,12 @@ func TestLoadFromJSON_BroadlinkFormat(t *testing.T) {
 
 		err := db.conn.QueryRowContext(ctx, query, args...).Scan(&code)
 		if err != nil {
-			t.Errorf("Failed to query code for mode=%s temp=%d fan=%s: %v", tc.mode, tc.temp, tc.fan, err)
+			t.Errorf("Failed to query code for mode=%s temp=%g fan=%s: %v", tc.mode, tc.temp, tc.fan, err)
 			continue
 		}
 
 		if code == "" {
-			t.Errorf("Empty code returned for mode=%s temp=%d fan=%s", tc.mode, tc.temp, tc.fan)
+			t.Errorf("Empty code returned for mode=%s temp=%g fan=%s", tc.mode, tc.temp, tc.fan)
 		}
 
 		// Verify it's in Tuya format (should start with common prefixes like "D", "C", "M")
@@ -238,7 +238,7 @@ func TestLoadFromJSON_BroadlinkFormat(t *testing.T) {
 				}
 			}
 			if !isValid {
-				t.Logf("Warning: Unexpected code prefix '%s' for mode=%s temp=%d fan=%s (code: %s)",
+				t.Logf("Warning: Unexpected code prefix '%s' for mode=%s temp=%g fan=%s (code: %s)",
 					firstChar, tc.mode, tc.temp, tc.fan, code[:20])
 			}
 		}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
+)
+
+// CoverageCell is how one requested mode × fan speed × temperature resolves
+type CoverageCell struct {
+	Mode        string        `json:"mode"`
+	FanSpeed    string        `json:"fan_speed"`
+	Temperature float64       `json:"temperature"`
+	Result      lookup.Result `json:"result"` // Strategy is lookup.StrategyMiss for unreachable cells
+}
+
+// Coverage maps every state of a model to the code LookupCode would send
+type Coverage struct {
+	ModelID      string         `json:"model_id"`
+	Modes        []string       `json:"modes"`        // Declared modes, then modes only found in ir_codes
+	FanModes     []string       `json:"fan_modes"`    // Declared fan speeds, then those only found in ir_codes
+	Temperatures []float64      `json:"temperatures"` // Declared range, in steps of the model's precision
+	Cells        []CoverageCell `json:"cells"`        // By mode, fan speed, then temperature
+	Off          bool           `json:"off"`          // Whether the model has an off code
+	On           bool           `json:"on"`           // Whether the model has a separate on code
+	Exact        int            `json:"exact"`
+	Fallback     int            `json:"fallback"`
+	Unreachable  int            `json:"unreachable"`
+}
+
+// Cell returns the cell of a state
+func (c *Coverage) Cell(mode, fanSpeed string, temperature float64) (CoverageCell, bool) {
+	for _, cell := range c.Cells {
+		if cell.Mode == mode && cell.FanSpeed == fanSpeed && cell.Temperature == temperature {
+			return cell, true
+		}
+	}
+	return CoverageCell{}, false
+}
+
+// Coverage resolves every mode × fan speed × temperature of a model with its fallback policy
+func (db *DB) Coverage(ctx context.Context, modelID string) (*Coverage, error) {
+	model, err := db.GetModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := db.ListCodes(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Coverage{
+		ModelID:  modelID,
+		Modes:    slices.Clone(model.OperationModes),
+		FanModes: slices.Clone(model.FanModes),
+	}
+	for _, code := range codes {
+		switch code.Mode {
+		case "off":
+			c.Off = true
+			continue
+		case "on":
+			c.On = true
+			continue
+		}
+		if !slices.Contains(c.Modes, code.Mode) {
+			c.Modes = append(c.Modes, code.Mode)
+		}
+		if code.FanSpeed != nil && !slices.Contains(c.FanModes, *code.FanSpeed) {
+			c.FanModes = append(c.FanModes, *code.FanSpeed)
+		}
+	}
+	step := model.Precision
+	if step <= 0 {
+		step = 1
+	}
+	for temp := float64(model.MinTemperature); temp <= float64(model.MaxTemperature); temp += step {
+		c.Temperatures = append(c.Temperatures, temp)
+	}
+
+	for _, mode := range c.Modes {
+		for _, fan := range c.FanModes {
+			for _, temp := range c.Temperatures {
+				_, result, err := db.lookupCode(ctx, db, modelID, mode, temp, fan)
+				if err != nil && result.Strategy != lookup.StrategyMiss {
+					return nil, fmt.Errorf("failed to resolve %s/%s/%g: %w", mode, fan, temp, err)
+				}
+
+				switch {
+				case result.Strategy == lookup.StrategyMiss:
+					c.Unreachable++
+				case result.Fallback():
+					c.Fallback++
+				default:
+					c.Exact++
+				}
+				c.Cells = append(c.Cells, CoverageCell{Mode: mode, FanSpeed: fan, Temperature: temp, Result: result})
+			}
+		}
+	}
+	return c, nil
+}
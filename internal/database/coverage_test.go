@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
+)
+
+func TestCoverage(t *testing.T) {
+	db := setupCacheDB(t)
+	defer db.Close()
+	logger.SetLevel(logger.ERROR)
+	defer logger.SetLevel(logger.INFO)
+	ctx := context.Background()
+
+	// 1109 declares 16-32°C but its codes stop at 31
+	c, err := db.Coverage(ctx, "1109")
+	if err != nil {
+		t.Fatalf("Coverage failed: %v", err)
+	}
+	if len(c.Cells) != 4*3*17 || !c.Off {
+		t.Fatalf("Expected 204 cells and an off code, got %d cells (off %v)", len(c.Cells), c.Off)
+	}
+	if c.Exact != 192 || c.Fallback != 6 || c.Unreachable != 6 {
+		t.Errorf("Expected 192 exact, 6 fallback, 6 unreachable; got %d, %d, %d", c.Exact, c.Fallback, c.Unreachable)
+	}
+	if cell, _ := c.Cell("cool", "low", 32); cell.Result.Strategy != lookup.StrategyMiss {
+		t.Errorf("cool/low/32: expected unreachable, got %s", cell.Result.Strategy)
+	}
+	if cell, _ := c.Cell("dry", "low", 32); cell.Result.Strategy != lookup.StrategyModeOnly {
+		t.Errorf("dry/low/32: expected mode_only, got %s", cell.Result.Strategy)
+	}
+
+	// A wider policy makes every cell reachable
+	db.SetFallbackPolicy("1109", FallbackPolicy{TemperatureRange: 1})
+	if c, err = db.Coverage(ctx, "1109"); err != nil {
+		t.Fatalf("Coverage failed: %v", err)
+	}
+	if c.Unreachable != 0 {
+		t.Errorf("Expected no unreachable cells with temperature_range 1, got %d", c.Unreachable)
+	}
+
+	// Modes and fan speeds only found in ir_codes are included
+	c, err = db.Coverage(ctx, "test-model")
+	if err != nil {
+		t.Fatalf("Coverage failed: %v", err)
+	}
+	if len(c.Modes) != 3 || len(c.FanModes) != 2 || c.Off {
+		t.Errorf("Expected 3 modes, 2 fan speeds and no off code, got %v %v (off %v)", c.Modes, c.FanModes, c.Off)
+	}
+	cells := []struct {
+		mode, fan string
+		temp      float64
+		strategy  string
+	}{
+		{"heat", "low", 22, lookup.StrategyExact},
+		{"heat", "high", 22, lookup.StrategyFan},
+		{"heat", "low", 23, lookup.StrategyMiss},
+		{"dry", "low", 24, lookup.StrategyExact},       // Stored without a fan speed: any fan speed
+		{"fan_only", "high", 16, lookup.StrategyExact}, // Stored without a temperature: any temperature
+	}
+	for _, tt := range cells {
+		cell, ok := c.Cell(tt.mode, tt.fan, tt.temp)
+		if !ok || cell.Result.Strategy != tt.strategy {
+			t.Errorf("%s/%s/%g: expected %s, got %+v", tt.mode, tt.fan, tt.temp, tt.strategy, cell)
+		}
+	}
+
+	if _, err := db.Coverage(ctx, "unknown"); err == nil {
+		t.Error("Expected an error for an unknown model")
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
+	"github.com/diogoaguiar/hvac-manager/internal/metrics"
 	_ "modernc.org/sqlite" // Pure Go SQLite driver
 )
 
@@ -15,27 +21,41 @@ var schemaSQL string
 
 const (
 	// CurrentSchemaVersion tracks the database schema version
-	CurrentSchemaVersion = 1
+	CurrentSchemaVersion = 6
 )
 
+// ErrModelNotFound is returned when a model is not in the database
+var ErrModelNotFound = errors.New("model not found")
+
+// ErrScheduleNotFound is returned when a schedule slot does not exist for the device
+var ErrScheduleNotFound = errors.New("schedule not found")
+
 // DB wraps the SQL database connection with application-specific methods
 type DB struct {
 	conn *sql.DB
+
+	mu        sync.RWMutex
+	policies  map[string]FallbackPolicy // Fallback policy per model ID (see SetFallbackPolicy)
+	listeners []func(modelID string)    // Called after a model's codes change (see onModelChange)
 }
 
 // New creates a new database connection WITHOUT initializing schema
 // filePath can be ":memory:" for in-memory database or a file path
 // Use InitSchema() to create tables, or Migrate() for schema updates
 func New(filePath string) (*DB, error) {
-	conn, err := sql.Open("sqlite", filePath)
+	// Enable foreign keys (not enabled by default in SQLite) on every pooled connection,
+	// so deleting a model always cascades to its IR codes
+	dsn := filePath + "?_pragma=foreign_keys(1)"
+	if strings.Contains(filePath, "?") {
+		dsn = filePath + "&_pragma=foreign_keys(1)"
+	}
+	conn, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
-
-	// Enable foreign keys (not enabled by default in SQLite)
-	if _, err := conn.Exec("PRAGMA foreign_keys = ON"); err != nil {
+	if err := conn.Ping(); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	db := &DB{conn: conn}
@@ -66,36 +86,6 @@ func (db *DB) InitSchema(ctx context.Context) error {
 	return db.setSchemaVersion(ctx, CurrentSchemaVersion)
 }
 
-// Migrate updates the database schema to the current version
-// Safe to call on already-initialized databases
-func (db *DB) Migrate(ctx context.Context) error {
-	currentVersion, err := db.GetSchemaVersion(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to check schema version: %w", err)
-	}
-
-	// Version 0 means uninitialized database
-	if currentVersion == 0 {
-		return db.InitSchema(ctx)
-	}
-
-	if currentVersion == CurrentSchemaVersion {
-		// Already up to date
-		return nil
-	}
-
-	// Future migrations would go here
-	// Example:
-	// if currentVersion == 1 {
-	//     if err := db.migrateV1ToV2(ctx); err != nil {
-	//         return err
-	//     }
-	//     currentVersion = 2
-	// }
-
-	return fmt.Errorf("unknown schema version %d (expected %d)", currentVersion, CurrentSchemaVersion)
-}
-
 // GetSchemaVersion retrieves the current schema version
 func (db *DB) GetSchemaVersion(ctx context.Context) (int, error) {
 	var version int
@@ -137,163 +127,199 @@ type Model struct {
 
 // IRCode represents a single IR code entry
 type IRCode struct {
-	ID          int     // Auto-generated primary key
-	ModelID     string  // References Model.ModelID
-	Mode        string  // e.g., "cool", "heat", "off"
-	Temperature *int    // Pointer to handle NULL for "off" command
-	FanSpeed    *string // Pointer to handle NULL for "off" command
-	IRCode      string  // Base64-encoded Tuya format code
+	ID          int      // Auto-generated primary key
+	ModelID     string   // References Model.ModelID
+	Mode        string   // e.g., "cool", "heat", "off"
+	Temperature *float64 // Pointer to handle NULL for "off"/"on" and codes for any temperature
+	FanSpeed    *string  // Pointer to handle NULL for "off"/"on" and codes for any fan speed
+	Swing       *string  // Swing mode, nil when the file has no swing level
+	IRCode      string   // Base64-encoded Tuya format code
 }
 
 // LookupCode retrieves the IR code for a specific state with intelligent fallback
-// Priority order:
+// The lookup.Result tells which row was matched and by which strategy; on a miss
+// the error is returned with a result whose Strategy is lookup.StrategyMiss.
+// Candidates are tried in the order given by the model's FallbackPolicy; by default:
 // 1. Exact match (mode + temp + fan)
-// 2. Mode + temp (ignore fan) - for heat/cool/auto modes
-// 3. Fan fallback: auto → low → medium → high
+// 2. Fan fallback: low → medium → high → auto
+// 3. Mode + temp (ignore fan) - for heat/cool/auto modes
 // 4. Mode only (ignore temp + fan) - for fan_only/dry modes
-func (db *DB) LookupCode(ctx context.Context, modelID, mode string, temperature int, fanSpeed string) (string, error) {
-	logger.Debug("DB LookupCode: model=%s mode=%s temp=%d fan=%s", modelID, mode, temperature, fanSpeed)
+func (db *DB) LookupCode(ctx context.Context, modelID, mode string, temperature float64, fanSpeed string) (string, lookup.Result, error) {
+	logger.Debug("DB LookupCode: model=%s mode=%s temp=%g fan=%s", modelID, mode, temperature, fanSpeed)
+	return db.lookupCode(ctx, db, modelID, mode, temperature, fanSpeed)
+}
 
-	// Try exact match first
-	code, err := db.lookupExact(ctx, modelID, mode, temperature, fanSpeed)
-	if err == nil {
-		logger.Info("✓ Exact match: mode=%s temp=%d fan=%s", mode, temperature, fanSpeed)
-		return code, nil
-	}
-	if err != sql.ErrNoRows {
-		return "", err // Database error, not just missing data
-	}
+// codeSource finds stored codes for lookups: SQLite queries (*DB) or the in-memory Cache
+type codeSource interface {
+	// findCode returns the lowest-ID code of mode matching the candidate (sql.ErrNoRows if none)
+	findCode(ctx context.Context, modelID, mode string, c Candidate) (string, lookup.Result, error)
 
-	logger.Debug("Exact match failed, trying fallback strategies...")
+	// countCodes returns how many codes mode has (for miss diagnostics)
+	countCodes(ctx context.Context, modelID, mode string) int
+}
 
-	// Determine if temperature is required for this mode
-	tempRequired := mode == "heat" || mode == "cool" || mode == "auto"
+// lookupCode resolves a state against src following the model's FallbackPolicy
+func (db *DB) lookupCode(ctx context.Context, src codeSource, modelID, mode string, temperature float64, fanSpeed string) (string, lookup.Result, error) {
+	// Record which strategy resolved the lookup (database errors are not counted)
+	start := time.Now()
+	result := lookup.Result{Strategy: lookup.StrategyMiss}
+	defer func() {
+		if result.Strategy != "" {
+			metrics.ObserveLookup(ctx, modelID, result.Strategy, time.Since(start))
+		}
+	}()
 
-	// Strategy 1: Try different fan speeds (if fan was specified)
-	if fanSpeed != "" {
-		fanFallbacks := getFanFallbacks(fanSpeed)
-		for _, fallbackFan := range fanFallbacks {
-			code, err := db.lookupExact(ctx, modelID, mode, temperature, fallbackFan)
-			if err == nil {
-				logger.Info("✓ Fan fallback: mode=%s temp=%d fan=%s (requested: %s)",
-					mode, temperature, fallbackFan, fanSpeed)
-				return code, nil
+	policy := db.FallbackPolicy(modelID)
+	for i, candidate := range policy.Plan(mode, temperature, fanSpeed) {
+		code, match, err := src.findCode(ctx, modelID, mode, candidate)
+		if err == sql.ErrNoRows {
+			if i == 0 {
+				logger.Debug("Exact match failed, trying fallback strategies...")
 			}
+			continue
 		}
-	}
-
-	// Strategy 2: Try ignoring fan speed (mode + temp only)
-	if tempRequired {
-		code, err := db.lookupModeTemp(ctx, modelID, mode, temperature)
-		if err == nil {
-			logger.Info("✓ Mode+temp match: mode=%s temp=%d (ignoring fan)", mode, temperature)
-			return code, nil
+		if err != nil {
+			result = lookup.Result{}
+			return "", result, err // Database error, not just missing data
 		}
-	}
 
-	// Strategy 3: Try mode only (ignore temp + fan) for modes that don't require temp
-	if !tempRequired {
-		code, err := db.lookupModeOnly(ctx, modelID, mode)
-		if err == nil {
-			logger.Info("✓ Mode-only match: mode=%s (ignoring temp/fan)", mode)
-			return code, nil
+		result = match
+		result.Strategy = candidate.Strategy
+		if candidate.Strategy == lookup.StrategyExact {
+			logger.Info("✓ Exact match: mode=%s temp=%g fan=%s", mode, temperature, fanSpeed)
+		} else {
+			logger.Info("✓ Fallback match: %s (requested: mode=%s temp=%g fan=%s)", result, mode, temperature, fanSpeed)
 		}
+		return code, result, nil
 	}
 
 	// All strategies failed
-	logger.Warn("⚠️  No IR code found for model=%s mode=%s temp=%d fan=%s (tried all fallbacks)",
+	logger.Warn("⚠️  No IR code found for model=%s mode=%s temp=%g fan=%s (tried all fallbacks)",
 		modelID, mode, temperature, fanSpeed)
 
 	// Debug info: show what's available
-	var count int
-	checkQuery := `SELECT COUNT(*) FROM ir_codes WHERE model_id = ? AND mode = ?`
-	db.conn.QueryRowContext(ctx, checkQuery, modelID, mode).Scan(&count)
+	count := src.countCodes(ctx, modelID, mode)
 	logger.Debug("Found %d codes for model=%s mode=%s (any temp/fan)", count, modelID, mode)
 
-	return "", fmt.Errorf("no IR code found for model=%s mode=%s temp=%d fan=%s",
+	if policy.Strict {
+		return "", result, fmt.Errorf("no exact IR code for model=%s mode=%s temp=%g fan=%s (strict fallback policy)",
+			modelID, mode, temperature, fanSpeed)
+	}
+	return "", result, fmt.Errorf("no IR code found for model=%s mode=%s temp=%g fan=%s",
 		modelID, mode, temperature, fanSpeed)
 }
 
-// lookupExact performs exact match query
-func (db *DB) lookupExact(ctx context.Context, modelID, mode string, temperature int, fanSpeed string) (string, error) {
+// lookupRow runs a single-row lookup query selecting ir_code, id, mode, temperature, fan_speed, swing
+func (db *DB) lookupRow(ctx context.Context, query string, args ...interface{}) (string, lookup.Result, error) {
 	var code string
+	var r lookup.Result
+	var temp sql.NullFloat64
+	var fan, swing sql.NullString
+	if err := db.conn.QueryRowContext(ctx, query, args...).Scan(&code, &r.CodeID, &r.Mode, &temp, &fan, &swing); err != nil {
+		return "", lookup.Result{}, err
+	}
+	if temp.Valid {
+		r.Temperature = &temp.Float64
+	}
+	if fan.Valid {
+		r.FanSpeed = &fan.String
+	}
+	if swing.Valid {
+		r.Swing = &swing.String
+	}
+	return code, r, nil
+}
+
+// findCode queries a code of mode matching the candidate's temperature and fan speed (if set)
+// A code stored without a temperature or fan speed applies to any, but a code stored
+// with the requested value is preferred. Ties are ordered by ID so that wildcard
+// candidates resolve like the in-memory Cache.
+func (db *DB) findCode(ctx context.Context, modelID, mode string, c Candidate) (string, lookup.Result, error) {
 	query := `
-		SELECT ir_code 
+		SELECT ir_code, id, mode, temperature, fan_speed, swing
 		FROM ir_codes 
-		WHERE model_id = ? AND mode = ? AND temperature = ? AND fan_speed = ?
-	`
-	err := db.conn.QueryRowContext(ctx, query, modelID, mode, temperature, fanSpeed).Scan(&code)
+		WHERE model_id = ? AND mode = ?`
+	args := []interface{}{modelID, mode}
+	order := " ORDER BY"
+	if c.Temperature != nil {
+		query += " AND (temperature = ? OR temperature IS NULL)"
+		args = append(args, *c.Temperature)
+		order += " temperature IS NULL,"
+	}
+	if c.FanSpeed != nil {
+		query += " AND (fan_speed = ? OR fan_speed IS NULL)"
+		args = append(args, *c.FanSpeed)
+		order += " fan_speed IS NULL,"
+	}
+	query += order + " id LIMIT 1"
+
+	code, r, err := db.lookupRow(ctx, query, args...)
 	if err == nil {
 		logger.Debug("Found IR code in DB (length: %d bytes)", len(code))
 	}
-	return code, err
+	return code, r, err
 }
 
-// lookupModeTemp tries to find code matching mode + temperature (any fan speed)
-func (db *DB) lookupModeTemp(ctx context.Context, modelID, mode string, temperature int) (string, error) {
-	var code string
-	query := `
-		SELECT ir_code 
-		FROM ir_codes 
-		WHERE model_id = ? AND mode = ? AND temperature = ?
-		LIMIT 1
-	`
-	err := db.conn.QueryRowContext(ctx, query, modelID, mode, temperature).Scan(&code)
-	return code, err
+// countCodes counts the codes of a mode
+func (db *DB) countCodes(ctx context.Context, modelID, mode string) int {
+	var count int
+	checkQuery := `SELECT COUNT(*) FROM ir_codes WHERE model_id = ? AND mode = ?`
+	db.conn.QueryRowContext(ctx, checkQuery, modelID, mode).Scan(&count)
+	return count
 }
 
-// lookupModeOnly tries to find code matching mode only (any temp/fan)
-func (db *DB) lookupModeOnly(ctx context.Context, modelID, mode string) (string, error) {
-	var code string
-	query := `
-		SELECT ir_code 
-		FROM ir_codes 
-		WHERE model_id = ? AND mode = ?
-		LIMIT 1
-	`
-	err := db.conn.QueryRowContext(ctx, query, modelID, mode).Scan(&code)
-	return code, err
+// LookupOffCode retrieves the "off" command IR code
+// Returns an error with a lookup.StrategyMiss result if no off code is found
+func (db *DB) LookupOffCode(ctx context.Context, modelID string) (string, lookup.Result, error) {
+	logger.Debug("DB LookupOffCode: model=%s", modelID)
+	return db.lookupOffCode(ctx, db, modelID)
 }
 
-// getFanFallbacks returns alternative fan speeds to try
-// Order: current speed is removed, then try: low → medium → high → auto
-func getFanFallbacks(requestedFan string) []string {
-	allFans := []string{"low", "medium", "high", "auto"}
-	fallbacks := []string{}
+// lookupOffCode resolves the off code of a model against src
+func (db *DB) lookupOffCode(ctx context.Context, src codeSource, modelID string) (string, lookup.Result, error) {
+	return db.lookupPowerCode(ctx, src, modelID, "off", lookup.StrategyOff)
+}
 
-	// Add all fan speeds except the one already tried
-	for _, fan := range allFans {
-		if fan != requestedFan {
-			fallbacks = append(fallbacks, fan)
-		}
-	}
+// LookupOnCode retrieves the dedicated "on" command IR code (the "on" key of SmartIR files)
+// Returns an error with a lookup.StrategyMiss result if the model has no on code
+func (db *DB) LookupOnCode(ctx context.Context, modelID string) (string, lookup.Result, error) {
+	logger.Debug("DB LookupOnCode: model=%s", modelID)
+	return db.lookupOnCode(ctx, db, modelID)
+}
 
-	return fallbacks
+// lookupOnCode resolves the on code of a model against src
+func (db *DB) lookupOnCode(ctx context.Context, src codeSource, modelID string) (string, lookup.Result, error) {
+	return db.lookupPowerCode(ctx, src, modelID, "on", lookup.StrategyOn)
 }
 
-// LookupOffCode retrieves the "off" command IR code
-// Returns sql.ErrNoRows if no off code is found
-func (db *DB) LookupOffCode(ctx context.Context, modelID string) (string, error) {
-	logger.Debug("DB LookupOffCode: model=%s", modelID)
+// lookupPowerCode resolves the single code stored for mode ("off" or "on") against src
+func (db *DB) lookupPowerCode(ctx context.Context, src codeSource, modelID, mode, strategy string) (string, lookup.Result, error) {
+	start := time.Now()
 
-	var code string
-	query := `
-		SELECT ir_code 
-		FROM ir_codes 
-		WHERE model_id = ? AND mode = 'off'
-	`
-	err := db.conn.QueryRowContext(ctx, query, modelID).Scan(&code)
+	code, result, err := src.findCode(ctx, modelID, mode, Candidate{})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			logger.Warn("No off code found in DB for model=%s", modelID)
-			return "", fmt.Errorf("no off code found for model=%s", modelID)
+			logger.Warn("No %s code found in DB for model=%s", mode, modelID)
+			metrics.ObserveLookup(ctx, modelID, lookup.StrategyMiss, time.Since(start))
+			return "", lookup.Result{Strategy: lookup.StrategyMiss}, fmt.Errorf("no %s code found for model=%s", mode, modelID)
 		}
 		logger.Error("Database query failed: %v", err)
-		return "", fmt.Errorf("database query failed: %w", err)
+		return "", lookup.Result{}, fmt.Errorf("database query failed: %w", err)
 	}
 
-	logger.Debug("Found off code in DB (length: %d bytes)", len(code))
-	return code, nil
+	metrics.ObserveLookup(ctx, modelID, strategy, time.Since(start))
+	logger.Debug("Found %s code in DB (length: %d bytes)", mode, len(code))
+	result.Strategy = strategy
+	return code, result, nil
+}
+
+// LookupPrecision implements interfaces.IRDatabase from the model metadata
+func (db *DB) LookupPrecision(ctx context.Context, modelID string) (float64, error) {
+	model, err := db.GetModel(ctx, modelID)
+	if err != nil {
+		return 0, err
+	}
+	return model.Precision, nil
 }
 
 // GetModel retrieves model metadata
@@ -318,7 +344,7 @@ func (db *DB) GetModel(ctx context.Context, modelID string) (*Model, error) {
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("model %s not found", modelID)
+			return nil, fmt.Errorf("%w: %s", ErrModelNotFound, modelID)
 		}
 		return nil, fmt.Errorf("database query failed: %w", err)
 	}
@@ -350,6 +376,64 @@ func (db *DB) ListModels(ctx context.Context) ([]string, error) {
 	return models, nil
 }
 
+// ListCodes returns all IR codes of a model ordered by mode, temperature and fan speed
+func (db *DB) ListCodes(ctx context.Context, modelID string) ([]IRCode, error) {
+	query := `
+		SELECT id, model_id, mode, temperature, fan_speed, swing, ir_code
+		FROM ir_codes
+		WHERE model_id = ?
+		ORDER BY mode, temperature, fan_speed, swing
+	`
+	rows, err := db.conn.QueryContext(ctx, query, modelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query codes: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []IRCode
+	for rows.Next() {
+		var code IRCode
+		var temperature sql.NullFloat64
+		var fanSpeed, swing sql.NullString
+		if err := rows.Scan(&code.ID, &code.ModelID, &code.Mode, &temperature, &fanSpeed, &swing, &code.IRCode); err != nil {
+			return nil, fmt.Errorf("failed to scan code: %w", err)
+		}
+		if temperature.Valid {
+			code.Temperature = &temperature.Float64
+		}
+		if fanSpeed.Valid {
+			code.FanSpeed = &fanSpeed.String
+		}
+		if swing.Valid {
+			code.Swing = &swing.String
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// onModelChange registers fn to be called after the codes of a model change
+func (db *DB) onModelChange(fn func(modelID string)) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.listeners = append(db.listeners, fn)
+}
+
+// modelChanged notifies listeners that the codes of a model changed
+func (db *DB) modelChanged(modelID string) {
+	db.mu.RLock()
+	listeners := db.listeners
+	db.mu.RUnlock()
+	for _, fn := range listeners {
+		fn(modelID)
+	}
+}
+
 // Ping verifies the database connection is alive
 func (db *DB) Ping(ctx context.Context) error {
 	return db.conn.PingContext(ctx)
@@ -358,15 +442,19 @@ func (db *DB) Ping(ctx context.Context) error {
 // InsertCode inserts a single IR code into the database (for testing)
 func (db *DB) InsertCode(ctx context.Context, code *IRCode) error {
 	query := `
-		INSERT INTO ir_codes (model_id, mode, temperature, fan_speed, ir_code)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO ir_codes (model_id, mode, temperature, fan_speed, swing, ir_code)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`
 	_, err := db.conn.ExecContext(ctx, query,
 		code.ModelID,
 		code.Mode,
 		code.Temperature,
 		code.FanSpeed,
+		code.Swing,
 		code.IRCode,
 	)
+	if err == nil {
+		db.modelChanged(code.ModelID)
+	}
 	return err
 }
@@ -2,9 +2,14 @@ package database
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
 )
 
 func setupTestDB(t *testing.T) *DB {
@@ -60,6 +65,51 @@ func TestMigrate(t *testing.T) {
 	}
 }
 
+func TestMigrate_FromV1(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	// Simulate a v1 database (IR code tables only)
+	if _, err := db.conn.ExecContext(ctx, `
+		CREATE TABLE models (id INTEGER PRIMARY KEY AUTOINCREMENT, model_id TEXT NOT NULL UNIQUE);
+		CREATE TABLE ir_codes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, model_id TEXT NOT NULL, mode TEXT NOT NULL,
+			temperature INTEGER, fan_speed TEXT, ir_code TEXT NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(model_id, mode, temperature, fan_speed)
+		);
+		PRAGMA user_version = 1;
+	`); err != nil {
+		t.Fatalf("failed to create v1 schema: %v", err)
+	}
+
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	version, err := db.GetSchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to get version: %v", err)
+	}
+	if version != CurrentSchemaVersion {
+		t.Errorf("expected version %d, got %d", CurrentSchemaVersion, version)
+	}
+
+	if _, err := db.ListSchedules(ctx, "living_room"); err != nil {
+		t.Errorf("schedules table missing after migration: %v", err)
+	}
+	if _, err := db.GetTimer(ctx, "living_room"); err != nil {
+		t.Errorf("timers table missing after migration: %v", err)
+	}
+	if _, err := db.CommandHistory(ctx, "living_room", 0); err != nil {
+		t.Errorf("command_log table missing after migration: %v", err)
+	}
+}
+
 func TestLoadAndQuery(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -77,7 +127,7 @@ func TestLoadAndQuery(t *testing.T) {
 	}
 
 	// Query code
-	code, err := db.LookupCode(ctx, "1109", "cool", 21, "low")
+	code, _, err := db.LookupCode(ctx, "1109", "cool", 21, "low")
 	if err != nil {
 		t.Fatalf("LookupCode failed: %v", err)
 	}
@@ -86,11 +136,349 @@ func TestLoadAndQuery(t *testing.T) {
 	}
 
 	// Query off code
-	offCode, err := db.LookupOffCode(ctx, "1109")
+	offCode, _, err := db.LookupOffCode(ctx, "1109")
 	if err != nil {
 		t.Fatalf("LookupOffCode failed: %v", err)
 	}
 	if offCode == "" {
 		t.Error("expected non-empty off code")
 	}
+
+	// List all codes
+	codes, err := db.ListCodes(ctx, "1109")
+	if err != nil {
+		t.Fatalf("ListCodes failed: %v", err)
+	}
+	if len(codes) == 0 {
+		t.Fatal("expected codes for model 1109")
+	}
+	for _, code := range codes {
+		if code.Mode != "off" && (code.Temperature == nil || code.FanSpeed == nil) {
+			t.Errorf("code %d missing temperature/fan: %+v", code.ID, code)
+		}
+	}
+}
+
+// TestLoadFromJSON_Reload tests that loading a model again replaces its codes instead of duplicating them
+func TestLoadFromJSON_Reload(t *testing.T) {
+	if _, err := os.Stat(referenceFile); os.IsNotExist(err) {
+		t.Skipf("Reference file not found: %s", referenceFile)
+	}
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if err := db.LoadFromJSON(ctx, "1109", referenceFile); err != nil {
+		t.Fatalf("LoadFromJSON failed: %v", err)
+	}
+	first, err := db.ListCodes(ctx, "1109")
+	if err != nil {
+		t.Fatalf("ListCodes failed: %v", err)
+	}
+
+	if err := db.LoadFromJSON(ctx, "1109", referenceFile); err != nil {
+		t.Fatalf("Second LoadFromJSON failed: %v", err)
+	}
+	second, err := db.ListCodes(ctx, "1109")
+	if err != nil {
+		t.Fatalf("ListCodes failed: %v", err)
+	}
+	if len(second) != len(first) {
+		t.Errorf("Expected %d codes after reload, got %d", len(first), len(second))
+	}
+
+	// A file without codes is rejected
+	empty := filepath.Join(t.TempDir(), "1109.json")
+	if err := os.WriteFile(empty, []byte(`{"commandsEncoding": "Raw", "supportedController": "MQTT"}`), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := db.LoadFromJSON(ctx, "1109", empty); err == nil {
+		t.Error("Expected an error for a file without codes")
+	}
+}
+
+func TestModelIDFromFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"1109.json", "1109", true},
+		{"1109_tuya.json", "1109", true},
+		{"/path/to/1116.json", "1116", true},
+		{"_tuya.json", "", false},
+		{"README.md", "", false},
+		{"broadlink_to_tuya.py", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ModelIDFromFilename(tt.name)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ModelIDFromFilename(%q) = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// nestedCommands uses every nesting SmartIR files have: top-level on/off codes,
+// a swing level, codes per fan speed and codes for a whole mode
+const nestedCommands = `{
+	"off": "OFF", "on": "ON",
+	"cool": {"low": {"off": {"22": "COOL22", "23": "COOL23"}, "on": {"22": "COOL22-SWING"}}},
+	"fan_only": {"low": "FAN-LOW", "high": "FAN-HIGH"},
+	"dry": "DRY"
+}`
+
+func TestSmartIRCommands_Unmarshal(t *testing.T) {
+	var commands SmartIRCommands
+	if err := json.Unmarshal([]byte(nestedCommands), &commands); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	var paths []string
+	for _, code := range commands.Codes {
+		paths = append(paths, code.Path()+"="+code.Code)
+	}
+	want := []string{
+		"cool/low/off/22=COOL22", "cool/low/off/23=COOL23", "cool/low/on/22=COOL22-SWING",
+		"dry=DRY", "fan_only/high=FAN-HIGH", "fan_only/low=FAN-LOW", "off=OFF", "on=ON",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Codes = %v, want %v", paths, want)
+	}
+
+	// Branches the loader cannot store are skipped, keeping the rest of the file
+	unstorable := []string{
+		`{"off": "OFF", "cool": {"low": {"22": 22}}}`,                     // Not a code
+		`{"off": "OFF", "cool": {"low": {"22": {"on": "X"}}}}`,            // Nested below a temperature
+		`{"off": "OFF", "cool": {"low": {"on": {"quiet": {"22": "X"}}}}}`, // Deeper than fan speed and swing
+		`{"off": "OFF", "cool": null}`,
+	}
+	for _, data := range unstorable {
+		var commands SmartIRCommands
+		if err := json.Unmarshal([]byte(data), &commands); err != nil {
+			t.Errorf("Unmarshal(%s) failed: %v", data, err)
+			continue
+		}
+		if len(commands.Codes) != 1 || commands.Codes[0].Path() != "off" || len(commands.Skipped) != 1 {
+			t.Errorf("Unmarshal(%s) = %+v, want the off code and one skipped branch", data, commands)
+		}
+	}
+
+	// Only a commands value that is not an object fails
+	for _, data := range []string{`["OFF"]`, `"OFF"`} {
+		if err := json.Unmarshal([]byte(data), &commands); err == nil {
+			t.Errorf("Expected an error for %s", data)
+		}
+	}
+}
+
+// TestLoadFromJSON_HalfDegree tests that 0.5-precision files store and look up their half-degree codes
+func TestLoadFromJSON_HalfDegree(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	path := filepath.Join("testdata", "half_degree.json")
+	if err := db.LoadFromJSON(ctx, "9002", path); err != nil {
+		t.Fatalf("LoadFromJSON failed: %v", err)
+	}
+	if err := db.ReplaceModel(ctx, "9002", path); err != nil {
+		t.Fatalf("ReplaceModel failed: %v", err)
+	}
+
+	codes, err := db.ListCodes(ctx, "9002")
+	if err != nil {
+		t.Fatalf("ListCodes failed: %v", err)
+	}
+	if len(codes) != 7 {
+		t.Errorf("Expected off and 6 codes, got %d", len(codes))
+	}
+	for _, tt := range []struct {
+		mode string
+		temp float64
+		want string
+	}{
+		{"cool", 16, "COOL16"},
+		{"cool", 16.5, "COOL16.5"},
+		{"cool", 17, "COOL17"},
+		{"heat", 16.5, "HEAT16.5"},
+	} {
+		for name, find := range map[string]func(context.Context, string, string, float64, string) (string, lookup.Result, error){
+			"db": db.LookupCode, "cache": NewCache(db).LookupCode,
+		} {
+			code, result, err := find(ctx, "9002", tt.mode, tt.temp, "low")
+			if err != nil || code != tt.want || result.Strategy != lookup.StrategyExact || *result.Temperature != tt.temp {
+				t.Errorf("%s: %s/%g = %q (%+v, err %v), want exact %q", name, tt.mode, tt.temp, code, result, err, tt.want)
+			}
+		}
+	}
+}
+
+// TestLoadFromJSON_Nesting tests that every nesting is stored and looked up
+func TestLoadFromJSON_Nesting(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "9001.json")
+	content := `{"manufacturer": "Test", "supportedModels": ["T1"], "commandsEncoding": "Raw",
+		"supportedController": "MQTT", "minTemperature": 18, "maxTemperature": 30, "precision": 1,
+		"operationModes": ["cool", "fan_only", "dry"], "fanModes": ["low", "high"], "swingModes": ["on", "off"],
+		"commands": ` + nestedCommands + `}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write model: %v", err)
+	}
+
+	// Loading twice updates the codes stored with NULL levels instead of duplicating them
+	for range 2 {
+		if err := db.LoadFromJSON(ctx, "9001", path); err != nil {
+			t.Fatalf("LoadFromJSON failed: %v", err)
+		}
+	}
+	codes, err := db.ListCodes(ctx, "9001")
+	if err != nil {
+		t.Fatalf("ListCodes failed: %v", err)
+	}
+	if len(codes) != 8 {
+		t.Errorf("Expected 8 codes, got %d", len(codes))
+	}
+
+	cache := NewCache(db)
+	lookups := []struct {
+		mode, fan string
+		temp      float64
+		want      string
+	}{
+		{"cool", "low", 22, "COOL22-SWING"}, // "on" is the first declared swing mode
+		{"cool", "low", 23, "COOL23"},
+		{"fan_only", "high", 25, "FAN-HIGH"},
+		{"dry", "high", 20, "DRY"},
+	}
+	for _, tt := range lookups {
+		for name, find := range map[string]func(context.Context, string, string, float64, string) (string, lookup.Result, error){
+			"db": db.LookupCode, "cache": cache.LookupCode,
+		} {
+			code, result, err := find(ctx, "9001", tt.mode, tt.temp, tt.fan)
+			if err != nil || code != tt.want || result.Strategy != lookup.StrategyExact {
+				t.Errorf("%s: %s/%s/%g = %q (%s, err %v), want exact %q", name, tt.mode, tt.fan, tt.temp, code, result.Strategy, err, tt.want)
+			}
+		}
+	}
+
+	for name, find := range map[string]func(context.Context, string) (string, lookup.Result, error){
+		"db": db.LookupOnCode, "cache": cache.LookupOnCode,
+	} {
+		if code, result, err := find(ctx, "9001"); err != nil || code != "ON" || result.Strategy != lookup.StrategyOn {
+			t.Errorf("%s: on code = %q (%s, err %v), want ON", name, code, result.Strategy, err)
+		}
+	}
+
+	// 1116 nests its codes by swing mode
+	reference := filepath.Join("..", "..", "docs", "smartir", "reference", "1116.json")
+	if _, err := os.Stat(reference); os.IsNotExist(err) {
+		t.Skipf("Reference file not found: %s", reference)
+	}
+	if err := db.LoadFromJSON(ctx, "1116", reference); err != nil {
+		t.Fatalf("LoadFromJSON failed: %v", err)
+	}
+	if codes, _ := db.ListCodes(ctx, "1116"); len(codes) != 205 {
+		t.Errorf("Expected 205 codes for 1116, got %d", len(codes))
+	}
+	if _, result, err := db.LookupCode(ctx, "1116", "cool", 22, "level2"); err != nil || result.Swing == nil || *result.Swing != "off" {
+		t.Errorf("Expected 1116 cool/level2/22 with swing off, got %s (err %v)", result, err)
+	}
+	if _, result, err := db.LookupOnCode(ctx, "1116"); err == nil || result.Strategy != lookup.StrategyMiss {
+		t.Errorf("Expected no on code for 1116, got %s (err %v)", result, err)
+	}
+}
+
+// writeTestModel writes a Tuya-format SmartIR file with the given cool/low codes by temperature
+func writeTestModel(t *testing.T, codes string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "9000.json")
+	content := `{"manufacturer": "Test", "supportedModels": ["T1"], "commandsEncoding": "Raw",
+		"supportedController": "MQTT", "minTemperature": 18, "maxTemperature": 30, "precision": 1,
+		"operationModes": ["cool"], "fanModes": ["low"],
+		"commands": {"off": "OFF", "cool": {"low": {` + codes + `}}}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write model: %v", err)
+	}
+	return path
+}
+
+// TestReplaceModel tests that replacing a model drops codes missing from the new file
+func TestReplaceModel(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	cache := NewCache(db)
+
+	if err := db.LoadFromJSON(ctx, "9000", writeTestModel(t, `"22": "COOL22", "23": "COOL23"`)); err != nil {
+		t.Fatalf("LoadFromJSON failed: %v", err)
+	}
+	if _, _, err := cache.LookupCode(ctx, "9000", "cool", 23, "low"); err != nil {
+		t.Fatalf("Expected COOL23 before replace: %v", err)
+	}
+
+	if err := db.ReplaceModel(ctx, "9000", writeTestModel(t, `"22": "COOL22-NEW"`)); err != nil {
+		t.Fatalf("ReplaceModel failed: %v", err)
+	}
+	codes, err := db.ListCodes(ctx, "9000")
+	if err != nil {
+		t.Fatalf("ListCodes failed: %v", err)
+	}
+	if len(codes) != 2 {
+		t.Errorf("Expected off and cool 22 after replace, got %d codes", len(codes))
+	}
+	if _, _, err := cache.LookupCode(ctx, "9000", "cool", 23, "low"); err == nil {
+		t.Error("Expected cached COOL23 to be gone after replace")
+	}
+	if code, _, err := cache.LookupCode(ctx, "9000", "cool", 22, "low"); err != nil || code != "COOL22-NEW" {
+		t.Errorf("Expected COOL22-NEW, got %q (err %v)", code, err)
+	}
+
+	// A file that fails to import keeps the model untouched
+	if err := db.ReplaceModel(ctx, "9000", writeTestModel(t, `"22": "COOL22", "23":`)); err == nil {
+		t.Fatal("Expected an error for broken JSON")
+	}
+	if after, _ := db.ListCodes(ctx, "9000"); len(after) != 2 {
+		t.Errorf("Expected the model to be kept after a failed replace, got %d codes", len(after))
+	}
+
+	// So does a well-formed file with an entry the loader would skip
+	if err := db.ReplaceModel(ctx, "9000", writeTestModel(t, `"22": 22`)); err == nil {
+		t.Fatal("Expected an error for a skipped entry")
+	}
+	if code, _, err := cache.LookupCode(ctx, "9000", "cool", 22, "low"); err != nil || code != "COOL22-NEW" {
+		t.Errorf("Expected COOL22-NEW to be kept, got %q (err %v)", code, err)
+	}
+}
+
+// TestDeleteModel tests that deleting a model cascades to its IR codes
+func TestDeleteModel(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if err := db.LoadFromJSON(ctx, "9000", writeTestModel(t, `"22": "COOL22"`)); err != nil {
+		t.Fatalf("LoadFromJSON failed: %v", err)
+	}
+	if err := db.DeleteModel(ctx, "9000"); err != nil {
+		t.Fatalf("DeleteModel failed: %v", err)
+	}
+
+	if _, err := db.GetModel(ctx, "9000"); err == nil {
+		t.Error("Expected the model to be gone")
+	}
+	var count int
+	if err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM ir_codes WHERE model_id = ?`, "9000").Scan(&count); err != nil {
+		t.Fatalf("Failed to count codes: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected codes to be deleted with the model, got %d", count)
+	}
+
+	if err := db.DeleteModel(ctx, "9000"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound, got %v", err)
+	}
 }
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
+)
+
+// Fan fallback orders (see FallbackPolicy.Fan)
+const (
+	FanFallbackFixed   = "fixed"   // low → medium → high → auto, skipping the requested speed
+	FanFallbackNearest = "nearest" // Closest speed by position in FanOrder (slower first on ties), then auto
+)
+
+// DefaultPolicyModel is the policies file key applying to models without their own policy
+const DefaultPolicyModel = "default"
+
+// DefaultFanOrder ranks fan speeds from slowest to fastest for FanFallbackNearest
+var DefaultFanOrder = []string{"low", "medium", "high"}
+
+// defaultTemperatureRequiredModes are matched only with their temperature unless TemperatureRange allows otherwise
+var defaultTemperatureRequiredModes = []string{"heat", "cool", "auto"}
+
+// FallbackPolicy decides how LookupCode approximates a state that has no exact code
+// The zero value is the default policy (fixed fan order, exact temperatures only)
+type FallbackPolicy struct {
+	Fan                      string   `json:"fan,omitempty"`                        // FanFallback* (default fixed)
+	FanOrder                 []string `json:"fan_order,omitempty"`                  // Slowest to fastest (default DefaultFanOrder)
+	TemperatureRange         int      `json:"temperature_range,omitempty"`          // Try temperatures up to N degrees away (0 = exact only)
+	TemperatureRequiredModes []string `json:"temperature_required_modes,omitempty"` // Default heat, cool, auto; other modes fall back to any code of the mode
+	Strict                   bool     `json:"strict,omitempty"`                     // Exact matches only: never send an approximation
+}
+
+// Candidate is one step of a fallback plan
+// Nil fields match any stored value
+type Candidate struct {
+	Strategy    string   // Reported when this candidate matches (see lookup.Strategy*)
+	Temperature *float64 // Required temperature, nil = any
+	FanSpeed    *string  // Required fan speed, nil = any
+}
+
+// Validate checks the policy fields
+func (p FallbackPolicy) Validate() error {
+	switch p.Fan {
+	case "", FanFallbackFixed, FanFallbackNearest:
+	default:
+		return fmt.Errorf("invalid fan fallback %q (must be %s or %s)", p.Fan, FanFallbackFixed, FanFallbackNearest)
+	}
+	if p.TemperatureRange < 0 {
+		return fmt.Errorf("invalid temperature range %d (must be >= 0)", p.TemperatureRange)
+	}
+	return nil
+}
+
+// Plan returns the candidates tried, in order, to find a code for a state
+// The first candidate is always the exact match.
+func (p FallbackPolicy) Plan(mode string, temperature float64, fanSpeed string) []Candidate {
+	plan := []Candidate{{Strategy: lookup.StrategyExact, Temperature: &temperature, FanSpeed: &fanSpeed}}
+	if p.Strict {
+		return plan
+	}
+
+	// Same temperature, other fan speeds
+	if fanSpeed != "" {
+		for _, fan := range p.fanFallbacks(fanSpeed) {
+			plan = append(plan, Candidate{Strategy: lookup.StrategyFan, Temperature: &temperature, FanSpeed: &fan})
+		}
+	}
+
+	// Modes without a meaningful temperature (fan_only, dry) take any code of the mode
+	if !p.requiresTemperature(mode) {
+		return append(plan, Candidate{Strategy: lookup.StrategyModeOnly})
+	}
+
+	plan = append(plan, Candidate{Strategy: lookup.StrategyModeTemp, Temperature: &temperature})
+
+	// Nearest temperatures, below first on ties, preferring the requested fan speed
+	for delta := 1; delta <= p.TemperatureRange; delta++ {
+		for _, temp := range []float64{temperature - float64(delta), temperature + float64(delta)} {
+			if fanSpeed != "" {
+				plan = append(plan, Candidate{Strategy: lookup.StrategyNearestTemp, Temperature: &temp, FanSpeed: &fanSpeed})
+			}
+			plan = append(plan, Candidate{Strategy: lookup.StrategyNearestTemp, Temperature: &temp})
+		}
+	}
+	return plan
+}
+
+// requiresTemperature reports whether mode is only matched with a stored temperature
+func (p FallbackPolicy) requiresTemperature(mode string) bool {
+	modes := p.TemperatureRequiredModes
+	if modes == nil {
+		modes = defaultTemperatureRequiredModes
+	}
+	return slices.Contains(modes, mode)
+}
+
+// fanFallbacks returns the other fan speeds to try, in order
+func (p FallbackPolicy) fanFallbacks(requestedFan string) []string {
+	if p.Fan != FanFallbackNearest {
+		return getFanFallbacks(requestedFan)
+	}
+
+	order := p.FanOrder
+	if len(order) == 0 {
+		order = DefaultFanOrder
+	}
+	index := slices.Index(order, requestedFan)
+	if index < 0 {
+		return getFanFallbacks(requestedFan) // No ordinal (e.g., auto)
+	}
+
+	fallbacks := []string{}
+	for delta := 1; delta < len(order); delta++ {
+		if index-delta >= 0 {
+			fallbacks = append(fallbacks, order[index-delta])
+		}
+		if index+delta < len(order) {
+			fallbacks = append(fallbacks, order[index+delta])
+		}
+	}
+	return append(fallbacks, "auto")
+}
+
+// getFanFallbacks returns alternative fan speeds to try
+// Order: current speed is removed, then try: low → medium → high → auto
+func getFanFallbacks(requestedFan string) []string {
+	allFans := []string{"low", "medium", "high", "auto"}
+	fallbacks := []string{}
+
+	// Add all fan speeds except the one already tried
+	for _, fan := range allFans {
+		if fan != requestedFan {
+			fallbacks = append(fallbacks, fan)
+		}
+	}
+
+	return fallbacks
+}
+
+// LoadFallbackPolicies reads a JSON object mapping model IDs (or DefaultPolicyModel) to policies
+func LoadFallbackPolicies(path string) (map[string]FallbackPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fallback policy file: %w", err)
+	}
+
+	var policies map[string]FallbackPolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse fallback policy file %s: %w", path, err)
+	}
+
+	for model, p := range policies {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("fallback policy for %s: %w", model, err)
+		}
+	}
+	return policies, nil
+}
+
+// SetFallbackPolicy sets the fallback policy of a model (DefaultPolicyModel for all others)
+func (db *DB) SetFallbackPolicy(modelID string, p FallbackPolicy) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.policies == nil {
+		db.policies = make(map[string]FallbackPolicy)
+	}
+	db.policies[modelID] = p
+}
+
+// FallbackPolicy returns the fallback policy applied to a model
+func (db *DB) FallbackPolicy(modelID string) FallbackPolicy {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if p, ok := db.policies[modelID]; ok {
+		return p
+	}
+	return db.policies[DefaultPolicyModel]
+}
@@ -2,7 +2,14 @@ package database
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
+	"github.com/diogoaguiar/hvac-manager/internal/metrics"
 )
 
 // TestLookupCode_FanFallback tests fan speed fallback logic
@@ -26,7 +33,7 @@ func TestLookupCode_FanFallback(t *testing.T) {
 	err = db.InsertCode(ctx, &IRCode{
 		ModelID:     "test-model",
 		Mode:        "heat",
-		Temperature: intPtr(22),
+		Temperature: tempPtr(22),
 		FanSpeed:    strPtr("low"),
 		IRCode:      "test-code-low",
 	})
@@ -35,7 +42,7 @@ func TestLookupCode_FanFallback(t *testing.T) {
 	}
 
 	// Test: Request "auto" fan, should fallback to "low"
-	code, err := db.LookupCode(ctx, "test-model", "heat", 22, "auto")
+	code, _, err := db.LookupCode(ctx, "test-model", "heat", 22, "auto")
 	if err != nil {
 		t.Fatalf("Expected fallback to succeed, got error: %v", err)
 	}
@@ -63,7 +70,7 @@ func TestLookupCode_TemperatureRequiredModes(t *testing.T) {
 
 	// Insert codes for heat mode with different fan speeds
 	codes := []struct {
-		temp int
+		temp float64
 		fan  string
 		code string
 	}{
@@ -76,7 +83,7 @@ func TestLookupCode_TemperatureRequiredModes(t *testing.T) {
 		err := db.InsertCode(ctx, &IRCode{
 			ModelID:     "test-model",
 			Mode:        "heat",
-			Temperature: intPtr(tc.temp),
+			Temperature: tempPtr(tc.temp),
 			FanSpeed:    strPtr(tc.fan),
 			IRCode:      tc.code,
 		})
@@ -88,7 +95,7 @@ func TestLookupCode_TemperatureRequiredModes(t *testing.T) {
 	tests := []struct {
 		name        string
 		mode        string
-		temp        int
+		temp        float64
 		fan         string
 		expectCode  string
 		expectError bool
@@ -125,7 +132,7 @@ func TestLookupCode_TemperatureRequiredModes(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			code, err := db.LookupCode(ctx, "test-model", tt.mode, tt.temp, tt.fan)
+			code, _, err := db.LookupCode(ctx, "test-model", tt.mode, tt.temp, tt.fan)
 			if tt.expectError {
 				if err == nil {
 					t.Errorf("Expected error, got code: %s", code)
@@ -163,7 +170,7 @@ func TestLookupCode_ModeOnlyFallback(t *testing.T) {
 	err = db.InsertCode(ctx, &IRCode{
 		ModelID:     "test-model",
 		Mode:        "fan_only",
-		Temperature: intPtr(25),
+		Temperature: tempPtr(25),
 		FanSpeed:    strPtr("high"),
 		IRCode:      "fan-only-code",
 	})
@@ -175,7 +182,7 @@ func TestLookupCode_ModeOnlyFallback(t *testing.T) {
 	err = db.InsertCode(ctx, &IRCode{
 		ModelID:     "test-model",
 		Mode:        "dry",
-		Temperature: intPtr(24),
+		Temperature: tempPtr(24),
 		FanSpeed:    strPtr("low"),
 		IRCode:      "dry-code",
 	})
@@ -186,7 +193,7 @@ func TestLookupCode_ModeOnlyFallback(t *testing.T) {
 	tests := []struct {
 		name       string
 		mode       string
-		temp       int
+		temp       float64
 		fan        string
 		expectCode string
 	}{
@@ -208,7 +215,7 @@ func TestLookupCode_ModeOnlyFallback(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			code, err := db.LookupCode(ctx, "test-model", tt.mode, tt.temp, tt.fan)
+			code, _, err := db.LookupCode(ctx, "test-model", tt.mode, tt.temp, tt.fan)
 			if err != nil {
 				t.Fatalf("Expected fallback to succeed, got error: %v", err)
 			}
@@ -240,7 +247,7 @@ func TestLookupCode_CompleteFallbackChain(t *testing.T) {
 	err = db.InsertCode(ctx, &IRCode{
 		ModelID:     "test-model",
 		Mode:        "cool",
-		Temperature: intPtr(21),
+		Temperature: tempPtr(21),
 		FanSpeed:    strPtr("high"),
 		IRCode:      "cool-21-high",
 	})
@@ -250,7 +257,7 @@ func TestLookupCode_CompleteFallbackChain(t *testing.T) {
 
 	// Request: cool/21/auto (auto not available)
 	// Should fallback: auto → low (fail) → medium (fail) → high (success)
-	code, err := db.LookupCode(ctx, "test-model", "cool", 21, "auto")
+	code, _, err := db.LookupCode(ctx, "test-model", "cool", 21, "auto")
 	if err != nil {
 		t.Fatalf("Expected fallback to succeed, got error: %v", err)
 	}
@@ -299,10 +306,346 @@ func TestGetFanFallbacks(t *testing.T) {
 }
 
 // Helper functions
-func intPtr(i int) *int {
-	return &i
+func tempPtr(t float64) *float64 {
+	return &t
 }
 
 func strPtr(s string) *string {
 	return &s
 }
+
+// TestLookupCode_StrategyMetrics tests that each lookup is counted under the strategy that resolved it
+func TestLookupCode_StrategyMetrics(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	// Tag lookups with a device only this test uses (metrics are process-wide)
+	ctx := metrics.WithDevice(context.Background(), "metrics-test")
+
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO models (model_id, manufacturer, supported_models, commands_encoding, 
+			supported_controller, min_temperature, max_temperature, precision, operation_modes, fan_modes) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, "test-model", "Test", "[]", "Raw", "MQTT", 16, 30, 1.0, "[]", "[]")
+	if err != nil {
+		t.Fatalf("Failed to insert model: %v", err)
+	}
+
+	codes := []IRCode{
+		{ModelID: "test-model", Mode: "heat", Temperature: tempPtr(22), FanSpeed: strPtr("low"), IRCode: "heat-22-low"},
+		{ModelID: "test-model", Mode: "cool", Temperature: tempPtr(24), FanSpeed: strPtr("quiet"), IRCode: "cool-24-quiet"},
+		{ModelID: "test-model", Mode: "dry", Temperature: tempPtr(24), FanSpeed: strPtr("quiet"), IRCode: "dry"},
+		{ModelID: "test-model", Mode: "off", IRCode: "off"},
+	}
+	for i := range codes {
+		if err := db.InsertCode(ctx, &codes[i]); err != nil {
+			t.Fatalf("Failed to insert test code: %v", err)
+		}
+	}
+
+	lookups := []struct {
+		mode     string
+		temp     float64
+		fan      string
+		strategy string
+	}{
+		{"heat", 22, "low", lookup.StrategyExact},
+		{"heat", 22, "high", lookup.StrategyFan},
+		{"cool", 24, "auto", lookup.StrategyModeTemp},
+		{"dry", 20, "auto", lookup.StrategyModeOnly},
+		{"cool", 18, "auto", lookup.StrategyMiss},
+	}
+	for _, l := range lookups {
+		db.LookupCode(ctx, "test-model", l.mode, l.temp, l.fan)
+		if got := metrics.LookupsTotal.Value("metrics-test", "test-model", l.strategy); got != 1 {
+			t.Errorf("%s/%g/%s: %s count = %v, want 1", l.mode, l.temp, l.fan, l.strategy, got)
+		}
+	}
+
+	if _, _, err := db.LookupOffCode(ctx, "test-model"); err != nil {
+		t.Fatalf("LookupOffCode failed: %v", err)
+	}
+	if got := metrics.LookupsTotal.Value("metrics-test", "test-model", lookup.StrategyOff); got != 1 {
+		t.Errorf("off count = %v, want 1", got)
+	}
+
+	if got := metrics.LookupDuration.Count("metrics-test", "test-model"); got != 6 {
+		t.Errorf("Lookup latency observations = %d, want 6", got)
+	}
+}
+
+// TestLookupCode_Result tests that the result describes the matched row
+func TestLookupCode_Result(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO models (model_id, manufacturer, supported_models, commands_encoding, 
+			supported_controller, min_temperature, max_temperature, precision, operation_modes, fan_modes) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, "test-model", "Test", "[]", "Raw", "MQTT", 16, 30, 1.0, "[]", "[]")
+	if err != nil {
+		t.Fatalf("Failed to insert model: %v", err)
+	}
+
+	codes := []IRCode{
+		{ModelID: "test-model", Mode: "heat", Temperature: tempPtr(22), FanSpeed: strPtr("low"), IRCode: "heat-22-low"},
+		{ModelID: "test-model", Mode: "cool", Temperature: tempPtr(24), FanSpeed: strPtr("quiet"), IRCode: "cool-24-quiet"},
+		{ModelID: "test-model", Mode: "dry", Temperature: tempPtr(24), FanSpeed: strPtr("quiet"), IRCode: "dry"},
+		{ModelID: "test-model", Mode: "off", IRCode: "off"},
+	}
+	for i := range codes {
+		if err := db.InsertCode(ctx, &codes[i]); err != nil {
+			t.Fatalf("Failed to insert test code: %v", err)
+		}
+	}
+	stored, err := db.ListCodes(ctx, "test-model")
+	if err != nil {
+		t.Fatalf("ListCodes failed: %v", err)
+	}
+	ids := map[string]int64{}
+	for _, c := range stored {
+		ids[c.IRCode] = int64(c.ID)
+	}
+
+	tests := []struct {
+		mode      string
+		temp      float64
+		fan       string
+		wantCode  string
+		wantTemp  float64
+		wantFan   string
+		wantStrat string
+	}{
+		{"heat", 22, "low", "heat-22-low", 22, "low", lookup.StrategyExact},
+		{"heat", 22, "high", "heat-22-low", 22, "low", lookup.StrategyFan},
+		{"cool", 24, "auto", "cool-24-quiet", 24, "quiet", lookup.StrategyModeTemp},
+		{"dry", 20, "auto", "dry", 24, "quiet", lookup.StrategyModeOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.fan, func(t *testing.T) {
+			code, result, err := db.LookupCode(ctx, "test-model", tt.mode, tt.temp, tt.fan)
+			if err != nil {
+				t.Fatalf("LookupCode failed: %v", err)
+			}
+			if code != tt.wantCode || result.CodeID != ids[tt.wantCode] || result.Mode != tt.mode ||
+				result.Temperature == nil || *result.Temperature != tt.wantTemp ||
+				result.FanSpeed == nil || *result.FanSpeed != tt.wantFan || result.Strategy != tt.wantStrat {
+				t.Errorf("Unexpected result %+v for code %s", result, code)
+			}
+			if result.Fallback() != (tt.wantStrat != lookup.StrategyExact) {
+				t.Errorf("Fallback() = %v for strategy %s", result.Fallback(), result.Strategy)
+			}
+		})
+	}
+
+	// A miss still reports its strategy
+	_, result, err := db.LookupCode(ctx, "test-model", "cool", 18, "auto")
+	if err == nil || result.Strategy != lookup.StrategyMiss || result.CodeID != 0 {
+		t.Errorf("Expected miss, got %+v (err %v)", result, err)
+	}
+
+	_, result, err = db.LookupOffCode(ctx, "test-model")
+	if err != nil {
+		t.Fatalf("LookupOffCode failed: %v", err)
+	}
+	if result.CodeID != ids["off"] || result.Strategy != lookup.StrategyOff || result.Temperature != nil || result.Fallback() {
+		t.Errorf("Unexpected off result: %+v", result)
+	}
+}
+
+// TestFallbackPolicy_Plan tests the candidates each policy tries, in order
+func TestFallbackPolicy_Plan(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   FallbackPolicy
+		mode     string
+		fan      string
+		expected []string // strategy:temp:fan, "*" = any
+	}{
+		{
+			name:     "Default heat",
+			mode:     "heat",
+			fan:      "high",
+			expected: []string{"exact:22:high", "fan:22:low", "fan:22:medium", "fan:22:auto", "mode_temp:22:*"},
+		},
+		{
+			name:     "Default dry",
+			mode:     "dry",
+			fan:      "auto",
+			expected: []string{"exact:22:auto", "fan:22:low", "fan:22:medium", "fan:22:high", "mode_only:*:*"},
+		},
+		{
+			name:     "Strict",
+			policy:   FallbackPolicy{Strict: true},
+			mode:     "dry",
+			fan:      "auto",
+			expected: []string{"exact:22:auto"},
+		},
+		{
+			name:     "Nearest fan",
+			policy:   FallbackPolicy{Fan: FanFallbackNearest},
+			mode:     "cool",
+			fan:      "high",
+			expected: []string{"exact:22:high", "fan:22:medium", "fan:22:low", "fan:22:auto", "mode_temp:22:*"},
+		},
+		{
+			name:   "Nearest temperature",
+			policy: FallbackPolicy{TemperatureRange: 1},
+			mode:   "cool",
+			fan:    "auto",
+			expected: []string{"exact:22:auto", "fan:22:low", "fan:22:medium", "fan:22:high", "mode_temp:22:*",
+				"nearest_temp:21:auto", "nearest_temp:21:*", "nearest_temp:23:auto", "nearest_temp:23:*"},
+		},
+		{
+			name:     "No temperature-required modes",
+			policy:   FallbackPolicy{TemperatureRequiredModes: []string{}},
+			mode:     "heat",
+			fan:      "low",
+			expected: []string{"exact:22:low", "fan:22:medium", "fan:22:high", "fan:22:auto", "mode_only:*:*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := tt.policy.Plan(tt.mode, 22, tt.fan)
+			var got []string
+			for _, c := range plan {
+				temp, fan := "*", "*"
+				if c.Temperature != nil {
+					temp = fmt.Sprint(*c.Temperature)
+				}
+				if c.FanSpeed != nil {
+					fan = *c.FanSpeed
+				}
+				got = append(got, c.Strategy+":"+temp+":"+fan)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Plan = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestFallbackPolicy_NearestFan tests the nearest fan order by ordinal
+func TestFallbackPolicy_NearestFan(t *testing.T) {
+	policy := FallbackPolicy{Fan: FanFallbackNearest}
+	tests := []struct {
+		requested string
+		expected  []string
+	}{
+		{"low", []string{"medium", "high", "auto"}},
+		{"medium", []string{"low", "high", "auto"}},
+		{"high", []string{"medium", "low", "auto"}},
+		{"auto", []string{"low", "medium", "high"}}, // No ordinal: fixed order
+	}
+
+	for _, tt := range tests {
+		if got := policy.fanFallbacks(tt.requested); !slices.Equal(got, tt.expected) {
+			t.Errorf("%s: fallbacks = %v, want %v", tt.requested, got, tt.expected)
+		}
+	}
+
+	custom := FallbackPolicy{Fan: FanFallbackNearest, FanOrder: []string{"quiet", "low", "mid", "high", "turbo"}}
+	if got := custom.fanFallbacks("turbo"); !slices.Equal(got, []string{"high", "mid", "low", "quiet", "auto"}) {
+		t.Errorf("Custom order fallbacks = %v", got)
+	}
+}
+
+// TestLookupCode_Policies runs the TestLookupCode_TemperatureRequiredModes scenarios under each policy
+func TestLookupCode_Policies(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO models (model_id, manufacturer, supported_models, commands_encoding, 
+			supported_controller, min_temperature, max_temperature, precision, operation_modes, fan_modes) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, "test-model", "Test", "[]", "Raw", "MQTT", 16, 30, 1.0, "[]", "[]")
+	if err != nil {
+		t.Fatalf("Failed to insert model: %v", err)
+	}
+
+	codes := []IRCode{
+		{ModelID: "test-model", Mode: "heat", Temperature: tempPtr(22), FanSpeed: strPtr("low"), IRCode: "heat-22-low"},
+		{ModelID: "test-model", Mode: "heat", Temperature: tempPtr(22), FanSpeed: strPtr("medium"), IRCode: "heat-22-med"},
+		{ModelID: "test-model", Mode: "heat", Temperature: tempPtr(23), FanSpeed: strPtr("low"), IRCode: "heat-23-low"},
+	}
+	for i := range codes {
+		if err := db.InsertCode(ctx, &codes[i]); err != nil {
+			t.Fatalf("Failed to insert test code: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		policy       FallbackPolicy
+		temp         float64
+		fan          string
+		expectCode   string
+		expectResult string // Strategy, empty = miss
+	}{
+		{"Default high falls back to low", FallbackPolicy{}, 22, "high", "heat-22-low", lookup.StrategyFan},
+		{"Nearest high falls back to medium", FallbackPolicy{Fan: FanFallbackNearest}, 22, "high", "heat-22-med", lookup.StrategyFan},
+		{"Strict exact match", FallbackPolicy{Strict: true}, 22, "low", "heat-22-low", lookup.StrategyExact},
+		{"Strict refuses fan fallback", FallbackPolicy{Strict: true}, 22, "auto", "", ""},
+		{"Default refuses other temperature", FallbackPolicy{}, 24, "low", "", ""},
+		{"Nearest temperature within range", FallbackPolicy{TemperatureRange: 1}, 24, "low", "heat-23-low", lookup.StrategyNearestTemp},
+		{"Nearest temperature out of range", FallbackPolicy{TemperatureRange: 1}, 25, "low", "", ""},
+		{"Nearest temperature prefers requested fan", FallbackPolicy{TemperatureRange: 1}, 21, "medium", "heat-22-med", lookup.StrategyNearestTemp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.SetFallbackPolicy("test-model", tt.policy)
+			code, result, err := db.LookupCode(ctx, "test-model", "heat", tt.temp, tt.fan)
+			if tt.expectCode == "" {
+				if err == nil || result.Strategy != lookup.StrategyMiss {
+					t.Errorf("Expected miss, got code %s (%+v)", code, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if code != tt.expectCode || result.Strategy != tt.expectResult {
+				t.Errorf("Got %s (%s), want %s (%s)", code, result.Strategy, tt.expectCode, tt.expectResult)
+			}
+		})
+	}
+
+	// Per-model policies take precedence over the default policy
+	db.SetFallbackPolicy("test-model", FallbackPolicy{Fan: FanFallbackNearest})
+	db.SetFallbackPolicy(DefaultPolicyModel, FallbackPolicy{Strict: true})
+	if code, _, _ := db.LookupCode(ctx, "test-model", "heat", 22, "high"); code != "heat-22-med" {
+		t.Errorf("Expected model policy, got %q", code)
+	}
+	if db.FallbackPolicy("other-model").Strict != true {
+		t.Error("Expected default policy for models without their own")
+	}
+}
+
+func TestLoadFallbackPolicies(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.json")
+	os.WriteFile(valid, []byte(`{"default": {"fan": "nearest"}, "1109": {"strict": true}}`), 0o644)
+	policies, err := LoadFallbackPolicies(valid)
+	if err != nil {
+		t.Fatalf("LoadFallbackPolicies failed: %v", err)
+	}
+	if policies[DefaultPolicyModel].Fan != FanFallbackNearest || !policies["1109"].Strict {
+		t.Errorf("Unexpected policies: %+v", policies)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`{"1109": {"fan": "fastest"}}`), 0o644)
+	if _, err := LoadFallbackPolicies(invalid); err == nil {
+		t.Error("Expected error for invalid fan fallback")
+	}
+}
@@ -7,6 +7,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
 )
 
 // SmartIRFile represents the structure of a SmartIR JSON file
@@ -20,84 +25,122 @@ type SmartIRFile struct {
 	Precision           float64         `json:"precision"`
 	OperationModes      []string        `json:"operationModes"`
 	FanModes            []string        `json:"fanModes"`
+	SwingModes          []string        `json:"swingModes"`
 	Commands            SmartIRCommands `json:"commands"`
 }
 
 // SmartIRCommands represents the nested command structure
-// Can contain either a direct "off" string or nested mode → fan → temp → code
+// Codes sit at any depth of mode → fan speed → swing → temperature: "off" and "on"
+// are top-level strings, fan_only and dry often stop at the fan speed or the mode.
 type SmartIRCommands struct {
-	Off   string                                  `json:"off,omitempty"`
-	Modes map[string]map[string]map[string]string `json:"-"` // Populated during custom unmarshal
+	Codes   []SmartIRCode // By path in the file (see walkCommands)
+	Skipped []string      // Branches the loader cannot store, as "path: reason"
+}
+
+// SmartIRCode is one code of a SmartIR file and the state it applies to
+// Levels the file does not nest down to are nil, e.g., Temperature for a code given per fan speed.
+type SmartIRCode struct {
+	Mode        string
+	FanSpeed    *string
+	Swing       *string
+	Temperature *float64
+	Code        string
+}
+
+// Path returns where the code sits in the file, e.g., "cool/low/on/22"
+func (c SmartIRCode) Path() string {
+	path := c.Mode
+	if c.FanSpeed != nil {
+		path += "/" + *c.FanSpeed
+	}
+	if c.Swing != nil {
+		path += "/" + *c.Swing
+	}
+	if c.Temperature != nil {
+		path += fmt.Sprintf("/%g", *c.Temperature)
+	}
+	return path
 }
 
-// UnmarshalJSON custom unmarshaler to handle the complex nested structure
+// UnmarshalJSON parses every code of the commands object
+// Only a commands value that is not an object is an error: branches the loader cannot
+// store (e.g., codes nested below a temperature) are listed in Skipped, while the linter reports them all.
 func (c *SmartIRCommands) UnmarshalJSON(data []byte) error {
-	// First, try to unmarshal into a map to inspect structure
-	var raw map[string]interface{}
-	if err := json.Unmarshal(data, &raw); err != nil {
+	var codes []SmartIRCode
+	var skipped []string
+	err := walkCommands(data,
+		func(_ string, code SmartIRCode) { codes = append(codes, code) },
+		func(path, message string) { skipped = append(skipped, fmt.Sprintf("commands/%s: %s", path, message)) })
+	if err != nil {
 		return err
 	}
 
-	c.Modes = make(map[string]map[string]map[string]string)
-
-	for key, value := range raw {
-		// "off" is a direct string
-		if key == "off" {
-			if str, ok := value.(string); ok {
-				c.Off = str
-			}
-			continue
-		}
+	c.Codes = codes
+	c.Skipped = skipped
+	return nil
+}
 
-		// Other keys are modes (fan_only, cool, heat, dry)
-		mode := key
-		c.Modes[mode] = make(map[string]map[string]string)
+// walkCommands calls visit with every code of a SmartIR commands object, in key order
+// Below a mode, numeric keys are temperatures; the first other level is the fan speed
+// and the second the swing mode. Branches the loader cannot store are passed to
+// invalid (with their path below "commands") and skipped.
+func walkCommands(data []byte, visit func(path string, code SmartIRCode), invalid func(path, message string)) error {
+	var modes map[string]json.RawMessage
+	if err := json.Unmarshal(data, &modes); err != nil {
+		return err
+	}
+	for _, mode := range sortedKeys(modes) {
+		walkLevel(mode, SmartIRCode{Mode: mode}, modes[mode], visit, invalid)
+	}
+	return nil
+}
 
-		// Value should be a nested object: fan_speed → temperature → code
-		modeData, ok := value.(map[string]interface{})
-		if !ok {
-			continue
-		}
+// walkLevel visits the code or the object of codes at path
+func walkLevel(path string, code SmartIRCode, data json.RawMessage, visit func(string, SmartIRCode), invalid func(string, string)) {
+	if err := json.Unmarshal(data, &code.Code); err == nil && code.Code != "" {
+		visit(path, code)
+		return
+	}
 
-		for fanSpeed, fanData := range modeData {
-			c.Modes[mode][fanSpeed] = make(map[string]string)
+	var level map[string]json.RawMessage
+	if err := json.Unmarshal(data, &level); err != nil || level == nil {
+		invalid(path, "expected a code string or an object")
+		return
+	}
+	if code.Temperature != nil {
+		invalid(path, "unexpected nesting below a temperature")
+		return
+	}
 
-			tempData, ok := fanData.(map[string]interface{})
-			if !ok {
+	for _, key := range sortedKeys(level) {
+		next := code
+		keyPath := path + "/" + key
+		if temp, err := strconv.ParseFloat(key, 64); err == nil {
+			next.Temperature = &temp
+		} else {
+			switch {
+			case code.FanSpeed == nil:
+				next.FanSpeed = &key
+			case code.Swing == nil:
+				next.Swing = &key
+			default:
+				invalid(keyPath, "temperature key is not a number (nested deeper than fan speed and swing?)")
 				continue
 			}
-
-			for temp, code := range tempData {
-				if codeStr, ok := code.(string); ok {
-					c.Modes[mode][fanSpeed][temp] = codeStr
-				}
-			}
 		}
+		walkLevel(keyPath, next, level[key], visit, invalid)
 	}
-
-	return nil
 }
 
 // LoadFromJSON reads a SmartIR JSON file and populates the database.
 // Supports both Broadlink and Tuya formats - automatically detects and converts if needed.
 // Can be called multiple times to add additional models.
 // Uses UPSERT (ON CONFLICT) to update existing models if called again with same modelID.
+// Codes missing from the file are kept; use ReplaceModel to drop them.
 func (db *DB) LoadFromJSON(ctx context.Context, modelID, filePath string) error {
-	// Read file
-	data, err := os.ReadFile(filePath)
+	smartIR, err := db.readSmartIR(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", filePath, err)
-	}
-
-	// Parse JSON
-	var smartIR SmartIRFile
-	if err := json.Unmarshal(data, &smartIR); err != nil {
-		return fmt.Errorf("failed to parse JSON: %w", err)
-	}
-
-	// Convert Broadlink codes to Tuya if needed
-	if err := db.convertCommandsIfNeeded(&smartIR); err != nil {
-		return fmt.Errorf("failed to convert IR codes: %w", err)
+		return err
 	}
 
 	// Start transaction for atomic insertion
@@ -108,12 +151,12 @@ func (db *DB) LoadFromJSON(ctx context.Context, modelID, filePath string) error
 	defer tx.Rollback() // Rollback if not committed
 
 	// Insert model metadata
-	if err := db.insertModel(ctx, tx, modelID, &smartIR); err != nil {
+	if err := db.insertModel(ctx, tx, modelID, smartIR); err != nil {
 		return fmt.Errorf("failed to insert model: %w", err)
 	}
 
 	// Insert IR codes
-	if err := db.insertIRCodes(ctx, tx, modelID, &smartIR); err != nil {
+	if err := db.insertIRCodes(ctx, tx, modelID, smartIR); err != nil {
 		return fmt.Errorf("failed to insert IR codes: %w", err)
 	}
 
@@ -122,9 +165,91 @@ func (db *DB) LoadFromJSON(ctx context.Context, modelID, filePath string) error
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	db.modelChanged(modelID)
 	return nil
 }
 
+// ReplaceModel replaces a model with the contents of a SmartIR file.
+// Unlike LoadFromJSON, codes missing from the file are removed: the model is deleted
+// (cascading to its codes) and reinserted in one transaction, so a failed import keeps the old codes.
+// Files with skipped entries are rejected, as replacing would drop the codes those entries held.
+func (db *DB) ReplaceModel(ctx context.Context, modelID, filePath string) error {
+	smartIR, err := db.readSmartIR(filePath)
+	if err != nil {
+		return err
+	}
+	if skipped := smartIR.Commands.Skipped; len(skipped) > 0 {
+		return fmt.Errorf("%d unstorable entries in %s (first: %s)", len(skipped), filePath, skipped[0])
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM models WHERE model_id = ?`, modelID); err != nil {
+		return fmt.Errorf("failed to delete model: %w", err)
+	}
+	if err := db.insertModel(ctx, tx, modelID, smartIR); err != nil {
+		return fmt.Errorf("failed to insert model: %w", err)
+	}
+	if err := db.insertIRCodes(ctx, tx, modelID, smartIR); err != nil {
+		return fmt.Errorf("failed to insert IR codes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	db.modelChanged(modelID)
+	return nil
+}
+
+// DeleteModel removes a model and, through ON DELETE CASCADE, all of its IR codes
+// Returns ErrModelNotFound if the model is not in the database.
+func (db *DB) DeleteModel(ctx context.Context, modelID string) error {
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM models WHERE model_id = ?`, modelID)
+	if err != nil {
+		return fmt.Errorf("failed to delete model: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrModelNotFound
+	}
+
+	db.modelChanged(modelID)
+	return nil
+}
+
+// readSmartIR reads, checks and converts a SmartIR file without touching the database
+func (db *DB) readSmartIR(filePath string) (*SmartIRFile, error) {
+	// Read file
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	// Parse JSON
+	var smartIR SmartIRFile
+	if err := json.Unmarshal(data, &smartIR); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	for _, skipped := range smartIR.Commands.Skipped {
+		logger.Warn("⚠️  Skipping %s in %s", skipped, filePath)
+	}
+
+	// A file without codes (e.g., cut short while being written) never replaces a model
+	if len(smartIR.Commands.Codes) == 0 {
+		return nil, fmt.Errorf("no IR codes in %s", filePath)
+	}
+
+	// Convert Broadlink codes to Tuya if needed
+	if err := db.convertCommandsIfNeeded(&smartIR); err != nil {
+		return nil, fmt.Errorf("failed to convert IR codes: %w", err)
+	}
+	return &smartIR, nil
+}
+
 // insertModel inserts model metadata into the database
 func (db *DB) insertModel(ctx context.Context, tx *sql.Tx, modelID string, smartIR *SmartIRFile) error {
 	// Serialize array fields to JSON for storage
@@ -167,48 +292,60 @@ func (db *DB) insertModel(ctx context.Context, tx *sql.Tx, modelID string, smart
 }
 
 // insertIRCodes inserts all IR codes from the SmartIR file
+// NULL levels never conflict in the unique index, so each state is updated in place
+// (keeping its ID) and only inserted if it is not stored yet.
 func (db *DB) insertIRCodes(ctx context.Context, tx *sql.Tx, modelID string, smartIR *SmartIRFile) error {
-	query := `
-		INSERT INTO ir_codes (model_id, mode, temperature, fan_speed, ir_code)
-		VALUES (?, ?, ?, ?, ?)
-		ON CONFLICT(model_id, mode, temperature, fan_speed) DO UPDATE SET
-			ir_code = excluded.ir_code
-	`
-
-	stmt, err := tx.PrepareContext(ctx, query)
+	update, err := tx.PrepareContext(ctx, `
+		UPDATE ir_codes SET ir_code = ?
+		WHERE model_id = ? AND mode = ? AND temperature IS ? AND fan_speed IS ? AND swing IS ?
+	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
-	defer stmt.Close()
+	defer update.Close()
 
-	// Insert "off" command (no temperature or fan speed)
-	if smartIR.Commands.Off != "" {
-		if _, err := stmt.ExecContext(ctx, modelID, "off", nil, nil, smartIR.Commands.Off); err != nil {
-			return fmt.Errorf("failed to insert off command: %w", err)
-		}
+	insert, err := tx.PrepareContext(ctx, `
+		INSERT INTO ir_codes (model_id, mode, temperature, fan_speed, swing, ir_code)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
+	defer insert.Close()
 
-	// Insert mode-based commands
-	for mode, fanSpeeds := range smartIR.Commands.Modes {
-		for fanSpeed, temperatures := range fanSpeeds {
-			for tempStr, code := range temperatures {
-				// Parse temperature string to int
-				var temp int
-				if _, err := fmt.Sscanf(tempStr, "%d", &temp); err != nil {
-					return fmt.Errorf("invalid temperature %s: %w", tempStr, err)
-				}
-
-				if _, err := stmt.ExecContext(ctx, modelID, mode, temp, fanSpeed, code); err != nil {
-					return fmt.Errorf("failed to insert code for mode=%s temp=%d fan=%s: %w",
-						mode, temp, fanSpeed, err)
-				}
-			}
+	// Lookups without a swing mode take the lowest ID, so the declared default (first) swing mode goes first
+	codes := slices.Clone(smartIR.Commands.Codes)
+	slices.SortStableFunc(codes, func(a, b SmartIRCode) int {
+		return swingOrder(smartIR.SwingModes, a.Swing) - swingOrder(smartIR.SwingModes, b.Swing)
+	})
+
+	for _, code := range codes {
+		result, err := update.ExecContext(ctx, code.Code, modelID, code.Mode, code.Temperature, code.FanSpeed, code.Swing)
+		if err != nil {
+			return fmt.Errorf("failed to update code %s: %w", code.Path(), err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			continue
+		}
+		if _, err := insert.ExecContext(ctx, modelID, code.Mode, code.Temperature, code.FanSpeed, code.Swing, code.Code); err != nil {
+			return fmt.Errorf("failed to insert code %s: %w", code.Path(), err)
 		}
 	}
 
 	return nil
 }
 
+// swingOrder ranks a swing mode by its position in the declared swing modes (undeclared ones last)
+func swingOrder(swingModes []string, swing *string) int {
+	if swing == nil {
+		return -1
+	}
+	if i := slices.Index(swingModes, *swing); i >= 0 {
+		return i
+	}
+	return len(swingModes)
+}
+
 // convertCommandsIfNeeded detects the format and converts Broadlink codes to Tuya if necessary.
 // Detection is based on the commandsEncoding field:
 // - "Base64" = Broadlink format (needs conversion)
@@ -227,27 +364,13 @@ func (db *DB) convertCommandsIfNeeded(smartIR *SmartIRFile) error {
 			smartIR.CommandsEncoding)
 	}
 
-	// Convert "off" command if present
-	if smartIR.Commands.Off != "" {
-		converted, err := ConvertBroadlinkToTuya(smartIR.Commands.Off)
+	// Convert every code, whatever its nesting
+	for i, code := range smartIR.Commands.Codes {
+		converted, err := ConvertBroadlinkToTuya(code.Code)
 		if err != nil {
-			return fmt.Errorf("failed to convert 'off' command: %w", err)
-		}
-		smartIR.Commands.Off = converted
-	}
-
-	// Convert all mode-based commands
-	for mode, fanSpeeds := range smartIR.Commands.Modes {
-		for fanSpeed, temperatures := range fanSpeeds {
-			for tempStr, code := range temperatures {
-				converted, err := ConvertBroadlinkToTuya(code)
-				if err != nil {
-					return fmt.Errorf("failed to convert code for mode=%s fan=%s temp=%s: %w",
-						mode, fanSpeed, tempStr, err)
-				}
-				smartIR.Commands.Modes[mode][fanSpeed][tempStr] = converted
-			}
+			return fmt.Errorf("failed to convert code %s: %w", code.Path(), err)
 		}
+		smartIR.Commands.Codes[i].Code = converted
 	}
 
 	// Update metadata to reflect Tuya format
@@ -276,20 +399,11 @@ func (db *DB) LoadFromDirectory(ctx context.Context, dirPath string) error {
 
 		// Only process JSON files
 		name := entry.Name()
-		if filepath.Ext(name) != ".json" {
+		modelID, ok := ModelIDFromFilename(name)
+		if !ok {
 			continue
 		}
 
-		// Extract model ID based on naming convention
-		var modelID string
-		if len(name) > 10 && name[len(name)-10:] == "_tuya.json" {
-			// Legacy format: "1109_tuya.json" → "1109"
-			modelID = name[:len(name)-10]
-		} else {
-			// Modern format: "1109.json" → "1109"
-			modelID = name[:len(name)-5]
-		}
-
 		filePath := filepath.Join(dirPath, name)
 		if err := db.LoadFromJSON(ctx, modelID, filePath); err != nil {
 			return fmt.Errorf("failed to load %s: %w", name, err)
@@ -298,3 +412,25 @@ func (db *DB) LoadFromDirectory(ctx context.Context, dirPath string) error {
 
 	return nil
 }
+
+// ModelIDFromFilename extracts the model ID from a SmartIR file name
+// "1109.json" and the legacy "1109_tuya.json" both give "1109"; other files are not model files.
+func ModelIDFromFilename(name string) (string, bool) {
+	name = filepath.Base(name)
+	if filepath.Ext(name) != ".json" {
+		return "", false
+	}
+	modelID := strings.TrimSuffix(strings.TrimSuffix(name, ".json"), "_tuya")
+	return modelID, modelID != ""
+}
+
+// ModelFile returns the SmartIR file of a model in dir, preferring "<id>.json" over "<id>_tuya.json"
+func ModelFile(dir, modelID string) (string, error) {
+	for _, name := range []string{modelID + ".json", modelID + "_tuya.json"} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no SmartIR file for model %s in %s", modelID, dir)
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+)
+
+// migrationFiles holds one "NNNN_name.sql" file per schema version after 1
+// schema.sql creates the current schema directly; migrations upgrade existing databases.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration upgrades the schema from Version-1 to Version
+type Migration struct {
+	Version int
+	Name    string // e.g., "schedules"
+	SQL     string
+}
+
+// Migrations returns the embedded migrations ordered by version
+// Versions must run from 2 to CurrentSchemaVersion without gaps.
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s (expected NNNN_name.sql)", entry.Name())
+		}
+		data, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(data)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+2 {
+			return nil, fmt.Errorf("migration %04d_%s out of sequence (expected version %d)", m.Version, m.Name, i+2)
+		}
+	}
+	if n := len(migrations); n > 0 && migrations[n-1].Version != CurrentSchemaVersion {
+		return nil, fmt.Errorf("last migration is version %d, schema version is %d", migrations[n-1].Version, CurrentSchemaVersion)
+	}
+	return migrations, nil
+}
+
+// PendingMigrations returns the migrations Migrate would apply
+// An uninitialized database (version 0) has none: Migrate creates the current schema directly.
+func (db *DB) PendingMigrations(ctx context.Context) ([]Migration, error) {
+	version, err := db.GetSchemaVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check schema version: %w", err)
+	}
+	if version > CurrentSchemaVersion {
+		return nil, fmt.Errorf("unknown schema version %d (expected at most %d)", version, CurrentSchemaVersion)
+	}
+	if version == 0 {
+		return nil, nil
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return migrations[version-1:], nil
+}
+
+// Migrate updates the database schema to the current version
+// Safe to call on already-initialized databases
+func (db *DB) Migrate(ctx context.Context) error {
+	return db.migrateTo(ctx, CurrentSchemaVersion)
+}
+
+// migrateTo applies the pending migrations up to and including version target
+func (db *DB) migrateTo(ctx context.Context, target int) error {
+	version, err := db.GetSchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check schema version: %w", err)
+	}
+
+	// Version 0 means uninitialized database
+	if version == 0 {
+		return db.InitSchema(ctx)
+	}
+
+	pending, err := db.PendingMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range pending {
+		if m.Version > target {
+			break
+		}
+		if err := db.applyMigration(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration runs one migration and bumps the schema version in the same transaction
+func (db *DB) applyMigration(ctx context.Context, m Migration) error {
+	logger.Info("📦 Migrating database schema v%d → v%d (%s)", m.Version-1, m.Version, m.Name)
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return fmt.Errorf("failed to migrate schema to v%d: %w", m.Version, err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", m.Version)); err != nil {
+		return fmt.Errorf("failed to set schema version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration to v%d: %w", m.Version, err)
+	}
+	return nil
+}
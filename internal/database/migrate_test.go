@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setupV1DB creates a database from the v1 fixture (IR code tables with one model)
+func setupV1DB(t *testing.T) *DB {
+	t.Helper()
+	fixture, err := os.ReadFile(filepath.Join("testdata", "schema_v1.sql"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.conn.ExecContext(context.Background(), string(fixture)); err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+	return db
+}
+
+// describeSchema lists every table column, index and trigger of a database
+func describeSchema(t *testing.T, db *DB) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT type, name, tbl_name FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' ORDER BY type, name
+	`)
+	if err != nil {
+		t.Fatalf("Failed to read schema: %v", err)
+	}
+	var schema, tables []string
+	for rows.Next() {
+		var kind, name, table string
+		if err := rows.Scan(&kind, &name, &table); err != nil {
+			t.Fatalf("Failed to scan schema: %v", err)
+		}
+		schema = append(schema, fmt.Sprintf("%s %s on %s", kind, name, table))
+		if kind == "table" {
+			tables = append(tables, name)
+		}
+	}
+	rows.Close()
+
+	for _, table := range tables {
+		cols, err := db.conn.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+		if err != nil {
+			t.Fatalf("Failed to read columns of %s: %v", table, err)
+		}
+		for cols.Next() {
+			var cid, notNull, pk int
+			var name, colType string
+			var dflt *string
+			if err := cols.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+				t.Fatalf("Failed to scan column: %v", err)
+			}
+			def := "<nil>"
+			if dflt != nil {
+				def = *dflt
+			}
+			schema = append(schema, fmt.Sprintf("%s.%s %s notnull=%d default=%s pk=%d", table, name, colType, notNull, def, pk))
+		}
+		cols.Close()
+	}
+	return schema
+}
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations failed: %v", err)
+	}
+	if len(migrations) != CurrentSchemaVersion-1 {
+		t.Fatalf("Expected %d migrations, got %d", CurrentSchemaVersion-1, len(migrations))
+	}
+	for i, m := range migrations {
+		if m.Version != i+2 || m.Name == "" || m.SQL == "" {
+			t.Errorf("Invalid migration %d: %+v", i, m)
+		}
+	}
+}
+
+// TestMigrate_Steps upgrades the v1 fixture one step at a time up to the schema of a fresh database
+func TestMigrate_Steps(t *testing.T) {
+	db := setupV1DB(t)
+	ctx := context.Background()
+
+	pending, err := db.PendingMigrations(ctx)
+	if err != nil {
+		t.Fatalf("PendingMigrations failed: %v", err)
+	}
+	if len(pending) != CurrentSchemaVersion-1 {
+		t.Fatalf("Expected %d pending migrations from v1, got %d", CurrentSchemaVersion-1, len(pending))
+	}
+
+	for target := 2; target <= CurrentSchemaVersion; target++ {
+		if err := db.migrateTo(ctx, target); err != nil {
+			t.Fatalf("Migration to v%d failed: %v", target, err)
+		}
+		version, err := db.GetSchemaVersion(ctx)
+		if err != nil {
+			t.Fatalf("failed to get version: %v", err)
+		}
+		if version != target {
+			t.Fatalf("Expected version %d, got %d", target, version)
+		}
+		if pending, _ := db.PendingMigrations(ctx); len(pending) != CurrentSchemaVersion-target {
+			t.Errorf("v%d: expected %d pending migrations, got %d", target, CurrentSchemaVersion-target, len(pending))
+		}
+	}
+
+	// Data from v1 survives
+	if code, _, err := db.LookupCode(ctx, "1109", "cool", 22, "low"); err != nil || code != "COOL22" {
+		t.Errorf("Expected COOL22 after migration, got %q (err %v)", code, err)
+	}
+
+	// The upgraded schema matches a fresh one
+	fresh := setupTestDB(t)
+	defer fresh.Close()
+	if got, want := describeSchema(t, db), describeSchema(t, fresh); !reflect.DeepEqual(got, want) {
+		t.Errorf("Migrated schema differs from schema.sql:\n got: %v\nwant: %v", got, want)
+	}
+}
+
+// TestMigrate_FailedStep tests that a failing migration leaves no partial changes
+func TestMigrate_FailedStep(t *testing.T) {
+	db := setupV1DB(t)
+	ctx := context.Background()
+
+	bad := Migration{Version: 2, Name: "broken", SQL: `
+		CREATE TABLE half_done (id INTEGER);
+		CREATE TABLE half_done (id INTEGER);
+	`}
+	if err := db.applyMigration(ctx, bad); err == nil {
+		t.Fatal("Expected the migration to fail")
+	}
+
+	if version, _ := db.GetSchemaVersion(ctx); version != 1 {
+		t.Errorf("Expected version 1 after a failed migration, got %d", version)
+	}
+	var name string
+	if err := db.conn.QueryRowContext(ctx, `SELECT name FROM sqlite_master WHERE name = 'half_done'`).Scan(&name); err == nil {
+		t.Error("Expected the failed migration to be rolled back")
+	}
+
+	// The real migrations still apply afterwards
+	if err := db.Migrate(ctx); err != nil {
+		t.Errorf("Migrate failed after a failed step: %v", err)
+	}
+}
+
+func TestMigrate_NewerVersion(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if err := db.setSchemaVersion(ctx, CurrentSchemaVersion+1); err != nil {
+		t.Fatalf("Failed to set version: %v", err)
+	}
+	if err := db.Migrate(ctx); err == nil {
+		t.Error("Expected an error for a schema newer than this build")
+	}
+}
@@ -0,0 +1,15 @@
+-- Weekly schedules (one row per slot)
+CREATE TABLE IF NOT EXISTS schedules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    device_id TEXT NOT NULL,
+    days TEXT NOT NULL,
+    time TEXT NOT NULL,
+    mode TEXT,
+    temperature REAL,
+    fan_mode TEXT,
+    enabled INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_device
+ON schedules(device_id);
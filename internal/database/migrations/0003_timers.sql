@@ -0,0 +1,9 @@
+-- Sleep/off-delay timers (at most one per device)
+CREATE TABLE IF NOT EXISTS timers (
+    device_id TEXT PRIMARY KEY,
+    fires_at INTEGER NOT NULL,
+    mode TEXT,
+    temperature REAL,
+    fan_mode TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
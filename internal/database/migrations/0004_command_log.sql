@@ -0,0 +1,29 @@
+-- Append-only command audit log
+CREATE TABLE IF NOT EXISTS command_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    device_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    command TEXT NOT NULL,
+    mode TEXT NOT NULL,
+    temperature REAL NOT NULL,
+    fan_mode TEXT NOT NULL,
+    preset TEXT,
+    ir_code_id INTEGER,
+    strategy TEXT,
+    success INTEGER NOT NULL,
+    error TEXT,
+    correlation_id TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_command_log_device
+ON command_log(device_id, id);
+
+CREATE INDEX IF NOT EXISTS idx_command_log_created
+ON command_log(created_at);
+
+CREATE TRIGGER IF NOT EXISTS command_log_append_only
+BEFORE UPDATE ON command_log
+BEGIN
+    SELECT RAISE(ABORT, 'command_log is append-only');
+END;
@@ -0,0 +1,27 @@
+-- Swing level of SmartIR codes
+-- The swing column joins the unique state, and SQLite cannot change a table
+-- constraint in place, so ir_codes is rebuilt with its IDs and indexes
+CREATE TABLE ir_codes_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    model_id TEXT NOT NULL,
+    mode TEXT NOT NULL,
+    temperature INTEGER,
+    fan_speed TEXT,
+    swing TEXT,
+    ir_code TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (model_id) REFERENCES models(model_id) ON DELETE CASCADE,
+    UNIQUE(model_id, mode, temperature, fan_speed, swing)
+);
+
+INSERT INTO ir_codes_new (id, model_id, mode, temperature, fan_speed, ir_code, created_at)
+SELECT id, model_id, mode, temperature, fan_speed, ir_code, created_at FROM ir_codes;
+
+DROP TABLE ir_codes;
+ALTER TABLE ir_codes_new RENAME TO ir_codes;
+
+CREATE INDEX IF NOT EXISTS idx_ir_codes_lookup
+ON ir_codes(model_id, mode, temperature, fan_speed);
+
+CREATE INDEX IF NOT EXISTS idx_ir_codes_mode
+ON ir_codes(model_id, mode);
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MirrorEntry is one climate code file of a SmartIR mirror
+type MirrorEntry struct {
+	ModelID         string   // From the file name, e.g., "1109"
+	Manufacturer    string   // e.g., "Daikin"
+	SupportedModels []string // e.g., ["FTXM20M", "FTXM25M"]
+	Path            string   // Relative to the mirror root (inside the archive for tarballs)
+}
+
+// Mirror indexes an on-disk copy of SmartIR's codes/climate directory
+// The root may be a checkout (or any directory containing the climate files) or a
+// .tar/.tar.gz/.tgz archive of one. Nothing is fetched over the network.
+type Mirror struct {
+	Root    string
+	Entries []MirrorEntry // By manufacturer, then model ID
+	Skipped int           // Climate files that could not be parsed
+
+	archive bool
+}
+
+// OpenMirror indexes the climate code files of a mirror by manufacturer and supported models
+// Files are indexed if they sit in a "climate" directory or directly in a directory root.
+func OpenMirror(root string) (*Mirror, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mirror: %w", err)
+	}
+
+	m := &Mirror{Root: root, archive: !info.IsDir()}
+	index := func(name string, r io.Reader) {
+		modelID, ok := ModelIDFromFilename(name)
+		dir := path.Base(path.Dir(name))
+		if !ok || (dir != "climate" && dir != ".") {
+			return
+		}
+
+		var meta struct {
+			Manufacturer    string   `json:"manufacturer"`
+			SupportedModels []string `json:"supportedModels"`
+		}
+		if err := json.NewDecoder(r).Decode(&meta); err != nil || meta.Manufacturer == "" {
+			m.Skipped++
+			return
+		}
+		m.Entries = append(m.Entries, MirrorEntry{
+			ModelID:         modelID,
+			Manufacturer:    meta.Manufacturer,
+			SupportedModels: meta.SupportedModels,
+			Path:            name,
+		})
+	}
+
+	if m.archive {
+		err = m.walkArchive(func(name string, r io.Reader) (bool, error) {
+			index(name, r)
+			return false, nil
+		})
+	} else {
+		err = fs.WalkDir(os.DirFS(root), ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			f, err := os.Open(filepath.Join(root, filepath.FromSlash(name)))
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			index(name, f)
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to index mirror %s: %w", root, err)
+	}
+
+	sort.Slice(m.Entries, func(i, j int) bool {
+		a, b := m.Entries[i], m.Entries[j]
+		if !strings.EqualFold(a.Manufacturer, b.Manufacturer) {
+			return strings.ToLower(a.Manufacturer) < strings.ToLower(b.Manufacturer)
+		}
+		if len(a.ModelID) != len(b.ModelID) {
+			return len(a.ModelID) < len(b.ModelID) // Numeric order for numeric IDs
+		}
+		return a.ModelID < b.ModelID
+	})
+	return m, nil
+}
+
+// Search returns the entries matching every term (case-insensitive)
+// A term matches the manufacturer, the model ID or any supported model by substring.
+func (m *Mirror) Search(terms ...string) []MirrorEntry {
+	var matches []MirrorEntry
+	for _, entry := range m.Entries {
+		if entry.matches(terms) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// matches reports whether every term is found in the entry
+func (e MirrorEntry) matches(terms []string) bool {
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		found := strings.Contains(strings.ToLower(e.Manufacturer), term) || strings.Contains(e.ModelID, term)
+		for _, model := range e.SupportedModels {
+			found = found || strings.Contains(strings.ToLower(model), term)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Entry returns the entry of a model ID
+func (m *Mirror) Entry(modelID string) (MirrorEntry, bool) {
+	for _, entry := range m.Entries {
+		if entry.ModelID == modelID {
+			return entry, true
+		}
+	}
+	return MirrorEntry{}, false
+}
+
+// Extract copies the code file of a model to dir as "<id>.json" and returns its path
+// The file is written under a temporary name and renamed, so a watched directory never sees it half written.
+func (m *Mirror) Extract(modelID, dir string) (string, error) {
+	entry, ok := m.Entry(modelID)
+	if !ok {
+		return "", fmt.Errorf("model %s not found in mirror %s", modelID, m.Root)
+	}
+
+	data, err := m.read(entry.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", entry.Path, err)
+	}
+
+	dest := filepath.Join(dir, modelID+".json")
+	tmp := filepath.Join(dir, "."+modelID+".json.tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	return dest, nil
+}
+
+// read returns the contents of a mirror file
+func (m *Mirror) read(name string) ([]byte, error) {
+	if !m.archive {
+		return os.ReadFile(filepath.Join(m.Root, filepath.FromSlash(name)))
+	}
+
+	var data []byte
+	err := m.walkArchive(func(file string, r io.Reader) (bool, error) {
+		if file != name {
+			return false, nil
+		}
+		var err error
+		data, err = io.ReadAll(r)
+		return true, err
+	})
+	if err == nil && data == nil {
+		err = fs.ErrNotExist
+	}
+	return data, err
+}
+
+// walkArchive calls fn with every regular file of the tarball until it returns true or an error
+func (m *Mirror) walkArchive(fn func(name string, r io.Reader) (bool, error)) error {
+	f, err := os.Open(m.Root)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(m.Root, ".gz") || strings.HasSuffix(m.Root, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if done, err := fn(path.Clean(header.Name), tr); done || err != nil {
+			return err
+		}
+	}
+}
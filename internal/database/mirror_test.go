@@ -0,0 +1,125 @@
+package database
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// mirrorFiles is a small SmartIR checkout: two climate files, a broken one and a non-climate file
+var mirrorFiles = map[string]string{
+	"codes/climate/1109.json": `{"manufacturer": "Daikin", "supportedModels": ["FTXM20M", "FTXM25M"], "commandsEncoding": "Raw",
+		"supportedController": "MQTT", "minTemperature": 18, "maxTemperature": 30, "precision": 1,
+		"operationModes": ["cool"], "fanModes": ["low"], "commands": {"off": "OFF", "cool": {"low": {"22": "COOL22"}}}}`,
+	"codes/climate/1000.json": `{"manufacturer": "Toshiba", "supportedModels": ["RAS-10"]}`,
+	"codes/climate/1001.json": `{"manufacturer": `,
+	"codes/fan/1109.json":     `{"manufacturer": "Daikin Fan", "supportedModels": ["FAN"]}`,
+	"README.md":               "SmartIR",
+}
+
+// setupMirror writes mirrorFiles to a directory and returns it
+func setupMirror(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range mirrorFiles {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+// setupMirrorArchive writes mirrorFiles to a .tar.gz under a top-level directory, like a GitHub tarball
+func setupMirrorArchive(t *testing.T) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "SmartIR-master.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range mirrorFiles {
+		header := &tar.Header{Name: "SmartIR-master/" + name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to close gzip: %v", err)
+	}
+	return archive
+}
+
+func TestMirror(t *testing.T) {
+	for name, root := range map[string]string{"directory": setupMirror(t), "archive": setupMirrorArchive(t)} {
+		t.Run(name, func(t *testing.T) {
+			m, err := OpenMirror(root)
+			if err != nil {
+				t.Fatalf("OpenMirror failed: %v", err)
+			}
+
+			var ids []string
+			for _, entry := range m.Entries {
+				ids = append(ids, entry.ModelID)
+			}
+			if want := []string{"1109", "1000"}; !reflect.DeepEqual(ids, want) {
+				t.Errorf("Entries = %v, want %v (by manufacturer)", ids, want)
+			}
+			if m.Skipped != 1 {
+				t.Errorf("Expected 1 skipped file, got %d", m.Skipped)
+			}
+
+			searches := []struct {
+				terms []string
+				want  int
+			}{
+				{[]string{"daikin", "FTX"}, 1},
+				{[]string{"ftxm25"}, 1},
+				{[]string{"1000"}, 1},
+				{[]string{"daikin", "RAS"}, 0},
+				{nil, 2},
+			}
+			for _, s := range searches {
+				if got := m.Search(s.terms...); len(got) != s.want {
+					t.Errorf("Search(%v) = %d entries, want %d", s.terms, len(got), s.want)
+				}
+			}
+
+			// Extract and import a model
+			dir := t.TempDir()
+			path, err := m.Extract("1109", dir)
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if path != filepath.Join(dir, "1109.json") {
+				t.Errorf("Extract path = %s", path)
+			}
+			db := setupTestDB(t)
+			defer db.Close()
+			if err := db.LoadFromJSON(context.Background(), "1109", path); err != nil {
+				t.Fatalf("LoadFromJSON failed: %v", err)
+			}
+
+			if _, err := m.Extract("9999", dir); err == nil {
+				t.Error("Expected an error for a model not in the mirror")
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Schedule represents one weekly schedule slot for a device
+// Nil command fields leave the current value unchanged when the slot fires
+type Schedule struct {
+	ID          int64    `json:"id"`
+	DeviceID    string   `json:"-"`
+	Days        string   `json:"days"`                  // e.g., "mon,tue,wed,thu,fri"
+	Time        string   `json:"time"`                  // Local time, e.g., "07:00"
+	Mode        *string  `json:"mode,omitempty"`        // e.g., "heat"
+	Temperature *float64 `json:"temperature,omitempty"` // e.g., 21.0
+	FanMode     *string  `json:"fan_mode,omitempty"`    // e.g., "low"
+	Enabled     bool     `json:"enabled"`
+}
+
+// ListSchedules returns all schedule slots of a device ordered by ID
+func (db *DB) ListSchedules(ctx context.Context, deviceID string) ([]Schedule, error) {
+	query := `
+		SELECT id, device_id, days, time, mode, temperature, fan_mode, enabled
+		FROM schedules
+		WHERE device_id = ?
+		ORDER BY id
+	`
+	rows, err := db.conn.QueryContext(ctx, query, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []Schedule
+	for rows.Next() {
+		var s Schedule
+		var mode, fanMode sql.NullString
+		var temperature sql.NullFloat64
+		if err := rows.Scan(&s.ID, &s.DeviceID, &s.Days, &s.Time, &mode, &temperature, &fanMode, &s.Enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		if mode.Valid {
+			s.Mode = &mode.String
+		}
+		if temperature.Valid {
+			s.Temperature = &temperature.Float64
+		}
+		if fanMode.Valid {
+			s.FanMode = &fanMode.String
+		}
+		schedules = append(schedules, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// SaveSchedule inserts a new slot (ID 0) or updates an existing one
+// On insert, the generated ID is stored in s.ID
+func (db *DB) SaveSchedule(ctx context.Context, s *Schedule) error {
+	if s.ID == 0 {
+		result, err := db.conn.ExecContext(ctx, `
+			INSERT INTO schedules (device_id, days, time, mode, temperature, fan_mode, enabled)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, s.DeviceID, s.Days, s.Time, s.Mode, s.Temperature, s.FanMode, s.Enabled)
+		if err != nil {
+			return fmt.Errorf("failed to insert schedule: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get schedule ID: %w", err)
+		}
+		s.ID = id
+		return nil
+	}
+
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE schedules
+		SET days = ?, time = ?, mode = ?, temperature = ?, fan_mode = ?, enabled = ?
+		WHERE id = ? AND device_id = ?
+	`, s.Days, s.Time, s.Mode, s.Temperature, s.FanMode, s.Enabled, s.ID, s.DeviceID)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %w", err)
+	}
+	return requireRow(result, s.ID, s.DeviceID)
+}
+
+// DeleteSchedule removes a slot from a device's schedule
+func (db *DB) DeleteSchedule(ctx context.Context, deviceID string, id int64) error {
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM schedules WHERE id = ? AND device_id = ?`, id, deviceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	return requireRow(result, id, deviceID)
+}
+
+// requireRow returns ErrScheduleNotFound if the statement affected no rows
+func requireRow(result sql.Result, id int64, deviceID string) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %d for device %s", ErrScheduleNotFound, id, deviceID)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSchedules_CRUD(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	temp := 21.0
+	morning := &Schedule{
+		DeviceID:    "living_room",
+		Days:        "mon,tue,wed,thu,fri",
+		Time:        "07:00",
+		Mode:        strPtr("heat"),
+		Temperature: &temp,
+		Enabled:     true,
+	}
+	if err := db.SaveSchedule(ctx, morning); err != nil {
+		t.Fatalf("SaveSchedule insert failed: %v", err)
+	}
+	if morning.ID == 0 {
+		t.Fatal("Expected generated ID")
+	}
+
+	other := &Schedule{DeviceID: "bedroom", Days: "sun", Time: "23:00", FanMode: strPtr("low"), Enabled: true}
+	if err := db.SaveSchedule(ctx, other); err != nil {
+		t.Fatalf("SaveSchedule insert failed: %v", err)
+	}
+
+	schedules, err := db.ListSchedules(ctx, "living_room")
+	if err != nil {
+		t.Fatalf("ListSchedules failed: %v", err)
+	}
+	if len(schedules) != 1 {
+		t.Fatalf("Expected 1 schedule, got %d", len(schedules))
+	}
+	got := schedules[0]
+	if got.Mode == nil || *got.Mode != "heat" || got.Temperature == nil || *got.Temperature != 21 || got.FanMode != nil {
+		t.Errorf("Unexpected schedule: %+v", got)
+	}
+
+	// Update
+	morning.Time = "06:30"
+	morning.Enabled = false
+	if err := db.SaveSchedule(ctx, morning); err != nil {
+		t.Fatalf("SaveSchedule update failed: %v", err)
+	}
+	schedules, _ = db.ListSchedules(ctx, "living_room")
+	if schedules[0].Time != "06:30" || schedules[0].Enabled {
+		t.Errorf("Update not applied: %+v", schedules[0])
+	}
+
+	// Slots are scoped to their device
+	other.DeviceID = "living_room"
+	if err := db.SaveSchedule(ctx, other); err == nil {
+		t.Error("Expected error updating another device's slot")
+	}
+	if err := db.DeleteSchedule(ctx, "living_room", other.ID); err == nil {
+		t.Error("Expected error deleting another device's slot")
+	}
+
+	// Delete
+	if err := db.DeleteSchedule(ctx, "living_room", morning.ID); err != nil {
+		t.Fatalf("DeleteSchedule failed: %v", err)
+	}
+	schedules, _ = db.ListSchedules(ctx, "living_room")
+	if len(schedules) != 0 {
+		t.Errorf("Expected no schedules after delete, got %d", len(schedules))
+	}
+}
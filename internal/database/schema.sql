@@ -1,5 +1,7 @@
 -- HVAC Manager IR Code Database Schema
 -- SQLite schema for storing pre-translated IR codes from SmartIR
+-- Creates the current schema (CurrentSchemaVersion) for new databases.
+-- Every change here needs a matching migrations/NNNN_name.sql for existing ones.
 
 -- Model metadata table
 -- Stores information about supported AC models
@@ -23,14 +25,15 @@ CREATE TABLE IF NOT EXISTS models (
 CREATE TABLE IF NOT EXISTS ir_codes (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     model_id TEXT NOT NULL,                  -- References models.model_id
-    mode TEXT NOT NULL,                      -- e.g., "cool", "heat", "fan_only", "dry", "off"
-    temperature INTEGER,                     -- Temperature (NULL for "off" command)
-    fan_speed TEXT,                          -- e.g., "low", "medium", "high" (NULL for "off")
+    mode TEXT NOT NULL,                      -- e.g., "cool", "heat", "fan_only", "dry", "off", "on"
+    temperature REAL,                        -- Temperature, e.g., 22 or 16.5 (NULL for "off"/"on" and codes the file gives for any temperature)
+    fan_speed TEXT,                          -- e.g., "low", "medium", "high" (NULL for "off"/"on" and codes for any fan speed)
+    swing TEXT,                              -- e.g., "off", "on" (NULL when the file has no swing level)
     ir_code TEXT NOT NULL,                   -- Base64-encoded Tuya format IR code
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (model_id) REFERENCES models(model_id) ON DELETE CASCADE,
-    -- Ensure unique combinations for each model
-    UNIQUE(model_id, mode, temperature, fan_speed)
+    -- Ensure unique combinations for each model (NULLs never conflict: the loader updates them explicitly)
+    UNIQUE(model_id, mode, temperature, fan_speed, swing)
 );
 
 -- Index for fast lookups by state
@@ -41,6 +44,70 @@ ON ir_codes(model_id, mode, temperature, fan_speed);
 CREATE INDEX IF NOT EXISTS idx_ir_codes_mode 
 ON ir_codes(model_id, mode);
 
+-- Weekly schedules table
+-- Each row is one slot: on the given days at the given local time,
+-- apply the command (NULL fields leave the current value unchanged)
+CREATE TABLE IF NOT EXISTS schedules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    device_id TEXT NOT NULL,                 -- e.g., "living_room"
+    days TEXT NOT NULL,                      -- Comma-separated weekdays, e.g., "mon,tue,wed,thu,fri"
+    time TEXT NOT NULL,                      -- Local time "HH:MM", e.g., "07:00"
+    mode TEXT,                               -- e.g., "heat", "off" (NULL = unchanged)
+    temperature REAL,                        -- e.g., 21.0 (NULL = unchanged)
+    fan_mode TEXT,                           -- e.g., "low" (NULL = unchanged)
+    enabled INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for loading a device's schedule
+CREATE INDEX IF NOT EXISTS idx_schedules_device
+ON schedules(device_id);
+
+-- Sleep/off-delay timers table
+-- At most one pending timer per device; survives restarts
+CREATE TABLE IF NOT EXISTS timers (
+    device_id TEXT PRIMARY KEY,              -- e.g., "living_room"
+    fires_at INTEGER NOT NULL,               -- Unix time (seconds) when the timer fires
+    mode TEXT,                               -- Target mode (NULL with all fields NULL = off)
+    temperature REAL,                        -- Target temperature (NULL = unchanged)
+    fan_mode TEXT,                           -- Target fan mode (NULL = unchanged)
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Command audit log
+-- Every command received for a device, whatever its source and outcome
+CREATE TABLE IF NOT EXISTS command_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    device_id TEXT NOT NULL,                 -- e.g., "living_room"
+    created_at INTEGER NOT NULL,             -- Unix time (milliseconds) the command was applied
+    source TEXT NOT NULL,                    -- mqtt, http, schedule, timer
+    command TEXT NOT NULL,                   -- Received command as JSON
+    mode TEXT NOT NULL,                      -- Resulting state
+    temperature REAL NOT NULL,
+    fan_mode TEXT NOT NULL,
+    preset TEXT,
+    ir_code_id INTEGER,                      -- ir_codes.id sent; no foreign key so history survives model reloads
+    strategy TEXT,                           -- Lookup strategy (exact, fan, mode_temp, mode_only, off, preset)
+    success INTEGER NOT NULL,
+    error TEXT,
+    correlation_id TEXT
+);
+
+-- Index for a device's history
+CREATE INDEX IF NOT EXISTS idx_command_log_device
+ON command_log(device_id, id);
+
+-- Index for retention pruning
+CREATE INDEX IF NOT EXISTS idx_command_log_created
+ON command_log(created_at);
+
+-- Records are append-only: only retention pruning (DELETE) removes them
+CREATE TRIGGER IF NOT EXISTS command_log_append_only
+BEFORE UPDATE ON command_log
+BEGIN
+    SELECT RAISE(ABORT, 'command_log is append-only');
+END;
+
 -- Comments for documentation:
 -- 
 -- Usage Examples:
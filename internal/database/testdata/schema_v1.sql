@@ -0,0 +1,40 @@
+-- Schema version 1 (IR code tables only), as created by the first releases
+CREATE TABLE models (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    model_id TEXT NOT NULL UNIQUE,
+    manufacturer TEXT NOT NULL,
+    supported_models TEXT NOT NULL,
+    commands_encoding TEXT NOT NULL,
+    supported_controller TEXT NOT NULL,
+    min_temperature INTEGER NOT NULL,
+    max_temperature INTEGER NOT NULL,
+    precision REAL NOT NULL,
+    operation_modes TEXT NOT NULL,
+    fan_modes TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE ir_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    model_id TEXT NOT NULL,
+    mode TEXT NOT NULL,
+    temperature INTEGER,
+    fan_speed TEXT,
+    ir_code TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (model_id) REFERENCES models(model_id) ON DELETE CASCADE,
+    UNIQUE(model_id, mode, temperature, fan_speed)
+);
+
+CREATE INDEX idx_ir_codes_lookup ON ir_codes(model_id, mode, temperature, fan_speed);
+CREATE INDEX idx_ir_codes_mode ON ir_codes(model_id, mode);
+
+INSERT INTO models (model_id, manufacturer, supported_models, commands_encoding, supported_controller,
+    min_temperature, max_temperature, precision, operation_modes, fan_modes)
+VALUES ('1109', 'Daikin', '["BRC4C158"]', 'Raw', 'MQTT', 18, 32, 1.0, '["cool"]', '["low"]');
+
+INSERT INTO ir_codes (model_id, mode, temperature, fan_speed, ir_code) VALUES
+    ('1109', 'off', NULL, NULL, 'OFF'),
+    ('1109', 'cool', 22, 'low', 'COOL22');
+
+PRAGMA user_version = 1;
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Timer represents a pending sleep/off-delay timer for a device
+// With all target fields nil the device is turned off when the timer fires
+type Timer struct {
+	DeviceID    string
+	FiresAt     time.Time
+	Mode        *string  // e.g., "fan_only"
+	Temperature *float64 // e.g., 26.0
+	FanMode     *string  // e.g., "low"
+}
+
+// GetTimer returns the pending timer of a device, or nil if none is set
+func (db *DB) GetTimer(ctx context.Context, deviceID string) (*Timer, error) {
+	query := `
+		SELECT device_id, fires_at, mode, temperature, fan_mode
+		FROM timers
+		WHERE device_id = ?
+	`
+	var t Timer
+	var firesAt int64
+	var mode, fanMode sql.NullString
+	var temperature sql.NullFloat64
+	err := db.conn.QueryRowContext(ctx, query, deviceID).Scan(&t.DeviceID, &firesAt, &mode, &temperature, &fanMode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query timer: %w", err)
+	}
+
+	t.FiresAt = time.Unix(firesAt, 0)
+	if mode.Valid {
+		t.Mode = &mode.String
+	}
+	if temperature.Valid {
+		t.Temperature = &temperature.Float64
+	}
+	if fanMode.Valid {
+		t.FanMode = &fanMode.String
+	}
+	return &t, nil
+}
+
+// SaveTimer sets the timer of a device, replacing any pending one
+func (db *DB) SaveTimer(ctx context.Context, t *Timer) error {
+	query := `
+		INSERT OR REPLACE INTO timers (device_id, fires_at, mode, temperature, fan_mode)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err := db.conn.ExecContext(ctx, query, t.DeviceID, t.FiresAt.Unix(), t.Mode, t.Temperature, t.FanMode)
+	if err != nil {
+		return fmt.Errorf("failed to save timer: %w", err)
+	}
+	return nil
+}
+
+// DeleteTimer removes the pending timer of a device (no-op if none is set)
+func (db *DB) DeleteTimer(ctx context.Context, deviceID string) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM timers WHERE device_id = ?`, deviceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete timer: %w", err)
+	}
+	return nil
+}
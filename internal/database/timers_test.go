@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimers(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	// No timer set
+	timer, err := db.GetTimer(ctx, "living_room")
+	if err != nil {
+		t.Fatalf("GetTimer failed: %v", err)
+	}
+	if timer != nil {
+		t.Fatalf("Expected no timer, got %+v", timer)
+	}
+
+	firesAt := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	if err := db.SaveTimer(ctx, &Timer{DeviceID: "living_room", FiresAt: firesAt}); err != nil {
+		t.Fatalf("SaveTimer failed: %v", err)
+	}
+
+	// Replacing keeps a single timer per device
+	temp := 26.0
+	if err := db.SaveTimer(ctx, &Timer{DeviceID: "living_room", FiresAt: firesAt, Mode: strPtr("fan_only"), Temperature: &temp}); err != nil {
+		t.Fatalf("SaveTimer replace failed: %v", err)
+	}
+
+	timer, err = db.GetTimer(ctx, "living_room")
+	if err != nil {
+		t.Fatalf("GetTimer failed: %v", err)
+	}
+	if timer == nil || !timer.FiresAt.Equal(firesAt) {
+		t.Fatalf("Unexpected timer: %+v", timer)
+	}
+	if timer.Mode == nil || *timer.Mode != "fan_only" || timer.Temperature == nil || *timer.Temperature != 26 || timer.FanMode != nil {
+		t.Errorf("Unexpected target: %+v", timer)
+	}
+
+	if err := db.DeleteTimer(ctx, "living_room"); err != nil {
+		t.Fatalf("DeleteTimer failed: %v", err)
+	}
+	if timer, _ := db.GetTimer(ctx, "living_room"); timer != nil {
+		t.Errorf("Expected timer to be deleted, got %+v", timer)
+	}
+}
@@ -0,0 +1,324 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Lint issue severities
+// Errors make the file unusable or wrongly imported; warnings are gaps worth knowing about.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Lint issue kinds
+const (
+	IssueMetadata    = "metadata"    // Header fields invalid or not matching the commands
+	IssueExtraKey    = "extra_key"   // Key or nesting the loader cannot store
+	IssueUndecodable = "undecodable" // Code that is not valid for the declared encoding
+	IssueDuplicate   = "duplicate"   // Same code stored for different states
+	IssueMissing     = "missing"     // Declared mode × fan × temperature without a code (see LintReport.Missing)
+)
+
+// knownFileKeys are the top-level keys of a SmartIR climate file
+var knownFileKeys = []string{
+	"manufacturer", "supportedModels", "commandsEncoding", "supportedController",
+	"minTemperature", "maxTemperature", "precision", "operationModes", "fanModes", "swingModes", "commands",
+}
+
+// LintIssue is one problem found in a SmartIR file
+type LintIssue struct {
+	Severity string `json:"severity"`       // Severity*
+	Kind     string `json:"kind"`           // Issue*
+	Path     string `json:"path,omitempty"` // Location in the file, e.g., "commands/cool/low/22"
+	Message  string `json:"message"`
+}
+
+// MissingCodes lists the temperatures of a declared mode, fan speed and swing mode without a code
+type MissingCodes struct {
+	Mode         string    `json:"mode"`
+	FanSpeed     string    `json:"fan_speed"`
+	Swing        string    `json:"swing,omitempty"` // Set when the file declares swing modes
+	Temperatures []float64 `json:"temperatures"`
+}
+
+// LintReport is the result of ValidateSmartIR
+type LintReport struct {
+	Codes   int            `json:"codes"` // Codes found in the file
+	Issues  []LintIssue    `json:"issues"`
+	Missing []MissingCodes `json:"missing"` // Missing combinations, by mode and fan speed
+}
+
+// Errors returns the number of error issues
+func (r *LintReport) Errors() int {
+	return r.count(SeverityError)
+}
+
+// Warnings returns the number of warning issues
+func (r *LintReport) Warnings() int {
+	return r.count(SeverityWarning)
+}
+
+func (r *LintReport) count(severity string) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *LintReport) add(severity, kind, path, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, LintIssue{Severity: severity, Kind: kind, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// lintCode is a code found while walking the commands
+type lintCode struct {
+	path, mode, code string
+}
+
+// ValidateSmartIR checks a SmartIR climate file without importing it
+// Unlike the loader, which skips what it does not understand, every unexpected key,
+// undecodable or duplicate code and mismatch with the declared metadata is reported.
+func ValidateSmartIR(data []byte) *LintReport {
+	report := &LintReport{}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		report.add(SeverityError, IssueMetadata, "", "invalid JSON: %v", err)
+		return report
+	}
+	// The header only: the commands are walked below so that every problem is reported
+	var header struct {
+		SmartIRFile
+		Commands json.RawMessage `json:"commands"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		report.add(SeverityError, IssueMetadata, "", "invalid SmartIR file: %v", err)
+		return report
+	}
+	file := header.SmartIRFile
+
+	for _, key := range sortedKeys(raw) {
+		if !slices.Contains(knownFileKeys, key) {
+			report.add(SeverityWarning, IssueExtraKey, key, "unknown top-level key")
+		}
+	}
+	lintMetadata(report, &file)
+
+	var commands map[string]json.RawMessage
+	if err := json.Unmarshal(raw["commands"], &commands); err != nil || len(commands) == 0 {
+		report.add(SeverityError, IssueMetadata, "commands", "no commands object")
+		return report
+	}
+
+	// Walk the commands like the loader, collecting what it would store
+	var codes []lintCode
+	var states []SmartIRCode
+	usedFans := make(map[string]bool)
+	reported := make(map[string]bool) // Undeclared modes, fan speeds and swing modes are reported once
+	undeclared := func(path, format string, args ...interface{}) {
+		if !reported[path] {
+			reported[path] = true
+			report.add(SeverityError, IssueMetadata, path, format, args...)
+		}
+	}
+	walkCommands(raw["commands"], func(path string, code SmartIRCode) {
+		path = "commands/" + path
+		if code.Mode != "off" && code.Mode != "on" && !slices.Contains(file.OperationModes, code.Mode) {
+			undeclared("commands/"+code.Mode, "mode %s is not in operationModes", code.Mode)
+		}
+		if code.FanSpeed != nil {
+			usedFans[*code.FanSpeed] = true
+			if !slices.Contains(file.FanModes, *code.FanSpeed) {
+				undeclared("commands/"+code.Mode+"/"+*code.FanSpeed, "fan speed %s is not in fanModes", *code.FanSpeed)
+			}
+		}
+		if code.Swing != nil && !slices.Contains(file.SwingModes, *code.Swing) {
+			undeclared("commands/"+code.Mode+"/"+*code.FanSpeed+"/"+*code.Swing, "swing mode %s is not in swingModes", *code.Swing)
+		}
+		if t := code.Temperature; t != nil && (*t < float64(file.MinTemperature) || *t > float64(file.MaxTemperature)) {
+			report.add(SeverityWarning, IssueMetadata, path, "temperature outside %d-%d", file.MinTemperature, file.MaxTemperature)
+		}
+		codes = append(codes, lintCode{path: path, mode: code.Mode, code: code.Code})
+		states = append(states, code)
+	}, func(path, message string) {
+		// The loader skips these branches, so they only fail the file with -strict
+		report.add(SeverityWarning, IssueExtraKey, "commands/"+path, "%s", message)
+	})
+	report.Codes = len(codes)
+
+	if _, ok := commands["off"]; !ok {
+		report.add(SeverityError, IssueMetadata, "commands", "no off code")
+	}
+	for _, mode := range file.OperationModes {
+		if _, ok := commands[mode]; !ok {
+			report.add(SeverityError, IssueMetadata, "operationModes", "mode %s has no commands", mode)
+		}
+	}
+	for _, fan := range file.FanModes {
+		if !usedFans[fan] {
+			report.add(SeverityWarning, IssueMetadata, "fanModes", "fan speed %s has no commands", fan)
+		}
+	}
+
+	lintCodes(report, &file, codes)
+	lintMissing(report, &file, states)
+	return report
+}
+
+// lintMetadata checks the header fields the loader relies on
+func lintMetadata(report *LintReport, file *SmartIRFile) {
+	if file.Manufacturer == "" {
+		report.add(SeverityError, IssueMetadata, "manufacturer", "missing manufacturer")
+	}
+	if len(file.SupportedModels) == 0 {
+		report.add(SeverityWarning, IssueMetadata, "supportedModels", "no supported models listed")
+	}
+	switch {
+	case file.CommandsEncoding == "Base64":
+	case file.CommandsEncoding == "Raw" && file.SupportedController == "MQTT":
+	default:
+		report.add(SeverityError, IssueMetadata, "commandsEncoding",
+			"unsupported encoding %q with controller %q (expected Base64, or Raw with MQTT)", file.CommandsEncoding, file.SupportedController)
+	}
+	if file.MinTemperature >= file.MaxTemperature {
+		report.add(SeverityError, IssueMetadata, "minTemperature", "minTemperature %d is not below maxTemperature %d", file.MinTemperature, file.MaxTemperature)
+	}
+	if file.Precision <= 0 {
+		report.add(SeverityError, IssueMetadata, "precision", "precision must be positive")
+	}
+	if len(file.OperationModes) == 0 {
+		report.add(SeverityError, IssueMetadata, "operationModes", "no operation modes")
+	}
+	if len(file.FanModes) == 0 {
+		report.add(SeverityWarning, IssueMetadata, "fanModes", "no fan modes")
+	}
+}
+
+// lintCodes reports codes that cannot be decoded and codes shared by different states
+// Codes repeated within a mode that ignores temperature (fan_only, dry) are expected.
+func lintCodes(report *LintReport, file *SmartIRFile, codes []lintCode) {
+	byCode := make(map[string][]lintCode)
+	var order []string
+	for _, c := range codes {
+		if err := decodeCode(file.CommandsEncoding, c.code); err != nil {
+			report.add(SeverityError, IssueUndecodable, c.path, "%v", err)
+			continue
+		}
+		if _, ok := byCode[c.code]; !ok {
+			order = append(order, c.code)
+		}
+		byCode[c.code] = append(byCode[c.code], c)
+	}
+
+	policy := FallbackPolicy{}
+	for _, code := range order {
+		uses := byCode[code]
+		if len(uses) < 2 {
+			continue
+		}
+		sameMode := true
+		for _, c := range uses {
+			sameMode = sameMode && c.mode == uses[0].mode
+		}
+		if sameMode && !policy.requiresTemperature(uses[0].mode) && uses[0].mode != "off" {
+			continue
+		}
+
+		paths := make([]string, len(uses))
+		for i, c := range uses {
+			paths[i] = strings.TrimPrefix(c.path, "commands/")
+		}
+		report.add(SeverityWarning, IssueDuplicate, uses[0].path, "same code used for %d states: %s", len(uses), strings.Join(paths, ", "))
+	}
+}
+
+// decodeCode checks that a code is valid for the file's encoding
+func decodeCode(encoding, code string) error {
+	if code == "" {
+		return fmt.Errorf("empty code")
+	}
+	if encoding == "Base64" {
+		if _, err := ConvertBroadlinkToTuya(code); err != nil {
+			return fmt.Errorf("invalid Broadlink code: %w", err)
+		}
+		return nil
+	}
+	if _, err := base64.StdEncoding.DecodeString(code); err != nil {
+		return fmt.Errorf("invalid Tuya code: %w", err)
+	}
+	return nil
+}
+
+// lintMissing fills the missing combinations matrix of the declared modes, fan speeds, swing modes and temperatures
+// A code stored without a level (e.g., per fan speed, without temperatures) covers every value of that level.
+func lintMissing(report *LintReport, file *SmartIRFile, states []SmartIRCode) {
+	if file.Precision <= 0 || file.MinTemperature >= file.MaxTemperature {
+		return
+	}
+	step := file.Precision
+
+	byMode := make(map[string][]SmartIRCode)
+	for _, state := range states {
+		byMode[state.Mode] = append(byMode[state.Mode], state)
+	}
+	swings := []string{""} // Any swing mode when none are declared
+	if len(file.SwingModes) > 0 {
+		swings = file.SwingModes
+	}
+
+	for _, mode := range file.OperationModes {
+		if byMode[mode] == nil {
+			continue // Reported as a mode without commands
+		}
+		for _, fan := range file.FanModes {
+			for _, swing := range swings {
+				var missing []float64
+				for temp := float64(file.MinTemperature); temp <= float64(file.MaxTemperature); temp += step {
+					if !covered(byMode[mode], fan, swing, temp) {
+						missing = append(missing, temp)
+					}
+				}
+				if len(missing) == 0 {
+					continue
+				}
+
+				path := "commands/" + mode + "/" + fan
+				if swing != "" {
+					path += "/" + swing
+				}
+				report.Missing = append(report.Missing, MissingCodes{Mode: mode, FanSpeed: fan, Swing: swing, Temperatures: missing})
+				report.add(SeverityWarning, IssueMissing, path, "%d of the declared temperatures have no code", len(missing))
+			}
+		}
+	}
+}
+
+// covered reports whether one of the codes of a mode applies to a fan speed, swing mode ("" = any) and temperature
+func covered(codes []SmartIRCode, fan, swing string, temp float64) bool {
+	for _, c := range codes {
+		if (c.FanSpeed == nil || *c.FanSpeed == fan) &&
+			(c.Swing == nil || swing == "" || *c.Swing == swing) &&
+			(c.Temperature == nil || *c.Temperature == temp) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a JSON object in order
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// lintFile is a Tuya-format file with one problem of each kind
+const lintFile = `{
+	"manufacturer": "Test",
+	"supportedModels": ["T1"],
+	"commandsEncoding": "Raw",
+	"supportedController": "MQTT",
+	"minTemperature": 20,
+	"maxTemperature": 22,
+	"precision": 1,
+	"operationModes": ["cool", "heat", "dry"],
+	"fanModes": ["low", "high"],
+	"comment": "not a SmartIR key",
+	"commands": {
+		"cool": {
+			"low": {"20": "QQ==", "21": "Qg==", "22": "Qw==", "24": "RA=="},
+			"high": {"20": "RQ==", "21": "!!", "22": "Qw==", "warm": "Rg=="}
+		},
+		"dry": {"low": {"20": "Rw==", "21": "Rw==", "22": "Rw==", "23": {"on": "SQ=="}}, "turbo": {"20": "SA=="}},
+		"fan_only": {"low": {"20": "SQ=="}}
+	}
+}`
+
+// findIssue returns the first issue of a kind at a path
+func findIssue(r *LintReport, kind, path string) (LintIssue, bool) {
+	for _, issue := range r.Issues {
+		if issue.Kind == kind && issue.Path == path {
+			return issue, true
+		}
+	}
+	return LintIssue{}, false
+}
+
+func TestValidateSmartIR(t *testing.T) {
+	r := ValidateSmartIR([]byte(lintFile))
+
+	tests := []struct {
+		kind     string
+		path     string
+		severity string
+	}{
+		{IssueExtraKey, "comment", SeverityWarning},
+		{IssueExtraKey, "commands/dry/low/23", SeverityWarning},
+		{IssueMetadata, "commands/cool/high/warm", SeverityError}, // Read as an undeclared swing mode
+		{IssueUndecodable, "commands/cool/high/21", SeverityError},
+		{IssueDuplicate, "commands/cool/high/22", SeverityWarning},
+		{IssueMetadata, "commands/cool/low/24", SeverityWarning}, // Outside the temperature range
+		{IssueMetadata, "commands/dry/turbo", SeverityError},     // Undeclared fan speed
+		{IssueMetadata, "commands/fan_only", SeverityError},      // Undeclared mode
+		{IssueMetadata, "commands", SeverityError},               // No off code
+		{IssueMetadata, "operationModes", SeverityError},         // heat has no commands
+		{IssueMissing, "commands/dry/high", SeverityWarning},
+	}
+	for _, tt := range tests {
+		issue, ok := findIssue(r, tt.kind, tt.path)
+		if !ok {
+			t.Errorf("Expected %s issue at %s, got %+v", tt.kind, tt.path, r.Issues)
+			continue
+		}
+		if issue.Severity != tt.severity {
+			t.Errorf("%s at %s: severity %s, want %s", tt.kind, tt.path, issue.Severity, tt.severity)
+		}
+	}
+
+	// Repeated dry codes are expected: dry ignores temperature
+	if issue, ok := findIssue(r, IssueDuplicate, "commands/dry/low/20"); ok {
+		t.Errorf("Unexpected duplicate in dry: %+v", issue)
+	}
+
+	// The undecodable cool/high/21 is reported as such, not as missing
+	wantMissing := []MissingCodes{{Mode: "dry", FanSpeed: "high", Temperatures: []float64{20, 21, 22}}}
+	if !reflect.DeepEqual(r.Missing, wantMissing) {
+		t.Errorf("Missing = %+v, want %+v", r.Missing, wantMissing)
+	}
+	if r.Errors() == 0 {
+		t.Error("Expected errors")
+	}
+}
+
+func TestValidateSmartIR_Reference(t *testing.T) {
+	data, err := os.ReadFile(referenceFile)
+	if os.IsNotExist(err) {
+		t.Skipf("Reference file not found: %s", referenceFile)
+	}
+	if err != nil {
+		t.Fatalf("Failed to read reference file: %v", err)
+	}
+
+	r := ValidateSmartIR(data)
+	if r.Errors() != 0 {
+		t.Errorf("Expected no errors in the reference file, got %+v", r.Issues)
+	}
+	if r.Codes != 193 {
+		t.Errorf("Expected 193 codes, got %d", r.Codes)
+	}
+	// The file declares up to 32°C but stops at 31
+	for _, m := range r.Missing {
+		if !reflect.DeepEqual(m.Temperatures, []float64{32}) {
+			t.Errorf("%s/%s: missing %v, want [32]", m.Mode, m.FanSpeed, m.Temperatures)
+		}
+	}
+}
+
+func TestValidateSmartIR_Invalid(t *testing.T) {
+	for _, data := range []string{`{"manufacturer": `, `[]`, `{"manufacturer": "Test"}`} {
+		if r := ValidateSmartIR([]byte(data)); r.Errors() == 0 {
+			t.Errorf("Expected errors for %q", data)
+		}
+	}
+}
+
+// TestValidateSmartIR_Swing tests the swing level and codes without temperatures
+func TestValidateSmartIR_Swing(t *testing.T) {
+	r := ValidateSmartIR([]byte(`{
+		"manufacturer": "Test", "supportedModels": ["T1"], "commandsEncoding": "Raw", "supportedController": "MQTT",
+		"minTemperature": 20, "maxTemperature": 21, "precision": 1,
+		"operationModes": ["cool", "fan_only"], "fanModes": ["low"], "swingModes": ["off", "on"],
+		"commands": {
+			"off": "QQ==", "on": "Qg==",
+			"cool": {"low": {"off": {"20": "Qw==", "21": "RA=="}, "on": {"20": "RQ=="}}},
+			"fan_only": {"low": "Rg=="}
+		}
+	}`))
+	if r.Errors() != 0 {
+		t.Errorf("Expected no errors, got %+v", r.Issues)
+	}
+	if r.Codes != 6 {
+		t.Errorf("Expected 6 codes, got %d", r.Codes)
+	}
+	// fan_only/low has one code for every swing mode and temperature
+	wantMissing := []MissingCodes{{Mode: "cool", FanSpeed: "low", Swing: "on", Temperatures: []float64{21}}}
+	if !reflect.DeepEqual(r.Missing, wantMissing) {
+		t.Errorf("Missing = %+v, want %+v", r.Missing, wantMissing)
+	}
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "docs", "smartir", "reference", "1116.json"))
+	if err != nil {
+		t.Skipf("Reference file not found: %v", err)
+	}
+	if r := ValidateSmartIR(data); r.Errors() != 0 {
+		t.Errorf("Expected no errors in 1116.json, got %+v", r.Issues)
+	}
+}
@@ -0,0 +1,764 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/audit"
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/homeassistant"
+	"github.com/diogoaguiar/hvac-manager/internal/integration"
+	"github.com/diogoaguiar/hvac-manager/internal/interfaces"
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
+	"github.com/diogoaguiar/hvac-manager/internal/metrics"
+	"github.com/diogoaguiar/hvac-manager/internal/preset"
+	"github.com/diogoaguiar/hvac-manager/internal/sensor"
+	"github.com/diogoaguiar/hvac-manager/internal/state"
+	"github.com/diogoaguiar/hvac-manager/internal/thermostat"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
+)
+
+// Config describes a single AC unit driven by an IR blaster
+type Config struct {
+	ID         string               // Used in MQTT topics and HA unique IDs, e.g., "living_room"
+	Name       string               // Display name in HA, e.g., "Living Room AC"
+	ModelID    string               // SmartIR model ID, e.g., "1109"
+	BlasterID  string               // Zigbee2MQTT friendly name of the IR blaster
+	Sensor     sensor.Config        // Optional room sensor
+	Thermostat thermostat.Config    // Optional closed-loop control (requires Sensor)
+	Presets    []preset.Preset      // HA preset modes (none = presets disabled)
+	PowerOn    integration.PowerOn  // Optional on code before the state code when the AC turns on
+	Sequence   integration.Sequence // Repeats, delays and extra codes of the model's transmissions
+	CommandLog CommandLog           // Optional audit log of every command (nil = disabled)
+}
+
+// CommandLog records the commands applied to a device (implemented by *database.DB)
+type CommandLog interface {
+	LogCommand(ctx context.Context, r *database.CommandRecord) error
+}
+
+// Device owns the state of one AC unit and is the single command path for it
+// All methods are safe for concurrent use (MQTT callbacks, control loop)
+// IR transmissions are serialized by sendMu and run without mu, so that power-on
+// and inter-frame delays never block status reads or sensor updates.
+// Lock order: sendMu, then mu.
+type Device struct {
+	sendMu sync.Mutex // Held for a whole command or control step, IR send included
+	mu     sync.Mutex // Held only while reading or changing the fields below
+
+	cfg    Config
+	db     interfaces.IRDatabase
+	mqtt   interfaces.MQTTPublisher
+	topics topics.Builder
+
+	state      *state.ACState         // Desired state as shown in HA
+	sent       *state.ACState         // Last state successfully sent over IR
+	matched    *lookup.Result         // Stored code matched for sent, nil after a preset code
+	irPower    bool                   // Power of the last state sent over IR (presets included); the AC is assumed off at startup
+	controller *thermostat.Controller // Closed-loop control, nil when disabled
+
+	lastSend         time.Time // Time of the last successful IR send
+	blasterAvailable *bool     // Blaster availability from Zigbee2MQTT, nil until reported
+}
+
+// ErrInvalidCommand is wrapped by Apply errors caused by the command itself
+// (as opposed to IR send failures), e.g., to answer HTTP 400 instead of 502
+var ErrInvalidCommand = errors.New("invalid command")
+
+// Status is a snapshot of a device for the HTTP API
+type Status struct {
+	ID               string                     `json:"id"`
+	Name             string                     `json:"name"`
+	ModelID          string                     `json:"model_id"`
+	BlasterID        string                     `json:"blaster_id"`
+	State            homeassistant.ClimateState `json:"state"`
+	BlasterAvailable *bool                      `json:"blaster_available"` // null until Zigbee2MQTT reports it
+	LastIRSend       string                     `json:"last_ir_send"`      // RFC 3339, empty if nothing was sent yet
+}
+
+// New creates a device with the default initial state
+func New(cfg Config, db interfaces.IRDatabase, mqtt interfaces.MQTTPublisher, t topics.Builder) *Device {
+	d := &Device{
+		cfg:    cfg,
+		db:     db,
+		mqtt:   mqtt,
+		topics: t,
+		state:  state.NewACState(),
+	}
+	if cfg.Thermostat.Enabled() {
+		d.controller = thermostat.NewController(cfg.Thermostat)
+	}
+	return d
+}
+
+// ID returns the device identifier
+func (d *Device) ID() string {
+	return d.cfg.ID
+}
+
+// Config returns the device configuration
+func (d *Device) Config() Config {
+	return d.cfg
+}
+
+// IRTopic returns the Zigbee2MQTT topic of the device's IR blaster
+func (d *Device) IRTopic() string {
+	return d.topics.Z2MSet(d.cfg.BlasterID)
+}
+
+// State returns a copy of the current desired state
+func (d *Device) State() state.ACState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return *d.state
+}
+
+// Status returns a snapshot of the device
+func (d *Device) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := Status{
+		ID:               d.cfg.ID,
+		Name:             d.cfg.Name,
+		ModelID:          d.cfg.ModelID,
+		BlasterID:        d.cfg.BlasterID,
+		State:            *d.climateStateLocked(),
+		BlasterAvailable: d.blasterAvailable,
+	}
+	if !d.lastSend.IsZero() {
+		status.LastIRSend = d.lastSend.Format(time.RFC3339)
+	}
+	return status
+}
+
+// LastSend returns the time of the last successful IR send (zero if none)
+func (d *Device) LastSend() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lastSend
+}
+
+// BlasterAvailable reports the blaster availability; ok is false until Zigbee2MQTT reports it
+func (d *Device) BlasterAvailable() (available bool, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.blasterAvailable == nil {
+		return false, false
+	}
+	return *d.blasterAvailable, true
+}
+
+// BlasterAvailabilityTopic returns the Zigbee2MQTT availability topic of the blaster
+func (d *Device) BlasterAvailabilityTopic() string {
+	return d.topics.Z2MAvailability(d.cfg.BlasterID)
+}
+
+// HandleBlasterAvailability records the blaster availability reported by Zigbee2MQTT
+// Accepts both the legacy plain payload ("online") and the JSON one ({"state": "online"})
+func (d *Device) HandleBlasterAvailability(payload []byte) {
+	value := strings.TrimSpace(string(payload))
+	var msg struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(payload, &msg); err == nil && msg.State != "" {
+		value = msg.State
+	}
+
+	available := value == "online"
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.blasterAvailable = &available
+	logger.Info("📡 IR blaster %s is %s", d.cfg.BlasterID, value)
+}
+
+// PublishDiscovery publishes the Home Assistant MQTT Discovery payload
+func (d *Device) PublishDiscovery() error {
+	discovery := homeassistant.NewClimateDiscoveryWithTopics(d.topics, d.cfg.ID, d.cfg.Name)
+	if d.cfg.Sensor.Enabled() {
+		discovery.EnableRoomSensor(d.cfg.Sensor.HasHumidity())
+	}
+	discovery.EnablePresets(preset.Names(d.cfg.Presets))
+
+	payload, err := discovery.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal discovery: %w", err)
+	}
+
+	topic := discovery.ConfigTopic(d.cfg.ID)
+	if err := d.mqtt.Publish(topic, 2, true, payload); err != nil {
+		return fmt.Errorf("failed to publish discovery: %w", err)
+	}
+
+	logger.Info("✅ Published discovery to: %s", topic)
+	return nil
+}
+
+// PublishAvailability publishes the online/offline status
+func (d *Device) PublishAvailability(online bool) error {
+	payload := "offline"
+	if online {
+		payload = "online"
+	}
+	return d.mqtt.Publish(d.topics.Availability(d.cfg.ID), 1, true, payload)
+}
+
+// PublishState publishes the current AC state to Home Assistant
+func (d *Device) PublishState() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.publishStateLocked()
+}
+
+// climateStateLocked builds the state shown in HA; caller must hold d.mu
+func (d *Device) climateStateLocked() *homeassistant.ClimateState {
+	action := d.state.Action()
+	if d.controller != nil && !d.controller.Running() {
+		// Cycled to fan_only by the control loop: the unit is not heating/cooling
+		action = state.ActionIdle
+	}
+
+	presetMode := d.state.Preset
+	if presetMode == "" {
+		presetMode = preset.None
+	}
+
+	haState := &homeassistant.ClimateState{
+		Temperature:        d.state.Temperature,
+		CurrentTemperature: d.state.CurrentTemperature,
+		CurrentHumidity:    d.state.CurrentHumidity,
+		Mode:               d.state.Mode,
+		FanMode:            d.state.FanMode,
+		PresetMode:         presetMode,
+		Action:             action,
+		Power:              d.state.Power,
+		Error:              d.state.LastError,
+		LastUpdated:        d.state.LastUpdated.Format(time.RFC3339),
+	}
+
+	// What the AC actually received, which differs from the above after a fallback
+	if m := d.matched; m != nil {
+		haState.EffectiveMode = m.Mode
+		if m.Temperature != nil {
+			temp := float64(*m.Temperature)
+			haState.EffectiveTemperature = &temp
+		}
+		if m.FanSpeed != nil {
+			haState.EffectiveFanMode = *m.FanSpeed
+		}
+		haState.LookupStrategy = m.Strategy
+	}
+	return haState
+}
+
+// publishStateLocked publishes the state; caller must hold d.mu
+func (d *Device) publishStateLocked() error {
+	haState := d.climateStateLocked()
+
+	payload, err := homeassistant.StateToJSON(haState)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	topic := d.topics.State(d.cfg.ID)
+
+	// Log what we're about to publish
+	logger.Info("📤 Publishing HA state to: %s", topic)
+	logger.Info("   JSON: %s", string(payload))
+
+	if err := d.mqtt.Publish(topic, 0, true, payload); err != nil {
+		return fmt.Errorf("failed to publish state: %w", err)
+	}
+
+	return nil
+}
+
+// HandleCommand processes a raw command payload received over MQTT
+// ctx is the service's run context: cancelling it on shutdown interrupts an IR sequence in progress.
+func (d *Device) HandleCommand(ctx context.Context, payload []byte) {
+	ctx = logger.WithContext(audit.WithSource(ctx, audit.SourceMQTT),
+		logger.FieldCorrelationID, logger.NewCorrelationID(),
+		logger.FieldTopic, d.topics.Command(d.cfg.ID))
+	log := logger.FromContext(ctx)
+
+	if logger.IsText() {
+		fmt.Println("\n" + strings.Repeat("─", 60))
+		defer fmt.Println(strings.Repeat("─", 60))
+	}
+	log.Info("📥 Received command: %s", string(payload))
+
+	// Try to parse as JSON first
+	cmd, err := homeassistant.ParseCommand(payload)
+	if err != nil {
+		// If JSON parsing fails, treat as plain text (temperature or mode value)
+		payloadStr := string(payload)
+		logger.Debug("📋 Plain text command: %s", payloadStr)
+
+		cmd, err = ParsePlainCommand(payloadStr)
+		if err != nil {
+			log.Error("%v", err)
+			d.Reject(ctx, payload, err)
+			return
+		}
+	}
+	if cmd.Empty() {
+		log.Error("%v", homeassistant.ErrEmptyCommand)
+		d.Reject(ctx, payload, homeassistant.ErrEmptyCommand)
+		return
+	}
+
+	// Pretty print the command for visibility
+	cmdJSON, _ := json.MarshalIndent(cmd, "", "  ")
+	logger.Debug("📋 Parsed command:\n%s", string(cmdJSON))
+
+	if err := d.Apply(ctx, cmd); err != nil {
+		log.Error("❌ Command failed: %v", err)
+	}
+}
+
+// ParsePlainCommand converts a plain text payload (temperature, mode or fan mode) into a command
+func ParsePlainCommand(payloadStr string) (*homeassistant.ClimateCommand, error) {
+	cmd := &homeassistant.ClimateCommand{}
+
+	// Try to parse as temperature (numeric)
+	if temp, err := strconv.ParseFloat(payloadStr, 64); err == nil {
+		cmd.Temperature = &temp
+		return cmd, nil
+	}
+
+	// Otherwise treat as mode or fan mode
+	for _, mode := range state.ValidModes {
+		if payloadStr == mode {
+			cmd.Mode = &payloadStr
+			return cmd, nil
+		}
+	}
+	for _, fanMode := range state.ValidFanModes {
+		if payloadStr == fanMode {
+			cmd.FanMode = &payloadStr
+			return cmd, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not parse command as JSON or plain text: %s", payloadStr)
+}
+
+// Apply validates a command, sends the resulting IR code and publishes the state
+// This is the single command path shared by every command source.
+// On failure the previous state is restored, unless codes already reached the AC
+// before an interruption, and the error is published to HA.
+func (d *Device) Apply(ctx context.Context, cmd *homeassistant.ClimateCommand) (err error) {
+	start := time.Now()
+	ctx = logger.WithContext(ctx, logger.FieldDeviceID, d.cfg.ID, logger.FieldModelID, d.cfg.ModelID)
+	log := logger.FromContext(ctx)
+	var result lookup.Result
+
+	d.sendMu.Lock()
+	defer d.sendMu.Unlock()
+	defer func() {
+		command, _ := json.Marshal(cmd)
+		d.mu.Lock()
+		record := d.commandRecordLocked(ctx, string(command), result, err)
+		d.mu.Unlock()
+		d.logCommand(ctx, record)
+	}()
+
+	d.mu.Lock()
+
+	// Save original state before any modifications
+	originalState := *d.state
+
+	// Apply changes to state
+	active, stateChanged, err := applyCommand(d.state, cmd, d.cfg.Presets)
+	if err != nil {
+		// Report the invalid command to HA without touching the AC
+		*d.state = originalState
+		d.state.SetError(err)
+		d.publishOrLog()
+		d.mu.Unlock()
+		metrics.CommandsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "invalid")
+		return fmt.Errorf("%w: %w", ErrInvalidCommand, err)
+	}
+
+	if !stateChanged {
+		d.mu.Unlock()
+		log.Warn("⚠️  No valid state changes in command")
+		metrics.CommandsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "ignored")
+		return nil
+	}
+
+	// A manual command restarts closed-loop control from the new target
+	if d.controller != nil {
+		d.controller.Reset()
+	}
+
+	// Send the preset's own code, or look up the code of the effective state
+	var presetCode *preset.Preset
+	if active != nil && active.Code != "" {
+		presetCode = active
+	}
+	tx := d.planLocked(presetCode)
+	d.mu.Unlock()
+
+	// Try to send IR code to IR blaster
+	result, err = d.transmit(ctx, tx)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err != nil {
+		if errors.Is(err, integration.ErrPartiallySent) {
+			// The AC may already be in the new state, so it is kept
+			d.commitLocked(tx, result)
+			log.With(logger.FieldDuration, time.Since(start)).Warn("⚠️  Command interrupted, keeping %s", d.state.String())
+		} else {
+			// Revert to original state on failure
+			d.revertLocked(originalState)
+			log.With(logger.FieldDuration, time.Since(start)).Warn("⏪ Reverted to original state: %s", originalState.String())
+		}
+		d.state.SetError(err)
+		d.publishOrLog()
+		metrics.CommandsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "error")
+		return err
+	}
+	d.commitLocked(tx, result)
+
+	// Always publish actual state
+	d.publishOrLog()
+	log.With(logger.FieldDuration, time.Since(start)).Info("✅ Command applied: %s", d.state.String())
+	metrics.CommandsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "success")
+	return nil
+}
+
+// revertLocked restores the state from before a failed command; caller must hold d.mu
+// Room readings received while the command was being sent are kept.
+func (d *Device) revertLocked(original state.ACState) {
+	original.CurrentTemperature = d.state.CurrentTemperature
+	original.CurrentHumidity = d.state.CurrentHumidity
+	original.RoomUpdated = d.state.RoomUpdated
+	*d.state = original
+}
+
+// Reject records a command refused before it reached Apply, e.g., a payload that does not parse
+// The raw payload is recorded as the command, with the reason as the error.
+func (d *Device) Reject(ctx context.Context, payload []byte, reason error) {
+	metrics.CommandsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "invalid")
+
+	d.mu.Lock()
+	record := d.commandRecordLocked(ctx, string(payload), lookup.Result{}, reason)
+	d.mu.Unlock()
+	d.logCommand(ctx, record)
+}
+
+// commandRecordLocked builds the command log entry of a command's outcome; caller must hold d.mu
+// Returns nil without a command log.
+func (d *Device) commandRecordLocked(ctx context.Context, command string, result lookup.Result, cmdErr error) *database.CommandRecord {
+	if d.cfg.CommandLog == nil {
+		return nil
+	}
+
+	record := &database.CommandRecord{
+		DeviceID:      d.cfg.ID,
+		Source:        audit.SourceFrom(ctx),
+		Command:       command,
+		Mode:          d.state.Mode,
+		Temperature:   d.state.Temperature,
+		FanMode:       d.state.FanMode,
+		Preset:        d.state.Preset,
+		Strategy:      result.Strategy,
+		Success:       cmdErr == nil,
+		CorrelationID: logger.CorrelationIDFrom(ctx),
+	}
+	if result.CodeID != 0 {
+		record.IRCodeID = &result.CodeID
+	}
+	if cmdErr != nil {
+		record.Error = cmdErr.Error()
+	}
+	return record
+}
+
+// logCommand appends a record to the command log; caller must not hold d.mu
+// Failures are logged only: the audit log must never block a command
+func (d *Device) logCommand(ctx context.Context, record *database.CommandRecord) {
+	if record == nil {
+		return
+	}
+	if err := d.cfg.CommandLog.LogCommand(context.WithoutCancel(ctx), record); err != nil {
+		logger.FromContext(ctx).Error("Failed to record command: %v", err)
+	}
+}
+
+// transmission is an IR send planned under d.mu and performed without it
+type transmission struct {
+	state   state.ACState  // Effective state to send
+	preset  *preset.Preset // Preset whose own code is sent instead of a looked up one
+	powerOn bool           // Send the on code first (see integration.PowerOn)
+}
+
+// planLocked builds the transmission for the current state; caller must hold d.mu
+// With closed-loop control the effective state may differ from the desired state.
+// The on code goes first if PowerOn is enabled and the last state sent over IR was off.
+func (d *Device) planLocked(p *preset.Preset) transmission {
+	tx := transmission{state: *d.state, preset: p}
+	if p == nil && d.controller != nil {
+		tx.state = d.controller.Evaluate(*d.state, time.Now())
+	}
+	tx.powerOn = d.cfg.PowerOn.Enabled && tx.state.Power && !d.irPower
+	return tx
+}
+
+// transmit sends a planned transmission; caller must hold d.sendMu but not d.mu
+// The returned lookup.Result is that of the failing on code if the power-on step fails.
+func (d *Device) transmit(ctx context.Context, tx transmission) (lookup.Result, error) {
+	ctx = metrics.WithDevice(ctx, d.cfg.ID) // Labels lookup metrics with this device
+	ctx = logger.WithContext(ctx, logger.FieldDeviceID, d.cfg.ID, logger.FieldModelID, d.cfg.ModelID)
+	log := logger.FromContext(ctx)
+
+	if tx.preset != nil {
+		return d.transmitPreset(ctx, tx)
+	}
+
+	if tx.powerOn {
+		if result, err := d.sendPowerOn(ctx); err != nil {
+			return result, err
+		}
+	}
+	result, err := integration.SendIRCode(ctx, d.db, d.mqtt, d.cfg.ModelID, d.IRTopic(), &tx.state, d.cfg.Sequence)
+	if err != nil {
+		log.Error("❌ Failed to send IR code: %v", err)
+		metrics.IRSendsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "failure")
+		return result, err
+	}
+	if result.Fallback() {
+		log.Info("↪️  Fallback %s sent for %s", result, tx.state.String())
+	}
+
+	log.Debug("✅ IR code sent successfully")
+	metrics.IRSendsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "success")
+	return result, nil
+}
+
+// transmitPreset sends a preset's dedicated IR code; caller must hold d.sendMu but not d.mu
+func (d *Device) transmitPreset(ctx context.Context, tx transmission) (lookup.Result, error) {
+	result := lookup.Result{Strategy: "preset"}
+	if !d.mqtt.IsConnected() {
+		metrics.IRSendsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "failure")
+		return result, fmt.Errorf("MQTT client not connected")
+	}
+	if tx.powerOn {
+		if _, err := d.sendPowerOn(ctx); err != nil {
+			return result, err
+		}
+	}
+	if err := integration.SendFrames(ctx, d.mqtt, d.IRTopic(), d.cfg.Sequence.Frames(tx.preset.Code, tx.state.Power)); err != nil {
+		logger.Error("❌ Failed to send IR code: %v", err)
+		metrics.IRSendsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "failure")
+		return result, err
+	}
+	metrics.IRSendsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "success")
+
+	logger.Info("✅ Preset %s code sent successfully", tx.preset.Name)
+	return result, nil
+}
+
+// sendPowerOn sends the on code and waits for the unit to start; caller must not hold d.mu
+// The returned lookup.Result is that of the on code.
+func (d *Device) sendPowerOn(ctx context.Context) (lookup.Result, error) {
+	result, err := integration.SendPowerOn(ctx, d.db, d.mqtt, d.cfg.ModelID, d.IRTopic(), d.cfg.PowerOn.Delay)
+	if err != nil {
+		logger.FromContext(ctx).Error("❌ Failed to send on code: %v", err)
+		metrics.IRSendsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "failure")
+		return result, err
+	}
+	metrics.IRSendsTotal.Inc(d.cfg.ID, d.cfg.ModelID, "success")
+	return result, nil
+}
+
+// commitLocked records a successful transmission; caller must hold d.mu
+// After a preset code the control loop pauses until the next command since the
+// AC is no longer in a state that maps to a lookup code
+func (d *Device) commitLocked(tx transmission, result lookup.Result) {
+	d.state.ClearError()
+	if tx.preset != nil {
+		d.sent = nil
+		d.matched = nil
+	} else {
+		sent := tx.state
+		d.sent = &sent
+		d.matched = &result
+	}
+	d.irPower = tx.state.Power
+	d.lastSend = time.Now()
+}
+
+// publishOrLog publishes the state and logs failures; caller must hold d.mu
+func (d *Device) publishOrLog() {
+	if err := d.publishStateLocked(); err != nil {
+		logger.Error("Failed to publish state: %v", err)
+	}
+}
+
+// applyCommand applies the fields present in cmd to acState
+// A preset is applied first; individual fields in the same command override it.
+// Returns the preset that is still active after the command (if any) and
+// true if at least one field was set
+func applyCommand(acState *state.ACState, cmd *homeassistant.ClimateCommand, presets []preset.Preset) (*preset.Preset, bool, error) {
+	stateChanged := false
+	var active *preset.Preset
+
+	if cmd.PresetMode != nil {
+		name := *cmd.PresetMode
+		if name == preset.None || name == "" {
+			// Keep the current settings, only drop the preset label
+			acState.SetPreset("")
+			stateChanged = true
+			logger.Info("🎚️  Preset cleared")
+		} else {
+			p, ok := preset.Find(presets, name)
+			if !ok {
+				return nil, false, fmt.Errorf("invalid preset: %s (valid: %v)", name, preset.Names(presets))
+			}
+			if _, _, err := applyCommand(acState, p.Command(), nil); err != nil {
+				return nil, false, fmt.Errorf("preset %s: %w", name, err)
+			}
+			acState.SetPreset(name)
+			active = &p
+			stateChanged = true
+			logger.Info("🎚️  Preset set to: %s", name)
+		}
+	}
+
+	if cmd.Temperature != nil {
+		if err := acState.SetTemperature(*cmd.Temperature); err != nil {
+			return nil, false, fmt.Errorf("invalid temperature: %w", err)
+		}
+		stateChanged = true
+		logger.Info("🌡️  Temperature set to: %.1f°C", *cmd.Temperature)
+	}
+
+	if cmd.Mode != nil {
+		if err := acState.SetMode(*cmd.Mode); err != nil {
+			return nil, false, fmt.Errorf("invalid mode: %w", err)
+		}
+		stateChanged = true
+		logger.Info("🔄 Mode set to: %s", *cmd.Mode)
+	}
+
+	if cmd.FanMode != nil {
+		if err := acState.SetFanMode(*cmd.FanMode); err != nil {
+			return nil, false, fmt.Errorf("invalid fan mode: %w", err)
+		}
+		stateChanged = true
+		logger.Info("💨 Fan mode set to: %s", *cmd.FanMode)
+	}
+
+	// An individual field in the same command replaced the preset
+	if active != nil && acState.Preset != active.Name {
+		active = nil
+	}
+
+	return active, stateChanged, nil
+}
+
+// sameIRState returns true if both states map to the same IR code
+func sameIRState(a, b state.ACState) bool {
+	return a.Mode == b.Mode && a.Temperature == b.Temperature && a.FanMode == b.FanMode
+}
+
+// HandleSensorReading updates the room readings and republishes the state
+func (d *Device) HandleSensorReading(topic string, payload []byte) {
+	reading, err := d.cfg.Sensor.Parse(topic, payload)
+	if err != nil {
+		logger.Warn("Ignoring room sensor message: %v", err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.state.SetRoomReading(reading.Temperature, reading.Humidity, reading.Time)
+	if reading.Temperature != nil {
+		logger.Debug("🌡️  Room temperature: %.1f°C", *reading.Temperature)
+	}
+	if reading.Humidity != nil {
+		logger.Debug("💧 Room humidity: %.0f%%", *reading.Humidity)
+	}
+
+	d.publishOrLog()
+}
+
+// Run executes the closed-loop control loop until ctx is cancelled
+// Returns immediately if closed-loop control is disabled
+func (d *Device) Run(ctx context.Context) {
+	if d.controller == nil {
+		return
+	}
+
+	cfg := d.controller.Config()
+	logger.Info("🎛️  Thermostat control (%s) active for %s: ±%.1f°C, check every %s",
+		cfg.Strategy, d.cfg.ID, cfg.Hysteresis, cfg.Interval)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.controlStep(ctx)
+		}
+	}
+}
+
+// controlStep re-evaluates the control loop and sends IR only if needed
+func (d *Device) controlStep(ctx context.Context) {
+	d.sendMu.Lock()
+	defer d.sendMu.Unlock()
+	d.mu.Lock()
+
+	// Nothing to hold while the user has the AC off or no code was ever sent
+	if d.sent == nil || !d.state.Power {
+		d.mu.Unlock()
+		return
+	}
+
+	wasRunning := d.controller.Running()
+	previous := *d.sent
+
+	tx := d.planLocked(nil)
+	if sameIRState(previous, tx.state) {
+		if wasRunning != d.controller.Running() {
+			d.publishOrLog()
+		}
+		d.mu.Unlock()
+		return
+	}
+	d.mu.Unlock()
+
+	result, err := d.transmit(ctx, tx)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if errors.Is(err, integration.ErrPartiallySent) {
+		d.commitLocked(tx, result) // The AC may already be in the new state
+	}
+	if err != nil {
+		d.state.SetError(err)
+		d.publishOrLog()
+		return
+	}
+	d.commitLocked(tx, result)
+
+	logger.Info("🎛️  Thermostat: %s → %s", previous.String(), d.sent.String())
+	d.publishOrLog()
+}
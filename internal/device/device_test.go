@@ -0,0 +1,666 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/audit"
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/homeassistant"
+	"github.com/diogoaguiar/hvac-manager/internal/integration"
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
+	"github.com/diogoaguiar/hvac-manager/internal/metrics"
+	"github.com/diogoaguiar/hvac-manager/internal/mocks"
+	"github.com/diogoaguiar/hvac-manager/internal/preset"
+	"github.com/diogoaguiar/hvac-manager/internal/sensor"
+	"github.com/diogoaguiar/hvac-manager/internal/thermostat"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
+)
+
+const testIRTopic = "zigbee2mqtt/ir-blaster/set"
+
+func testConfig() Config {
+	return Config{
+		ID:        "living_room",
+		Name:      "Living Room AC",
+		ModelID:   "1109",
+		BlasterID: "ir-blaster",
+	}
+}
+
+func testDB() *mocks.MockDatabase {
+	return &mocks.MockDatabase{
+		Codes: map[string]string{
+			"1109:cool:22:auto":     "COOL22",
+			"1109:cool:24:auto":     "COOL24",
+			"1109:cool:23:auto":     "COOL23",
+			"1109:fan_only:24:auto": "FAN",
+		},
+		OffCodes: map[string]string{"1109": "OFF"},
+	}
+}
+
+func strPtr(s string) *string     { return &s }
+func floatPtr(f float64) *float64 { return &f }
+
+// irCodes returns the IR codes published to the blaster, in order
+func irCodes(t *testing.T, m *mocks.MockMQTT) []string {
+	t.Helper()
+	var codes []string
+	for _, pub := range m.Published {
+		if pub.Topic != testIRTopic {
+			continue
+		}
+		var payload map[string]string
+		if err := json.Unmarshal(pub.Payload.([]byte), &payload); err != nil {
+			t.Fatalf("Invalid IR payload: %v", err)
+		}
+		codes = append(codes, payload["ir_code_to_send"])
+	}
+	return codes
+}
+
+// signalMQTT is a MockMQTT safe for concurrent use that reports each IR code as it is published
+type signalMQTT struct {
+	mu sync.Mutex
+	mocks.MockMQTT
+	sent chan string
+}
+
+func newSignalMQTT() *signalMQTT {
+	return &signalMQTT{MockMQTT: mocks.MockMQTT{Connected: true}, sent: make(chan string, 16)}
+}
+
+func (m *signalMQTT) Publish(topic string, qos byte, retained bool, payload interface{}) error {
+	m.mu.Lock()
+	err := m.MockMQTT.Publish(topic, qos, retained, payload)
+	m.mu.Unlock()
+
+	if topic == testIRTopic {
+		var p map[string]string
+		_ = json.Unmarshal(payload.([]byte), &p)
+		m.sent <- p["ir_code_to_send"]
+	}
+	return err
+}
+
+// expectIRCode waits for the next IR code published to m
+func expectIRCode(t *testing.T, m *signalMQTT, want string) {
+	t.Helper()
+	select {
+	case code := <-m.sent:
+		if code != want {
+			t.Fatalf("IR code = %q, want %q", code, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for IR code %q", want)
+	}
+}
+
+// expectStatus fails if d.Status blocks, e.g., because d.mu is held during an IR send
+func expectStatus(t *testing.T, d *Device) Status {
+	t.Helper()
+	done := make(chan Status, 1)
+	go func() { done <- d.Status() }()
+	select {
+	case status := <-done:
+		return status
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("Status blocked while an IR sequence was being sent")
+		return Status{}
+	}
+}
+
+// lastState returns the last ClimateState published to the state topic
+func lastState(t *testing.T, m *mocks.MockMQTT) homeassistant.ClimateState {
+	t.Helper()
+	stateTopic := topics.Default().State("living_room")
+	for i := len(m.Published) - 1; i >= 0; i-- {
+		if m.Published[i].Topic != stateTopic {
+			continue
+		}
+		var s homeassistant.ClimateState
+		if err := json.Unmarshal(m.Published[i].Payload.([]byte), &s); err != nil {
+			t.Fatalf("Invalid state payload: %v", err)
+		}
+		return s
+	}
+	t.Fatal("No state published")
+	return homeassistant.ClimateState{}
+}
+
+func TestApply_Success(t *testing.T) {
+	mqtt := &mocks.MockMQTT{Connected: true}
+	d := New(testConfig(), testDB(), mqtt, topics.Default())
+
+	err := d.Apply(context.Background(), &homeassistant.ClimateCommand{Mode: strPtr("cool")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if codes := irCodes(t, mqtt); len(codes) != 1 || codes[0] != "COOL22" {
+		t.Errorf("IR codes = %v, want [COOL22]", codes)
+	}
+
+	published := lastState(t, mqtt)
+	if published.Mode != "cool" || published.Action != "cooling" || !published.Power {
+		t.Errorf("Unexpected published state: %+v", published)
+	}
+}
+
+func TestApply_SendFailureReverts(t *testing.T) {
+	db := testDB()
+	db.Err = errors.New("database unavailable")
+	mqtt := &mocks.MockMQTT{Connected: true}
+	d := New(testConfig(), db, mqtt, topics.Default())
+
+	err := d.Apply(context.Background(), &homeassistant.ClimateCommand{Mode: strPtr("cool")})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	if got := d.State(); got.Mode != "off" || got.LastError == "" {
+		t.Errorf("Expected reverted state with error, got %s (error %q)", got.String(), got.LastError)
+	}
+	if published := lastState(t, mqtt); published.Error == "" {
+		t.Error("Expected error in published state")
+	}
+}
+
+func TestApply_InvalidCommand(t *testing.T) {
+	db := testDB()
+	mqtt := &mocks.MockMQTT{Connected: true}
+	d := New(testConfig(), db, mqtt, topics.Default())
+
+	err := d.Apply(context.Background(), &homeassistant.ClimateCommand{Temperature: floatPtr(45)})
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	if len(db.Calls) != 0 {
+		t.Errorf("Invalid command should not reach the database, got %v", db.Calls)
+	}
+	if got := d.State(); got.Temperature != 22 {
+		t.Errorf("Temperature should be unchanged, got %.1f", got.Temperature)
+	}
+}
+
+func presetConfig() Config {
+	cfg := testConfig()
+	cfg.Presets = []preset.Preset{
+		{Name: "eco", Temperature: floatPtr(24), FanMode: strPtr("auto")},
+		{Name: "turbo", Code: "TURBO"},
+	}
+	return cfg
+}
+
+func TestApply_Preset(t *testing.T) {
+	mqtt := &mocks.MockMQTT{Connected: true}
+	d := New(presetConfig(), testDB(), mqtt, topics.Default())
+	ctx := context.Background()
+
+	if err := d.Apply(ctx, &homeassistant.ClimateCommand{Mode: strPtr("cool")}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if published := lastState(t, mqtt); published.PresetMode != "none" {
+		t.Errorf("Expected preset_mode none, got %q", published.PresetMode)
+	}
+
+	// State bundle preset: looked up like any other state
+	if err := d.Apply(ctx, &homeassistant.ClimateCommand{PresetMode: strPtr("eco")}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if codes := irCodes(t, mqtt); codes[len(codes)-1] != "COOL24" {
+		t.Errorf("IR codes = %v, want COOL24 last", codes)
+	}
+	if published := lastState(t, mqtt); published.PresetMode != "eco" || published.Temperature != 24 {
+		t.Errorf("Unexpected published state: %+v", published)
+	}
+
+	// Changing an individual field leaves the preset
+	if err := d.Apply(ctx, &homeassistant.ClimateCommand{Temperature: floatPtr(22)}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if published := lastState(t, mqtt); published.PresetMode != "none" {
+		t.Errorf("Expected preset cleared, got %q", published.PresetMode)
+	}
+
+	// Dedicated code preset: sent as-is
+	if err := d.Apply(ctx, &homeassistant.ClimateCommand{PresetMode: strPtr("turbo")}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if codes := irCodes(t, mqtt); codes[len(codes)-1] != "TURBO" {
+		t.Errorf("IR codes = %v, want TURBO last", codes)
+	}
+	if got := d.State(); got.Preset != "turbo" {
+		t.Errorf("Preset = %q, want turbo", got.Preset)
+	}
+
+	// Unknown preset is rejected without touching the AC
+	sent := len(irCodes(t, mqtt))
+	if err := d.Apply(ctx, &homeassistant.ClimateCommand{PresetMode: strPtr("party")}); err == nil {
+		t.Error("Expected error for unknown preset")
+	}
+	if len(irCodes(t, mqtt)) != sent {
+		t.Error("Unknown preset should not send IR")
+	}
+	if got := d.State(); got.Preset != "turbo" {
+		t.Errorf("Preset should be unchanged, got %q", got.Preset)
+	}
+}
+
+// TestApply_PowerOn tests that the on code precedes the state code only when the AC turns on
+func TestApply_PowerOn(t *testing.T) {
+	mqtt := &mocks.MockMQTT{Connected: true}
+	db := testDB()
+	db.OnCodes = map[string]string{"1109": "ON"}
+	cfg := testConfig()
+	cfg.PowerOn = integration.PowerOn{Enabled: true}
+	d := New(cfg, db, mqtt, topics.Default())
+	ctx := context.Background()
+
+	commands := []struct {
+		cmd  homeassistant.ClimateCommand
+		want []string
+	}{
+		{homeassistant.ClimateCommand{Mode: strPtr("cool")}, []string{"ON", "COOL22"}},
+		{homeassistant.ClimateCommand{Temperature: floatPtr(23)}, []string{"COOL23"}},
+		{homeassistant.ClimateCommand{Mode: strPtr("off")}, []string{"OFF"}},
+		{homeassistant.ClimateCommand{Mode: strPtr("cool")}, []string{"ON", "COOL23"}},
+	}
+	for _, tt := range commands {
+		before := len(irCodes(t, mqtt))
+		if err := d.Apply(ctx, &tt.cmd); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if codes := irCodes(t, mqtt)[before:]; !slices.Equal(codes, tt.want) {
+			t.Errorf("IR codes = %v, want %v", codes, tt.want)
+		}
+	}
+
+	// Without an on code the command fails and the AC stays off
+	db.OnCodes = nil
+	if err := d.Apply(ctx, &homeassistant.ClimateCommand{Mode: strPtr("off")}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := d.Apply(ctx, &homeassistant.ClimateCommand{Mode: strPtr("cool")}); err == nil {
+		t.Error("Expected an error without an on code")
+	}
+	if got := d.State(); got.Power {
+		t.Errorf("Expected the state to stay off, got %s", got.String())
+	}
+}
+
+// TestApply_PowerOnDelayDoesNotBlock tests that the device can be read while the AC starts up
+func TestApply_PowerOnDelayDoesNotBlock(t *testing.T) {
+	mqtt := newSignalMQTT()
+	db := testDB()
+	db.OnCodes = map[string]string{"1109": "ON"}
+	cfg := testConfig()
+	cfg.PowerOn = integration.PowerOn{Enabled: true, Delay: 500 * time.Millisecond}
+	d := New(cfg, db, mqtt, topics.Default())
+
+	done := make(chan error, 1)
+	go func() { done <- d.Apply(context.Background(), &homeassistant.ClimateCommand{Mode: strPtr("cool")}) }()
+
+	expectIRCode(t, mqtt, "ON")
+	if status := expectStatus(t, d); status.State.Mode != "cool" {
+		t.Errorf("Status mode = %q during power-on, want cool", status.State.Mode)
+	}
+
+	expectIRCode(t, mqtt, "COOL22")
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestApply_Sequence(t *testing.T) {
+	mqtt := &mocks.MockMQTT{Connected: true}
+	cfg := testConfig()
+	cfg.Sequence = integration.Sequence{Repeat: 2, After: []integration.Frame{{Code: "SWING"}}}
+	d := New(cfg, testDB(), mqtt, topics.Default())
+	ctx := context.Background()
+
+	commands := []struct {
+		cmd  homeassistant.ClimateCommand
+		want []string
+	}{
+		{homeassistant.ClimateCommand{Mode: strPtr("cool")}, []string{"COOL22", "COOL22", "SWING"}},
+		{homeassistant.ClimateCommand{Mode: strPtr("off")}, []string{"OFF", "OFF"}},
+	}
+	for _, tt := range commands {
+		before := len(irCodes(t, mqtt))
+		if err := d.Apply(ctx, &tt.cmd); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if codes := irCodes(t, mqtt)[before:]; !slices.Equal(codes, tt.want) {
+			t.Errorf("IR codes = %v, want %v", codes, tt.want)
+		}
+	}
+}
+
+// TestHandleCommand_CancelSequence tests that cancelling the run context interrupts an MQTT command's IR sequence
+// The first frame already reached the AC, so the new state is kept
+func TestHandleCommand_CancelSequence(t *testing.T) {
+	mqtt := newSignalMQTT()
+	cfg := testConfig()
+	cfg.Sequence = integration.Sequence{Repeat: 3, DelayMS: 500}
+	d := New(cfg, testDB(), mqtt, topics.Default())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.HandleCommand(ctx, []byte(`{"mode": "cool"}`))
+		close(done)
+	}()
+
+	// The device stays readable between frames
+	expectIRCode(t, mqtt, "COOL22")
+	expectStatus(t, d)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("HandleCommand did not return after cancellation")
+	}
+
+	if n := len(mqtt.sent); n != 0 {
+		t.Errorf("Expected no transmission after cancellation, got %d more", n)
+	}
+	if got := d.State(); got.Mode != "cool" || got.LastError == "" {
+		t.Errorf("Expected the cool state kept with an error, got %s (error %q)", got.String(), got.LastError)
+	}
+}
+
+func TestParsePlainCommand(t *testing.T) {
+	tests := []struct {
+		payload string
+		check   func(*homeassistant.ClimateCommand) bool
+		wantErr bool
+	}{
+		{"21.5", func(c *homeassistant.ClimateCommand) bool { return c.Temperature != nil && *c.Temperature == 21.5 }, false},
+		{"heat", func(c *homeassistant.ClimateCommand) bool { return c.Mode != nil && *c.Mode == "heat" }, false},
+		{"high", func(c *homeassistant.ClimateCommand) bool { return c.FanMode != nil && *c.FanMode == "high" }, false},
+		{"turbo", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.payload, func(t *testing.T) {
+			cmd, err := ParsePlainCommand(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePlainCommand(%q) error = %v, wantErr %v", tt.payload, err, tt.wantErr)
+			}
+			if !tt.wantErr && !tt.check(cmd) {
+				t.Errorf("ParsePlainCommand(%q) = %+v", tt.payload, cmd)
+			}
+		})
+	}
+}
+
+func TestHandleSensorReading(t *testing.T) {
+	cfg := testConfig()
+	cfg.Sensor = sensor.Config{TemperatureTopic: "zigbee2mqtt/room", TemperaturePath: "temperature"}
+	mqtt := &mocks.MockMQTT{Connected: true}
+	d := New(cfg, testDB(), mqtt, topics.Default())
+
+	d.HandleSensorReading("zigbee2mqtt/room", []byte(`{"temperature": 23.5}`))
+
+	published := lastState(t, mqtt)
+	if published.CurrentTemperature == nil || *published.CurrentTemperature != 23.5 {
+		t.Errorf("Expected current_temperature 23.5, got %v", published.CurrentTemperature)
+	}
+}
+
+func TestControlStep_CycleStrategy(t *testing.T) {
+	cfg := testConfig()
+	cfg.Sensor = sensor.Config{TemperatureTopic: "zigbee2mqtt/room", TemperaturePath: "temperature"}
+	cfg.Thermostat = thermostat.DefaultConfig()
+	cfg.Thermostat.Strategy = thermostat.StrategyCycle
+	cfg.Thermostat.MinOnTime = 0
+	cfg.Thermostat.MinOffTime = 0
+
+	mqtt := &mocks.MockMQTT{Connected: true}
+	d := New(cfg, testDB(), mqtt, topics.Default())
+	ctx := context.Background()
+
+	d.HandleSensorReading("zigbee2mqtt/room", []byte(`{"temperature": 26}`))
+	if err := d.Apply(ctx, &homeassistant.ClimateCommand{Mode: strPtr("cool"), Temperature: floatPtr(24)}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Still warm: nothing new to send
+	d.controlStep(ctx)
+	if codes := irCodes(t, mqtt); len(codes) != 1 || codes[0] != "COOL24" {
+		t.Fatalf("IR codes = %v, want [COOL24]", codes)
+	}
+
+	// Room overshoots: compressor off (fan_only), target unchanged in HA
+	d.HandleSensorReading("zigbee2mqtt/room", []byte(`{"temperature": 23}`))
+	d.controlStep(ctx)
+	if codes := irCodes(t, mqtt); len(codes) != 2 || codes[1] != "FAN" {
+		t.Fatalf("IR codes = %v, want [COOL24 FAN]", codes)
+	}
+	published := lastState(t, mqtt)
+	if published.Mode != "cool" || published.Action != "idle" {
+		t.Errorf("Expected mode cool with idle action, got %s/%s", published.Mode, published.Action)
+	}
+
+	// Room warms up again: back to cooling
+	d.HandleSensorReading("zigbee2mqtt/room", []byte(`{"temperature": 25}`))
+	d.controlStep(ctx)
+	if codes := irCodes(t, mqtt); len(codes) != 3 || codes[2] != "COOL24" {
+		t.Fatalf("IR codes = %v, want [COOL24 FAN COOL24]", codes)
+	}
+	if published := lastState(t, mqtt); published.Action != "cooling" {
+		t.Errorf("Expected cooling action, got %s", published.Action)
+	}
+}
+
+func TestControlStep_SkipsWhenOff(t *testing.T) {
+	cfg := testConfig()
+	cfg.Thermostat = thermostat.DefaultConfig()
+	cfg.Thermostat.Strategy = thermostat.StrategySetpoint
+	mqtt := &mocks.MockMQTT{Connected: true}
+	d := New(cfg, testDB(), mqtt, topics.Default())
+
+	d.state.SetRoomReading(floatPtr(30), nil, time.Now())
+	d.controlStep(context.Background())
+
+	if len(mqtt.Published) != 0 {
+		t.Errorf("Control loop should not act before the first command, got %d publishes", len(mqtt.Published))
+	}
+}
+
+func TestHandleBlasterAvailability(t *testing.T) {
+	d := New(testConfig(), testDB(), &mocks.MockMQTT{Connected: true}, topics.Default())
+
+	if _, known := d.BlasterAvailable(); known {
+		t.Error("Availability should be unknown before the first report")
+	}
+
+	tests := []struct {
+		payload string
+		want    bool
+	}{
+		{`online`, true},
+		{`{"state": "offline"}`, false},
+		{`{"state": "online"}`, true},
+		{`offline`, false},
+	}
+	for _, tt := range tests {
+		d.HandleBlasterAvailability([]byte(tt.payload))
+		if got, known := d.BlasterAvailable(); !known || got != tt.want {
+			t.Errorf("After %s: available = %v (known %v), want %v", tt.payload, got, known, tt.want)
+		}
+	}
+}
+
+func TestApply_Metrics(t *testing.T) {
+	cfg := testConfig()
+	cfg.ID = "metrics_room" // Metrics are process-wide; use a device no other test touches
+	db := testDB()
+	d := New(cfg, db, &mocks.MockMQTT{Connected: true}, topics.Default())
+	ctx := context.Background()
+
+	d.Apply(ctx, &homeassistant.ClimateCommand{Mode: strPtr("cool")})
+	d.Apply(ctx, &homeassistant.ClimateCommand{Temperature: floatPtr(45)})
+	db.Err = errors.New("database unavailable")
+	d.Apply(ctx, &homeassistant.ClimateCommand{Mode: strPtr("off")})
+
+	counts := []struct {
+		counter *metrics.CounterVec
+		result  string
+	}{
+		{metrics.CommandsTotal, "success"},
+		{metrics.CommandsTotal, "invalid"},
+		{metrics.CommandsTotal, "error"},
+		{metrics.IRSendsTotal, "success"},
+		{metrics.IRSendsTotal, "failure"},
+	}
+	for _, c := range counts {
+		if got := c.counter.Value("metrics_room", "1109", c.result); got != 1 {
+			t.Errorf("%s count = %v, want 1", c.result, got)
+		}
+	}
+}
+
+// fakeCommandLog collects command records
+type fakeCommandLog struct {
+	records []*database.CommandRecord
+	onLog   func() // Called on every record, if set
+}
+
+func (f *fakeCommandLog) LogCommand(ctx context.Context, r *database.CommandRecord) error {
+	if f.onLog != nil {
+		f.onLog()
+	}
+	f.records = append(f.records, r)
+	return nil
+}
+
+// TestCommandLog_Unlocked tests that records are written without holding the device lock
+func TestCommandLog_Unlocked(t *testing.T) {
+	log := &fakeCommandLog{}
+	cfg := testConfig()
+	cfg.CommandLog = log
+	d := New(cfg, testDB(), &mocks.MockMQTT{Connected: true}, topics.Default())
+	log.onLog = func() {
+		if !d.mu.TryLock() {
+			t.Error("LogCommand called with the device lock held")
+			return
+		}
+		d.mu.Unlock()
+	}
+
+	d.Apply(context.Background(), &homeassistant.ClimateCommand{Mode: strPtr("cool")})
+	d.Reject(context.Background(), []byte("turbo"), errors.New("unknown command"))
+	if len(log.records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(log.records))
+	}
+}
+
+func TestApply_CommandLog(t *testing.T) {
+	log := &fakeCommandLog{}
+	cfg := testConfig()
+	cfg.CommandLog = log
+	d := New(cfg, testDB(), &mocks.MockMQTT{Connected: true}, topics.Default())
+
+	ctx := logger.WithContext(audit.WithSource(context.Background(), audit.SourceSchedule), logger.FieldCorrelationID, "corr-1")
+	d.Apply(ctx, &homeassistant.ClimateCommand{Mode: strPtr("cool")})
+	d.Apply(context.Background(), &homeassistant.ClimateCommand{Mode: strPtr("dry")})
+
+	if len(log.records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(log.records))
+	}
+
+	ok := log.records[0]
+	if !ok.Success || ok.Source != audit.SourceSchedule || ok.CorrelationID != "corr-1" ||
+		ok.Mode != "cool" || ok.Command != `{"mode":"cool"}` {
+		t.Errorf("Unexpected success record: %+v", ok)
+	}
+
+	// Failed commands are recorded with the reverted state
+	failed := log.records[1]
+	if failed.Success || failed.Error == "" || failed.Source != audit.SourceUnknown || failed.Mode != "cool" {
+		t.Errorf("Unexpected failure record: %+v", failed)
+	}
+}
+
+// TestHandleCommand_RecordsRejected tests that a payload that does not parse, or sets nothing, is still recorded
+func TestHandleCommand_RecordsRejected(t *testing.T) {
+	log := &fakeCommandLog{}
+	cfg := testConfig()
+	cfg.CommandLog = log
+	mqtt := &mocks.MockMQTT{Connected: true}
+	d := New(cfg, testDB(), mqtt, topics.Default())
+
+	d.HandleCommand(context.Background(), []byte("turbo"))
+	d.HandleCommand(context.Background(), []byte("{}"))
+
+	if len(log.records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(log.records))
+	}
+	for i, want := range []string{"turbo", "{}"} {
+		r := log.records[i]
+		if r.Command != want || r.Success || r.Error == "" || r.Source != audit.SourceMQTT || r.CorrelationID == "" {
+			t.Errorf("Unexpected record: %+v", r)
+		}
+	}
+	if len(irCodes(t, mqtt)) != 0 {
+		t.Error("No IR code should be sent for a rejected command")
+	}
+}
+
+// fallbackDB resolves every lookup to the low fan speed, like a model without auto fan codes
+type fallbackDB struct {
+	*mocks.MockDatabase
+}
+
+func (f fallbackDB) LookupCode(ctx context.Context, modelID, mode string, temperature float64, fanSpeed string) (string, lookup.Result, error) {
+	code, result, err := f.MockDatabase.LookupCode(ctx, modelID, mode, temperature, fanSpeed)
+	if err == nil && fanSpeed != "low" {
+		low := "low"
+		result.CodeID, result.FanSpeed, result.Strategy = 7, &low, lookup.StrategyFan
+	}
+	return code, result, err
+}
+
+func TestApply_EffectiveState(t *testing.T) {
+	log := &fakeCommandLog{}
+	mqtt := &mocks.MockMQTT{Connected: true}
+	cfg := presetConfig()
+	cfg.CommandLog = log
+	d := New(cfg, fallbackDB{testDB()}, mqtt, topics.Default())
+
+	if published := d.Status().State; published.EffectiveFanMode != "" || published.LookupStrategy != "" {
+		t.Errorf("Expected no effective state before any send, got %+v", published)
+	}
+
+	if err := d.Apply(context.Background(), &homeassistant.ClimateCommand{Mode: strPtr("cool")}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	published := lastState(t, mqtt)
+	if published.FanMode != "auto" || published.EffectiveFanMode != "low" || published.EffectiveMode != "cool" ||
+		published.EffectiveTemperature == nil || *published.EffectiveTemperature != 22 || published.LookupStrategy != lookup.StrategyFan {
+		t.Errorf("Unexpected published state: %+v", published)
+	}
+	if r := log.records[0]; r.IRCodeID == nil || *r.IRCodeID != 7 || r.Strategy != lookup.StrategyFan {
+		t.Errorf("Unexpected command record: %+v", r)
+	}
+
+	// A dedicated preset code does not come from a lookup
+	if err := d.Apply(context.Background(), &homeassistant.ClimateCommand{PresetMode: strPtr("turbo")}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if published := lastState(t, mqtt); published.EffectiveFanMode != "" || published.LookupStrategy != "" {
+		t.Errorf("Expected effective state cleared after preset code, got %+v", published)
+	}
+}
@@ -0,0 +1,190 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+)
+
+// Check statuses
+const (
+	StatusOK      = "ok"
+	StatusFail    = "fail"
+	StatusUnknown = "unknown" // Not reported yet; does not fail readiness
+)
+
+// DefaultTimeout bounds each readiness probe
+const DefaultTimeout = 2 * time.Second
+
+// Pinger checks the database connection (implemented by *database.DB)
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Connection reports the MQTT connection state (implemented by *mqtt.Client)
+type Connection interface {
+	IsConnected() bool
+}
+
+// Device reports blaster availability and the last IR send (implemented by *device.Device)
+type Device interface {
+	ID() string
+	BlasterAvailable() (available bool, known bool)
+	LastSend() time.Time
+}
+
+// Config holds the dependencies checked by /readyz
+type Config struct {
+	DB      Pinger
+	MQTT    Connection
+	Devices []Device
+
+	// MaxCommandAge fails readiness when a device has not sent an IR code for
+	// longer than this (0 = report only, an idle AC is not a failure)
+	MaxCommandAge time.Duration
+
+	Timeout time.Duration // Per-probe timeout (0 = DefaultTimeout)
+}
+
+// Check is the result of a single readiness probe
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the /readyz response
+type Report struct {
+	Ready  bool    `json:"ready"`
+	Checks []Check `json:"checks"`
+}
+
+// Checker runs the liveness and readiness probes
+type Checker struct {
+	cfg     Config
+	started time.Time
+}
+
+// New creates a health checker
+func New(cfg Config) *Checker {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Checker{cfg: cfg, started: time.Now()}
+}
+
+// Ready runs all readiness probes
+func (c *Checker) Ready(ctx context.Context, now time.Time) Report {
+	report := Report{Ready: true}
+	add := func(check Check) {
+		if check.Status == StatusFail {
+			report.Ready = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	add(c.checkDatabase(ctx))
+	add(c.checkMQTT())
+	for _, d := range c.cfg.Devices {
+		add(checkBlaster(d))
+		add(c.checkLastCommand(d, now))
+	}
+
+	return report
+}
+
+// checkDatabase pings the database
+func (c *Checker) checkDatabase(ctx context.Context) Check {
+	check := Check{Name: "database", Status: StatusOK}
+	if c.cfg.DB == nil {
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	if err := c.cfg.DB.Ping(ctx); err != nil {
+		check.Status = StatusFail
+		check.Detail = err.Error()
+	}
+	return check
+}
+
+// checkMQTT reports the broker connection
+func (c *Checker) checkMQTT() Check {
+	check := Check{Name: "mqtt", Status: StatusOK}
+	if c.cfg.MQTT != nil && !c.cfg.MQTT.IsConnected() {
+		check.Status = StatusFail
+		check.Detail = "not connected to broker"
+	}
+	return check
+}
+
+// checkBlaster reports the IR blaster availability seen on Zigbee2MQTT
+// Unknown availability passes: Z2M availability reporting may be disabled
+func checkBlaster(d Device) Check {
+	check := Check{Name: "blaster:" + d.ID()}
+	available, known := d.BlasterAvailable()
+	switch {
+	case !known:
+		check.Status = StatusUnknown
+		check.Detail = "no availability reported by Zigbee2MQTT"
+	case available:
+		check.Status = StatusOK
+	default:
+		check.Status = StatusFail
+		check.Detail = "IR blaster offline"
+	}
+	return check
+}
+
+// checkLastCommand reports when the device last sent an IR code
+func (c *Checker) checkLastCommand(d Device, now time.Time) Check {
+	check := Check{Name: "last_command:" + d.ID(), Status: StatusOK}
+	last := d.LastSend()
+	if last.IsZero() {
+		check.Detail = "no command sent yet"
+		return check
+	}
+
+	age := now.Sub(last).Truncate(time.Second)
+	check.Detail = fmt.Sprintf("%s (%s ago)", last.Format(time.RFC3339), age)
+	if c.cfg.MaxCommandAge > 0 && age > c.cfg.MaxCommandAge {
+		check.Status = StatusFail
+	}
+	return check
+}
+
+// LivenessHandler answers /healthz: the process is up and serving requests
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"status": StatusOK,
+			"uptime": time.Since(c.started).Truncate(time.Second).String(),
+		})
+	})
+}
+
+// ReadinessHandler answers /readyz with the check breakdown (503 when not ready)
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Ready(r.Context(), time.Now())
+		status := http.StatusOK
+		if !report.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("Failed to write health response: %v", err)
+	}
+}
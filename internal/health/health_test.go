@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/mocks"
+)
+
+type fakeDB struct{ err error }
+
+func (f fakeDB) Ping(ctx context.Context) error { return f.err }
+
+type fakeDevice struct {
+	available, known bool
+	lastSend         time.Time
+}
+
+func (f fakeDevice) ID() string                     { return "living_room" }
+func (f fakeDevice) BlasterAvailable() (bool, bool) { return f.available, f.known }
+func (f fakeDevice) LastSend() time.Time            { return f.lastSend }
+
+func TestReady(t *testing.T) {
+	now := time.Date(2025, 1, 15, 21, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantReady bool
+		wantFail  string
+	}{
+		{
+			name:      "All healthy",
+			cfg:       Config{DB: fakeDB{}, MQTT: &mocks.MockMQTT{Connected: true}, Devices: []Device{fakeDevice{available: true, known: true}}},
+			wantReady: true,
+		},
+		{
+			name:      "Unknown blaster availability passes",
+			cfg:       Config{DB: fakeDB{}, MQTT: &mocks.MockMQTT{Connected: true}, Devices: []Device{fakeDevice{}}},
+			wantReady: true,
+		},
+		{
+			name:     "Database down",
+			cfg:      Config{DB: fakeDB{err: errors.New("disk I/O error")}, MQTT: &mocks.MockMQTT{Connected: true}},
+			wantFail: "database",
+		},
+		{
+			name:     "MQTT disconnected",
+			cfg:      Config{DB: fakeDB{}, MQTT: &mocks.MockMQTT{Connected: false}},
+			wantFail: "mqtt",
+		},
+		{
+			name:     "Blaster offline",
+			cfg:      Config{DB: fakeDB{}, MQTT: &mocks.MockMQTT{Connected: true}, Devices: []Device{fakeDevice{known: true}}},
+			wantFail: "blaster:living_room",
+		},
+		{
+			name: "Stale last command",
+			cfg: Config{DB: fakeDB{}, MQTT: &mocks.MockMQTT{Connected: true}, MaxCommandAge: time.Hour,
+				Devices: []Device{fakeDevice{available: true, known: true, lastSend: now.Add(-2 * time.Hour)}}},
+			wantFail: "last_command:living_room",
+		},
+		{
+			name: "Old last command without limit",
+			cfg: Config{DB: fakeDB{}, MQTT: &mocks.MockMQTT{Connected: true},
+				Devices: []Device{fakeDevice{available: true, known: true, lastSend: now.Add(-48 * time.Hour)}}},
+			wantReady: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := New(tt.cfg).Ready(context.Background(), now)
+			if report.Ready != tt.wantReady {
+				t.Errorf("Ready = %v, want %v (%+v)", report.Ready, tt.wantReady, report.Checks)
+			}
+
+			var failed []string
+			for _, check := range report.Checks {
+				if check.Status == StatusFail {
+					failed = append(failed, check.Name)
+				}
+			}
+			if tt.wantFail == "" && len(failed) != 0 || tt.wantFail != "" && (len(failed) != 1 || failed[0] != tt.wantFail) {
+				t.Errorf("Failed checks = %v, want [%s]", failed, tt.wantFail)
+			}
+		})
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	c := New(Config{DB: fakeDB{}, MQTT: &mocks.MockMQTT{Connected: false}})
+
+	rec := httptest.NewRecorder()
+	c.LivenessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz = %d, want 200", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	c.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz = %d, want 503", rec.Code)
+	}
+
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if report.Ready || len(report.Checks) != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
@@ -2,33 +2,52 @@ package homeassistant
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+
+	"github.com/diogoaguiar/hvac-manager/internal/state"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
 )
 
 // ClimateDiscovery represents the MQTT Discovery payload for a Climate entity
 type ClimateDiscovery struct {
-	Name                     string   `json:"name"`
-	UniqueID                 string   `json:"unique_id"`
-	DeviceClass              string   `json:"device_class,omitempty"`
-	StateTopic               string   `json:"state_topic"`
-	TemperatureCommandTopic  string   `json:"temperature_command_topic"`
-	ModeCommandTopic         string   `json:"mode_command_topic"`
-	FanModeCommandTopic      string   `json:"fan_mode_command_topic"`
-	TemperatureStateTopic    string   `json:"temperature_state_topic"`
-	ModeStateTopic           string   `json:"mode_state_topic"`
-	FanModeStateTopic        string   `json:"fan_mode_state_topic"`
-	TemperatureStateTemplate string   `json:"temperature_state_template"`
-	ModeStateTemplate        string   `json:"mode_state_template"`
-	FanModeStateTemplate     string   `json:"fan_mode_state_template"`
-	AvailabilityTopic        string   `json:"availability_topic"`
-	Modes                    []string `json:"modes"`
-	FanModes                 []string `json:"fan_modes"`
-	MinTemp                  float64  `json:"min_temp"`
-	MaxTemp                  float64  `json:"max_temp"`
-	TempStep                 float64  `json:"temp_step"`
-	TemperatureUnit          string   `json:"temperature_unit"`
-	Precision                float64  `json:"precision"`
-	Device                   Device   `json:"device"`
+	Name                      string   `json:"name"`
+	UniqueID                  string   `json:"unique_id"`
+	DeviceClass               string   `json:"device_class,omitempty"`
+	StateTopic                string   `json:"state_topic"`
+	TemperatureCommandTopic   string   `json:"temperature_command_topic"`
+	ModeCommandTopic          string   `json:"mode_command_topic"`
+	FanModeCommandTopic       string   `json:"fan_mode_command_topic"`
+	TemperatureStateTopic     string   `json:"temperature_state_topic"`
+	ModeStateTopic            string   `json:"mode_state_topic"`
+	FanModeStateTopic         string   `json:"fan_mode_state_topic"`
+	TemperatureStateTemplate  string   `json:"temperature_state_template"`
+	ModeStateTemplate         string   `json:"mode_state_template"`
+	FanModeStateTemplate      string   `json:"fan_mode_state_template"`
+	ActionTopic               string   `json:"action_topic"`
+	ActionTemplate            string   `json:"action_template"`
+	JSONAttributesTopic       string   `json:"json_attributes_topic"`
+	JSONAttributesTemplate    string   `json:"json_attributes_template"`
+	CurrentTempTopic          string   `json:"current_temperature_topic,omitempty"`
+	CurrentTempTemplate       string   `json:"current_temperature_template,omitempty"`
+	CurrentHumidityTopic      string   `json:"current_humidity_topic,omitempty"`
+	CurrentHumidityTemplate   string   `json:"current_humidity_template,omitempty"`
+	PresetModes               []string `json:"preset_modes,omitempty"`
+	PresetModeCommandTopic    string   `json:"preset_mode_command_topic,omitempty"`
+	PresetModeCommandTemplate string   `json:"preset_mode_command_template,omitempty"`
+	PresetModeStateTopic      string   `json:"preset_mode_state_topic,omitempty"`
+	PresetModeValueTemplate   string   `json:"preset_mode_value_template,omitempty"`
+	AvailabilityTopic         string   `json:"availability_topic"`
+	Modes                     []string `json:"modes"`
+	FanModes                  []string `json:"fan_modes"`
+	MinTemp                   float64  `json:"min_temp"`
+	MaxTemp                   float64  `json:"max_temp"`
+	TempStep                  float64  `json:"temp_step"`
+	TemperatureUnit           string   `json:"temperature_unit"`
+	Precision                 float64  `json:"precision"`
+	Device                    Device   `json:"device"`
+
+	topics topics.Builder // Used to build the config topic
 }
 
 // Device represents the device information in the discovery payload
@@ -41,9 +60,15 @@ type Device struct {
 }
 
 // NewClimateDiscovery creates a new MQTT Discovery payload for a climate entity
+// using the default topic prefixes
 func NewClimateDiscovery(deviceID string, deviceName string) *ClimateDiscovery {
-	cmdTopic := fmt.Sprintf("homeassistant/climate/%s/set", deviceID)
-	stateTopic := fmt.Sprintf("homeassistant/climate/%s/state", deviceID)
+	return NewClimateDiscoveryWithTopics(topics.Default(), deviceID, deviceName)
+}
+
+// NewClimateDiscoveryWithTopics creates a discovery payload using custom topic prefixes
+func NewClimateDiscoveryWithTopics(t topics.Builder, deviceID string, deviceName string) *ClimateDiscovery {
+	cmdTopic := t.Command(deviceID)
+	stateTopic := t.State(deviceID)
 	return &ClimateDiscovery{
 		Name:                    deviceName,
 		UniqueID:                fmt.Sprintf("hvac_manager_%s", deviceID),
@@ -59,7 +84,11 @@ func NewClimateDiscovery(deviceID string, deviceName string) *ClimateDiscovery {
 		TemperatureStateTemplate: "{{ value_json.temperature }}",
 		ModeStateTemplate:        "{{ value_json.mode }}",
 		FanModeStateTemplate:     "{{ value_json.fan_mode }}",
-		AvailabilityTopic:        fmt.Sprintf("homeassistant/climate/%s/availability", deviceID),
+		ActionTopic:              stateTopic,
+		ActionTemplate:           "{{ value_json.action }}",
+		JSONAttributesTopic:      stateTopic,
+		JSONAttributesTemplate:   attributesTemplate,
+		AvailabilityTopic:        t.Availability(deviceID),
 		Modes:                    []string{"off", "cool", "heat", "dry", "fan_only"},
 		FanModes:                 []string{"low", "medium", "high"},
 		MinTemp:                  16.0,
@@ -67,16 +96,46 @@ func NewClimateDiscovery(deviceID string, deviceName string) *ClimateDiscovery {
 		TempStep:                 1.0,
 		TemperatureUnit:          "C",
 		Precision:                0.1,
-		Device: Device{
-			Identifiers:  []string{fmt.Sprintf("hvac_manager_%s", deviceID)},
-			Name:         deviceName,
-			Model:        "HVAC Manager POC",
-			Manufacturer: "HVAC Manager",
-			SWVersion:    "0.1.0-poc",
-		},
+		Device:                   newDevice(deviceID, deviceName),
+		topics:                   t,
+	}
+}
+
+// newDevice returns the HA device block shared by all entities of a device
+func newDevice(deviceID string, deviceName string) Device {
+	return Device{
+		Identifiers:  []string{fmt.Sprintf("hvac_manager_%s", deviceID)},
+		Name:         deviceName,
+		Model:        "HVAC Manager POC",
+		Manufacturer: "HVAC Manager",
+		SWVersion:    "0.1.0-poc",
+	}
+}
+
+// EnableRoomSensor advertises current_temperature (and optionally current_humidity)
+// from the state topic so the HA thermostat card shows the real room readings
+func (d *ClimateDiscovery) EnableRoomSensor(withHumidity bool) {
+	d.CurrentTempTopic = d.StateTopic
+	d.CurrentTempTemplate = "{{ value_json.current_temperature }}"
+	if withHumidity {
+		d.CurrentHumidityTopic = d.StateTopic
+		d.CurrentHumidityTemplate = "{{ value_json.current_humidity }}"
 	}
 }
 
+// EnablePresets advertises HA preset modes, sent as {"preset_mode": ...} on the command topic
+// names must not include "none", which HA adds by itself
+func (d *ClimateDiscovery) EnablePresets(names []string) {
+	if len(names) == 0 {
+		return
+	}
+	d.PresetModes = names
+	d.PresetModeCommandTopic = d.ModeCommandTopic
+	d.PresetModeCommandTemplate = `{"preset_mode": "{{ value }}"}`
+	d.PresetModeStateTopic = d.StateTopic
+	d.PresetModeValueTemplate = "{{ value_json.preset_mode }}"
+}
+
 // ToJSON converts the discovery payload to JSON
 func (d *ClimateDiscovery) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(d, "", "  ")
@@ -84,14 +143,86 @@ func (d *ClimateDiscovery) ToJSON() ([]byte, error) {
 
 // ConfigTopic returns the MQTT topic for publishing this discovery payload
 func (d *ClimateDiscovery) ConfigTopic(deviceID string) string {
-	return fmt.Sprintf("homeassistant/climate/%s/config", deviceID)
+	return d.topics.DiscoveryConfig("climate", deviceID)
+}
+
+// NumberDiscovery represents the MQTT Discovery payload for a Number entity
+type NumberDiscovery struct {
+	Name              string  `json:"name"`
+	UniqueID          string  `json:"unique_id"`
+	CommandTopic      string  `json:"command_topic"`
+	StateTopic        string  `json:"state_topic"`
+	ValueTemplate     string  `json:"value_template"`
+	AvailabilityTopic string  `json:"availability_topic"`
+	Min               float64 `json:"min"`
+	Max               float64 `json:"max"`
+	Step              float64 `json:"step"`
+	Mode              string  `json:"mode"` // "box" or "slider"
+	UnitOfMeasurement string  `json:"unit_of_measurement,omitempty"`
+	Icon              string  `json:"icon,omitempty"`
+	Device            Device  `json:"device"`
+
+	objectID string         // Used to build the config topic
+	topics   topics.Builder // Used to build the config topic
+}
+
+// NewSleepTimerDiscovery creates the Number entity for a device's sleep timer
+// The value is the remaining time in minutes; setting it starts the timer, 0 cancels it
+func NewSleepTimerDiscovery(t topics.Builder, deviceID string, deviceName string) *NumberDiscovery {
+	return &NumberDiscovery{
+		Name:              fmt.Sprintf("%s Sleep Timer", deviceName),
+		UniqueID:          fmt.Sprintf("hvac_manager_%s_sleep_timer", deviceID),
+		CommandTopic:      t.TimerSet(deviceID),
+		StateTopic:        t.Timer(deviceID),
+		ValueTemplate:     "{{ value_json.remaining }}",
+		AvailabilityTopic: t.Availability(deviceID),
+		Min:               0,
+		Max:               720,
+		Step:              15,
+		Mode:              "box",
+		UnitOfMeasurement: "min",
+		Icon:              "mdi:timer-outline",
+		Device:            newDevice(deviceID, deviceName),
+		objectID:          fmt.Sprintf("%s_sleep_timer", deviceID),
+		topics:            t,
+	}
+}
+
+// ToJSON converts the discovery payload to JSON
+func (d *NumberDiscovery) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
 }
 
+// ConfigTopic returns the MQTT topic for publishing this discovery payload
+func (d *NumberDiscovery) ConfigTopic() string {
+	return d.topics.DiscoveryConfig("number", d.objectID)
+}
+
+// attributesTemplate exposes the non-climate state fields as entity attributes
+// The effective_* fields are omitted until a lookup code is sent, hence .get()
+const attributesTemplate = `{{ {"power": value_json.power, "error": value_json.error, "last_updated": value_json.last_updated, ` +
+	`"effective_mode": value_json.get('effective_mode'), "effective_temperature": value_json.get('effective_temperature'), ` +
+	`"effective_fan_mode": value_json.get('effective_fan_mode'), "lookup_strategy": value_json.get('lookup_strategy')} | tojson }}`
+
 // ClimateState represents the current state published to Home Assistant
 type ClimateState struct {
-	Temperature float64 `json:"temperature"`
-	Mode        string  `json:"mode"`
-	FanMode     string  `json:"fan_mode"`
+	Temperature        float64  `json:"temperature"`
+	CurrentTemperature *float64 `json:"current_temperature,omitempty"` // Measured room temperature (if known)
+	CurrentHumidity    *float64 `json:"current_humidity,omitempty"`    // Measured room humidity (if known)
+	Mode               string   `json:"mode"`
+	FanMode            string   `json:"fan_mode"`
+	PresetMode         string   `json:"preset_mode"`  // Active preset, "none" when none
+	Action             string   `json:"action"`       // idle, cooling, heating, drying, fan, off
+	Power              bool     `json:"power"`        // false when mode is off
+	Error              string   `json:"error"`        // Last failure, empty after next success
+	LastUpdated        string   `json:"last_updated"` // RFC 3339 timestamp of last state change
+
+	// Stored IR code the AC last received (omitted until a lookup code is sent)
+	// Differs from the fields above when the lookup fell back, e.g., to another fan speed
+	EffectiveMode        string   `json:"effective_mode,omitempty"`
+	EffectiveTemperature *float64 `json:"effective_temperature,omitempty"`
+	EffectiveFanMode     string   `json:"effective_fan_mode,omitempty"`
+	LookupStrategy       string   `json:"lookup_strategy,omitempty"` // See lookup.Strategy*
 }
 
 // ClimateCommand represents a command received from Home Assistant
@@ -99,6 +230,15 @@ type ClimateCommand struct {
 	Temperature *float64 `json:"temperature,omitempty"`
 	Mode        *string  `json:"mode,omitempty"`
 	FanMode     *string  `json:"fan_mode,omitempty"`
+	PresetMode  *string  `json:"preset_mode,omitempty"`
+}
+
+// ErrEmptyCommand is returned for commands that set no field, e.g., "{}"
+var ErrEmptyCommand = errors.New("command must set temperature, mode, fan_mode or preset_mode")
+
+// Empty reports whether the command sets no field
+func (c *ClimateCommand) Empty() bool {
+	return c.Temperature == nil && c.Mode == nil && c.FanMode == nil && c.PresetMode == nil
 }
 
 // ParseCommand parses a JSON command from Home Assistant
@@ -110,6 +250,28 @@ func ParseCommand(payload []byte) (*ClimateCommand, error) {
 	return &cmd, nil
 }
 
+// Validate checks the fields present in the command against the AC's limits
+// Used for commands that are stored and applied later (schedules, timers)
+func (c *ClimateCommand) Validate() error {
+	scratch := state.NewACState()
+	if c.Temperature != nil {
+		if err := scratch.SetTemperature(*c.Temperature); err != nil {
+			return fmt.Errorf("invalid temperature: %w", err)
+		}
+	}
+	if c.Mode != nil {
+		if err := scratch.SetMode(*c.Mode); err != nil {
+			return fmt.Errorf("invalid mode: %w", err)
+		}
+	}
+	if c.FanMode != nil {
+		if err := scratch.SetFanMode(*c.FanMode); err != nil {
+			return fmt.Errorf("invalid fan mode: %w", err)
+		}
+	}
+	return nil
+}
+
 // StateToJSON converts a state struct to JSON
 func StateToJSON(state *ClimateState) ([]byte, error) {
 	return json.Marshal(state)
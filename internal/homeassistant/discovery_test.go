@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
 )
 
 func TestNewClimateDiscovery(t *testing.T) {
@@ -226,6 +228,28 @@ func TestParseCommand(t *testing.T) {
 	}
 }
 
+func TestClimateCommand_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     ClimateCommand
+		wantErr bool
+	}{
+		{"Empty", ClimateCommand{}, false},
+		{"Valid", ClimateCommand{Temperature: floatPtr(21), Mode: strPtr("heat"), FanMode: strPtr("low")}, false},
+		{"Temperature too high", ClimateCommand{Temperature: floatPtr(35)}, true},
+		{"Unknown mode", ClimateCommand{Mode: strPtr("turbo")}, true},
+		{"Unknown fan mode", ClimateCommand{FanMode: strPtr("max")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cmd.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestClimateState_JSON(t *testing.T) {
 	state := ClimateState{
 		Temperature: 22.5,
@@ -255,6 +279,152 @@ func TestClimateState_JSON(t *testing.T) {
 	}
 }
 
+func TestClimateDiscovery_ActionAndAttributes(t *testing.T) {
+	discovery := NewClimateDiscovery("test_room", "Test AC")
+
+	if discovery.ActionTopic != discovery.StateTopic {
+		t.Errorf("ActionTopic = %q, want state topic %q", discovery.ActionTopic, discovery.StateTopic)
+	}
+	if discovery.ActionTemplate != "{{ value_json.action }}" {
+		t.Errorf("Unexpected action template: %q", discovery.ActionTemplate)
+	}
+	if discovery.JSONAttributesTopic != discovery.StateTopic {
+		t.Errorf("JSONAttributesTopic = %q, want state topic %q", discovery.JSONAttributesTopic, discovery.StateTopic)
+	}
+	for _, field := range []string{"error", "last_updated", "power"} {
+		if !strings.Contains(discovery.JSONAttributesTemplate, "value_json."+field) {
+			t.Errorf("Attributes template should expose %q: %s", field, discovery.JSONAttributesTemplate)
+		}
+	}
+}
+
+func TestClimateState_RichFields(t *testing.T) {
+	state := ClimateState{
+		Temperature: 21.0,
+		Mode:        "cool",
+		FanMode:     "auto",
+		Action:      "cooling",
+		Power:       true,
+		Error:       "",
+		LastUpdated: "2026-01-24T15:30:00Z",
+	}
+
+	jsonData, err := StateToJSON(&state)
+	if err != nil {
+		t.Fatalf("StateToJSON failed: %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(jsonData, &result); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+
+	if result["action"] != "cooling" {
+		t.Errorf("action = %v, want cooling", result["action"])
+	}
+	if result["power"] != true {
+		t.Errorf("power = %v, want true", result["power"])
+	}
+	// error is always present so HA clears the attribute after a success
+	if v, ok := result["error"]; !ok || v != "" {
+		t.Errorf("error = %v (present: %v), want empty string", v, ok)
+	}
+	if result["last_updated"] != "2026-01-24T15:30:00Z" {
+		t.Errorf("last_updated = %v", result["last_updated"])
+	}
+	// current_temperature is omitted when no sensor reading is available
+	if _, ok := result["current_temperature"]; ok {
+		t.Error("current_temperature should be omitted when unknown")
+	}
+}
+
+func TestClimateDiscovery_EnableRoomSensor(t *testing.T) {
+	discovery := NewClimateDiscovery("test_room", "Test AC")
+
+	// Not advertised until a sensor is bound
+	jsonData, _ := discovery.ToJSON()
+	if strings.Contains(string(jsonData), "current_temperature_topic") {
+		t.Error("current_temperature_topic should be omitted without a sensor")
+	}
+
+	discovery.EnableRoomSensor(false)
+	if discovery.CurrentTempTopic != discovery.StateTopic {
+		t.Errorf("CurrentTempTopic = %q, want %q", discovery.CurrentTempTopic, discovery.StateTopic)
+	}
+	if discovery.CurrentTempTemplate != "{{ value_json.current_temperature }}" {
+		t.Errorf("Unexpected template: %q", discovery.CurrentTempTemplate)
+	}
+	if discovery.CurrentHumidityTopic != "" {
+		t.Error("Humidity should not be advertised without a humidity source")
+	}
+
+	discovery.EnableRoomSensor(true)
+	if discovery.CurrentHumidityTopic != discovery.StateTopic {
+		t.Errorf("CurrentHumidityTopic = %q, want %q", discovery.CurrentHumidityTopic, discovery.StateTopic)
+	}
+}
+
+func TestClimateDiscovery_EnablePresets(t *testing.T) {
+	discovery := NewClimateDiscovery("test_room", "Test AC")
+
+	// No presets configured: nothing advertised
+	discovery.EnablePresets(nil)
+	jsonData, _ := discovery.ToJSON()
+	if strings.Contains(string(jsonData), "preset_modes") {
+		t.Error("preset_modes should be omitted without presets")
+	}
+
+	discovery.EnablePresets([]string{"eco", "boost"})
+	if len(discovery.PresetModes) != 2 {
+		t.Errorf("PresetModes = %v", discovery.PresetModes)
+	}
+	if discovery.PresetModeCommandTopic != discovery.ModeCommandTopic {
+		t.Errorf("PresetModeCommandTopic = %q, want command topic", discovery.PresetModeCommandTopic)
+	}
+
+	// The command template must produce a command ParseCommand understands
+	payload := strings.ReplaceAll(discovery.PresetModeCommandTemplate, "{{ value }}", "eco")
+	cmd, err := ParseCommand([]byte(payload))
+	if err != nil {
+		t.Fatalf("ParseCommand(%s) failed: %v", payload, err)
+	}
+	if cmd.PresetMode == nil || *cmd.PresetMode != "eco" {
+		t.Errorf("PresetMode = %v, want eco", cmd.PresetMode)
+	}
+}
+
+func TestNewSleepTimerDiscovery(t *testing.T) {
+	discovery := NewSleepTimerDiscovery(topics.Default(), "living_room", "Living Room AC")
+
+	if got := discovery.ConfigTopic(); got != "homeassistant/number/living_room_sleep_timer/config" {
+		t.Errorf("ConfigTopic() = %q", got)
+	}
+	if discovery.CommandTopic != "homeassistant/climate/living_room/timer/set" {
+		t.Errorf("CommandTopic = %q", discovery.CommandTopic)
+	}
+	if discovery.StateTopic != "homeassistant/climate/living_room/timer" {
+		t.Errorf("StateTopic = %q", discovery.StateTopic)
+	}
+
+	// Grouped with the climate entity under the same HA device
+	climate := NewClimateDiscovery("living_room", "Living Room AC")
+	if discovery.Device.Identifiers[0] != climate.Device.Identifiers[0] {
+		t.Errorf("Device identifiers differ: %v vs %v", discovery.Device.Identifiers, climate.Device.Identifiers)
+	}
+
+	jsonData, err := discovery.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(jsonData, &parsed); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if parsed["unit_of_measurement"] != "min" || parsed["max"] != 720.0 {
+		t.Errorf("Unexpected payload: %s", jsonData)
+	}
+}
+
 // Helper functions for creating pointers
 func floatPtr(f float64) *float64 {
 	return &f
@@ -5,51 +5,114 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/diogoaguiar/hvac-manager/internal/interfaces"
 	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
 	"github.com/diogoaguiar/hvac-manager/internal/state"
 )
 
 // SendIRCode looks up the IR code for the current AC state and publishes it to Zigbee2MQTT
-func SendIRCode(ctx context.Context, db interfaces.IRDatabase, mqtt interfaces.MQTTPublisher, modelID, irBlasterID string, acState *state.ACState) error {
-	logger.Debug("SendIRCode called for state: %s", acState.String())
+// irTopic is the blaster's Zigbee2MQTT set topic (see topics.Builder.Z2MSet)
+// The code is sent as the model's sequence gives it (repeats, then any After codes); a
+// cancelled ctx stops the sequence. The returned lookup.Result tells which stored code
+// was sent (or why none was found)
+func SendIRCode(ctx context.Context, db interfaces.IRDatabase, mqtt interfaces.MQTTPublisher, modelID, irTopic string, acState *state.ACState, seq Sequence) (lookup.Result, error) {
+	log := logger.FromContext(ctx).With(logger.FieldTopic, irTopic)
+	log.Debug("SendIRCode called for state: %s", acState.String())
 
 	// Check MQTT connection
 	if !mqtt.IsConnected() {
-		logger.Error("MQTT client not connected")
-		return fmt.Errorf("MQTT client not connected")
+		log.Error("MQTT client not connected")
+		return lookup.Result{}, fmt.Errorf("MQTT client not connected")
 	}
 	logger.Debug("MQTT client connected")
 
 	var code string
+	var result lookup.Result
 	var err error
 
 	// Special case for "off" mode - use dedicated off code lookup
 	if acState.Mode == "off" {
 		logger.Debug("Looking up OFF code for model: %s", modelID)
-		code, err = db.LookupOffCode(ctx, modelID)
+		code, result, err = db.LookupOffCode(ctx, modelID)
 		if err != nil {
-			logger.Error("Failed to lookup off code for model %s: %v", modelID, err)
-			return fmt.Errorf("failed to lookup off code for model %s: %w", modelID, err)
+			log.Error("Failed to lookup off code for model %s: %v", modelID, err)
+			return result, fmt.Errorf("failed to lookup off code for model %s: %w", modelID, err)
 		}
 		logger.Debug("Found OFF code (length: %d bytes)", len(code))
 	} else {
-		// Convert float temperature to int (round to nearest)
-		temp := int(math.Round(acState.Temperature))
+		// Round to the model's precision (whole degrees if unknown)
+		step, err := db.LookupPrecision(ctx, modelID)
+		if err != nil || step <= 0 {
+			step = 1
+		}
+		temp := math.Round(acState.Temperature/step) * step
 
-		logger.Debug("Looking up IR code: model=%s mode=%s temp=%d fan=%s",
+		logger.Debug("Looking up IR code: model=%s mode=%s temp=%g fan=%s",
 			modelID, acState.Mode, temp, acState.FanMode)
 
-		code, err = db.LookupCode(ctx, modelID, acState.Mode, temp, acState.FanMode)
+		code, result, err = db.LookupCode(ctx, modelID, acState.Mode, temp, acState.FanMode)
 		if err != nil {
-			logger.Error("Failed to lookup IR code for %s: %v", acState.String(), err)
-			return fmt.Errorf("failed to lookup IR code for %s: %w", acState.String(), err)
+			log.Error("Failed to lookup IR code for %s: %v", acState.String(), err)
+			return result, fmt.Errorf("failed to lookup IR code for %s: %w", acState.String(), err)
 		}
 		logger.Debug("Found IR code (length: %d bytes)", len(code))
 		logger.Debug("IR code: %s", code)
 	}
 
+	if err := SendFrames(ctx, mqtt, irTopic, seq.Frames(code, acState.Power)); err != nil {
+		log.Error("Failed to send IR sequence for %s: %v", acState.String(), err)
+		return result, err
+	}
+
+	log.Info("📡 IR code sent to %s for state: %s (matched %s)", irTopic, acState.String(), result)
+	return result, nil
+}
+
+// DefaultPowerOnDelay is the wait between the on code and the state code
+const DefaultPowerOnDelay = time.Second
+
+// PowerOn configures the sequence sent when the AC goes from off to an active mode
+// Some units ignore state codes while off, or need their dedicated on code first.
+type PowerOn struct {
+	Enabled bool          // Send the model's on code before the state code
+	Delay   time.Duration // Wait after the on code, while the unit starts up
+}
+
+// SendPowerOn publishes the model's on code and waits for the unit to start
+// Called before SendIRCode on an off → active transition. Returns early with the
+// context's error if ctx is cancelled during the delay.
+func SendPowerOn(ctx context.Context, db interfaces.IRDatabase, mqtt interfaces.MQTTPublisher, modelID, irTopic string, delay time.Duration) (lookup.Result, error) {
+	log := logger.FromContext(ctx).With(logger.FieldTopic, irTopic)
+
+	if !mqtt.IsConnected() {
+		log.Error("MQTT client not connected")
+		return lookup.Result{}, fmt.Errorf("MQTT client not connected")
+	}
+
+	code, result, err := db.LookupOnCode(ctx, modelID)
+	if err != nil {
+		log.Error("Failed to lookup on code for model %s: %v", modelID, err)
+		return result, fmt.Errorf("failed to lookup on code for model %s: %w", modelID, err)
+	}
+	if err := PublishIRCode(mqtt, irTopic, code); err != nil {
+		return result, err
+	}
+	log.Info("🔌 On code sent to %s, waiting %s before the state code", irTopic, delay)
+
+	select {
+	case <-ctx.Done():
+		return result, fmt.Errorf("%w (on code only): %w", ErrPartiallySent, ctx.Err())
+	case <-time.After(delay):
+		return result, nil
+	}
+}
+
+// PublishIRCode publishes a raw Tuya IR code to the blaster's Zigbee2MQTT set topic
+// Used directly for codes that do not come from a state lookup (e.g., preset codes)
+func PublishIRCode(mqtt interfaces.MQTTPublisher, irTopic, code string) error {
 	// Build Zigbee2MQTT payload
 	payload := map[string]string{
 		"ir_code_to_send": code,
@@ -61,15 +124,13 @@ func SendIRCode(ctx context.Context, db interfaces.IRDatabase, mqtt interfaces.M
 	}
 
 	// Publish to Zigbee2MQTT IR blaster
-	topic := fmt.Sprintf("zigbee2mqtt/%s/set", irBlasterID)
-	logger.Debug("Publishing to topic: %s", topic)
+	logger.Debug("Publishing to topic: %s", irTopic)
 	logger.Debug("Payload: %s", string(payloadJSON))
 
-	if err := mqtt.Publish(topic, 1, false, payloadJSON); err != nil {
-		logger.Error("Failed to publish IR code to %s: %v", topic, err)
-		return fmt.Errorf("failed to publish IR code to %s: %w", topic, err)
+	if err := mqtt.Publish(irTopic, 1, false, payloadJSON); err != nil {
+		logger.Error("Failed to publish IR code to %s: %v", irTopic, err)
+		return fmt.Errorf("failed to publish IR code to %s: %w", irTopic, err)
 	}
 
-	logger.Info("📡 IR code sent to %s for state: %s", irBlasterID, acState.String())
 	return nil
 }
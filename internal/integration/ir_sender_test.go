@@ -5,11 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
 	"github.com/diogoaguiar/hvac-manager/internal/mocks"
 	"github.com/diogoaguiar/hvac-manager/internal/state"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
 )
 
+var testIRTopic = topics.Default().Z2MSet("ir-blaster")
+
 func TestSendIRCode_Success(t *testing.T) {
 	// Setup
 	mockDB := &mocks.MockDatabase{
@@ -25,7 +30,7 @@ func TestSendIRCode_Success(t *testing.T) {
 	acState.SetFanMode("low")
 
 	// Execute
-	err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", "ir-blaster", acState)
+	_, err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, acState, Sequence{})
 
 	// Assert
 	if err != nil {
@@ -89,7 +94,7 @@ func TestSendIRCode_OffMode(t *testing.T) {
 	acState.SetMode("off")
 
 	// Execute
-	err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", "ir-blaster", acState)
+	_, err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, acState, Sequence{})
 
 	// Assert
 	if err != nil {
@@ -146,7 +151,7 @@ func TestSendIRCode_TemperatureRounding(t *testing.T) {
 			acState.SetMode("cool")
 			acState.SetTemperature(tt.temperature)
 
-			err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", "ir-blaster", acState)
+			_, err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, acState, Sequence{})
 
 			if err != nil {
 				t.Fatalf("Unexpected error: %v", err)
@@ -164,6 +169,25 @@ func TestSendIRCode_TemperatureRounding(t *testing.T) {
 	}
 }
 
+func TestSendIRCode_HalfDegreePrecision(t *testing.T) {
+	mockDB := &mocks.MockDatabase{
+		Codes:     map[string]string{"9002:cool:21.5:auto": "CODE_21.5"},
+		Precision: 0.5,
+	}
+	mockMQTT := &mocks.MockMQTT{Connected: true}
+
+	acState := state.NewACState()
+	acState.SetMode("cool")
+	acState.SetTemperature(21.6)
+
+	if _, err := SendIRCode(context.Background(), mockDB, mockMQTT, "9002", testIRTopic, acState, Sequence{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(mockDB.Calls) != 1 || mockDB.Calls[0] != "9002:cool:21.5:auto" {
+		t.Errorf("DB calls = %v, want the nearest half degree", mockDB.Calls)
+	}
+}
+
 func TestSendIRCode_DatabaseError(t *testing.T) {
 	mockDB := &mocks.MockDatabase{
 		Err: errors.New("database connection lost"),
@@ -173,7 +197,7 @@ func TestSendIRCode_DatabaseError(t *testing.T) {
 	acState := state.NewACState()
 	acState.SetMode("cool")
 
-	err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", "ir-blaster", acState)
+	_, err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, acState, Sequence{})
 
 	// Should return error
 	if err == nil {
@@ -199,7 +223,7 @@ func TestSendIRCode_CodeNotFound(t *testing.T) {
 	acState.SetTemperature(21.0)
 	acState.SetFanMode("low")
 
-	err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", "ir-blaster", acState)
+	_, err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, acState, Sequence{})
 
 	// Should return error when code not found
 	if err == nil {
@@ -225,7 +249,7 @@ func TestSendIRCode_MQTTDisconnected(t *testing.T) {
 	acState.SetTemperature(21.0)
 	acState.SetFanMode("low")
 
-	err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", "ir-blaster", acState)
+	_, err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, acState, Sequence{})
 
 	// Should return error when MQTT disconnected
 	if err == nil {
@@ -254,7 +278,7 @@ func TestSendIRCode_MQTTPublishError(t *testing.T) {
 	acState.SetTemperature(21.0)
 	acState.SetFanMode("low")
 
-	err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", "ir-blaster", acState)
+	_, err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, acState, Sequence{})
 
 	// Should return error when publish fails
 	if err == nil {
@@ -283,7 +307,7 @@ func TestSendIRCode_AllModes(t *testing.T) {
 			acState := state.NewACState()
 			acState.SetMode(mode)
 
-			err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", "ir-blaster", acState)
+			_, err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, acState, Sequence{})
 
 			if err != nil {
 				t.Fatalf("Mode %s failed: %v", mode, err)
@@ -317,7 +341,7 @@ func TestSendIRCode_AllFanModes(t *testing.T) {
 			acState.SetMode("cool")
 			acState.SetFanMode(fan)
 
-			err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", "ir-blaster", acState)
+			_, err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, acState, Sequence{})
 
 			if err != nil {
 				t.Fatalf("Fan mode %s failed: %v", fan, err)
@@ -333,3 +357,43 @@ func TestSendIRCode_AllFanModes(t *testing.T) {
 		})
 	}
 }
+
+func TestSendPowerOn(t *testing.T) {
+	mockDB := &mocks.MockDatabase{OnCodes: map[string]string{"1109": "ON"}}
+	mockMQTT := &mocks.MockMQTT{Connected: true}
+
+	start := time.Now()
+	result, err := SendPowerOn(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Strategy != lookup.StrategyOn {
+		t.Errorf("Strategy = %q, want %q", result.Strategy, lookup.StrategyOn)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Returned after %s, expected to wait the delay", elapsed)
+	}
+	if len(mockMQTT.Published) != 1 {
+		t.Fatalf("Expected 1 MQTT publish, got %d", len(mockMQTT.Published))
+	}
+
+	// A cancelled context stops the wait
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	if _, err := SendPowerOn(ctx, mockDB, mockMQTT, "1109", testIRTopic, time.Hour); !errors.Is(err, context.Canceled) || !errors.Is(err, ErrPartiallySent) {
+		t.Errorf("Expected a partial send with context.Canceled, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected the delay to be interrupted")
+	}
+
+	// A model without an on code fails before sending anything
+	mockMQTT = &mocks.MockMQTT{Connected: true}
+	if _, err := SendPowerOn(context.Background(), mockDB, mockMQTT, "1116", testIRTopic, 0); err == nil {
+		t.Error("Expected an error for a model without an on code")
+	}
+	if len(mockMQTT.Published) != 0 {
+		t.Errorf("Expected no publish, got %d", len(mockMQTT.Published))
+	}
+}
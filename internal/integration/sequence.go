@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/interfaces"
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+)
+
+// ErrPartiallySent reports a sequence interrupted after some codes were transmitted
+// The AC may already be in the new state, so callers keep it instead of reverting.
+var ErrPartiallySent = errors.New("IR sequence partially sent")
+
+// DefaultSequenceModel is the sequences file key applying to models without their own sequence
+const DefaultSequenceModel = "default"
+
+// Frame is one IR code of a transmission sequence
+type Frame struct {
+	Code    string `json:"ir_code"`            // Base64-encoded Tuya code
+	Repeat  int    `json:"repeat,omitempty"`   // Transmissions of the code (default 1)
+	DelayMS int    `json:"delay_ms,omitempty"` // Wait after each transmission, before the next one
+}
+
+// Sequence configures how the codes of a model are transmitted
+// The zero value sends the state code once, as-is.
+type Sequence struct {
+	Repeat  int     `json:"repeat,omitempty"`   // Transmissions of the state code (default 1), e.g., 2 for unreliable receivers
+	DelayMS int     `json:"delay_ms,omitempty"` // Wait after each transmission of the state code
+	After   []Frame `json:"after,omitempty"`    // Codes sent after the state code while the AC is on, e.g., swing or light toggles
+}
+
+// Validate checks the sequence fields
+func (s Sequence) Validate() error {
+	if s.Repeat < 0 || s.DelayMS < 0 {
+		return fmt.Errorf("invalid repeat %d or delay_ms %d (must be >= 0)", s.Repeat, s.DelayMS)
+	}
+	for i, f := range s.After {
+		if f.Repeat < 0 || f.DelayMS < 0 {
+			return fmt.Errorf("after[%d]: invalid repeat %d or delay_ms %d (must be >= 0)", i, f.Repeat, f.DelayMS)
+		}
+		if _, err := base64.StdEncoding.DecodeString(f.Code); err != nil || f.Code == "" {
+			return fmt.Errorf("after[%d]: ir_code must be a Base64-encoded Tuya code", i)
+		}
+	}
+	return nil
+}
+
+// Frames returns the frames sent for a state code
+// The After codes only follow states that leave the AC on.
+func (s Sequence) Frames(code string, power bool) []Frame {
+	frames := []Frame{{Code: code, Repeat: s.Repeat, DelayMS: s.DelayMS}}
+	if power {
+		frames = append(frames, s.After...)
+	}
+	return frames
+}
+
+// LoadSequences reads transmission sequences by model ID from a JSON file
+// The DefaultSequenceModel key applies to models without their own entry (see SequenceFor).
+func LoadSequences(path string) (map[string]Sequence, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sequences file: %w", err)
+	}
+
+	var sequences map[string]Sequence
+	if err := json.Unmarshal(data, &sequences); err != nil {
+		return nil, fmt.Errorf("failed to parse sequences file %s: %w", path, err)
+	}
+
+	for model, s := range sequences {
+		if err := s.Validate(); err != nil {
+			return nil, fmt.Errorf("sequence for %s: %w", model, err)
+		}
+	}
+	return sequences, nil
+}
+
+// SequenceFor returns the sequence of a model, or the default one
+func SequenceFor(sequences map[string]Sequence, modelID string) Sequence {
+	if s, ok := sequences[modelID]; ok {
+		return s
+	}
+	return sequences[DefaultSequenceModel]
+}
+
+// SendFrames publishes frames in order, each one Repeat times (at least once)
+// The delay of a frame follows each of its transmissions except the very last of the
+// sequence. If ctx is cancelled, the remaining transmissions are dropped and an error
+// wrapping the context's error is returned.
+func SendFrames(ctx context.Context, mqtt interfaces.MQTTPublisher, irTopic string, frames []Frame) error {
+	total := 0
+	for _, f := range frames {
+		total += max(f.Repeat, 1)
+	}
+
+	sent := 0
+	interrupted := func(err error) error {
+		if sent == 0 {
+			return fmt.Errorf("IR sequence interrupted before the first transmission: %w", err)
+		}
+		return fmt.Errorf("%w (%d of %d transmissions): %w", ErrPartiallySent, sent, total, err)
+	}
+	for _, f := range frames {
+		for range max(f.Repeat, 1) {
+			if err := ctx.Err(); err != nil {
+				return interrupted(err)
+			}
+			if err := PublishIRCode(mqtt, irTopic, f.Code); err != nil {
+				return err
+			}
+			sent++
+
+			if sent == total || f.DelayMS <= 0 {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return interrupted(ctx.Err())
+			case <-time.After(time.Duration(f.DelayMS) * time.Millisecond):
+			}
+		}
+	}
+
+	if total > 1 {
+		logger.Debug("IR sequence of %d transmissions sent to %s", total, irTopic)
+	}
+	return nil
+}
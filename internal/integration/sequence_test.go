@@ -0,0 +1,149 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/mocks"
+	"github.com/diogoaguiar/hvac-manager/internal/state"
+)
+
+// sentCodes returns the IR codes published to the mock, in order
+func sentCodes(t *testing.T, mqtt *mocks.MockMQTT) []string {
+	t.Helper()
+	var codes []string
+	for _, pub := range mqtt.Published {
+		var payload map[string]string
+		if err := json.Unmarshal(pub.Payload.([]byte), &payload); err != nil {
+			t.Fatalf("Failed to unmarshal payload: %v", err)
+		}
+		codes = append(codes, payload["ir_code_to_send"])
+	}
+	return codes
+}
+
+func TestSendFrames(t *testing.T) {
+	mockMQTT := &mocks.MockMQTT{Connected: true}
+	frames := []Frame{
+		{Code: "STATE", Repeat: 2, DelayMS: 10},
+		{Code: "SWING", DelayMS: 10},
+		{Code: "LIGHT", Repeat: 2, DelayMS: 500}, // Not applied after the last transmission
+	}
+
+	start := time.Now()
+	if err := SendFrames(context.Background(), mockMQTT, testIRTopic, frames); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	elapsed := time.Since(start)
+	if want := []string{"STATE", "STATE", "SWING", "LIGHT", "LIGHT"}; !slices.Equal(sentCodes(t, mockMQTT), want) {
+		t.Errorf("IR codes = %v, want %v", sentCodes(t, mockMQTT), want)
+	}
+	if elapsed < 530*time.Millisecond || elapsed > 1000*time.Millisecond {
+		t.Errorf("Sequence took %s, want about 530ms", elapsed)
+	}
+}
+
+func TestSendFrames_Cancelled(t *testing.T) {
+	mockMQTT := &mocks.MockMQTT{Connected: true}
+	frames := []Frame{{Code: "STATE", Repeat: 2, DelayMS: 3600_000}, {Code: "SWING"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := SendFrames(ctx, mockMQTT, testIRTopic, frames)
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrPartiallySent) {
+		t.Errorf("Expected a partial send with context.DeadlineExceeded, got %v", err)
+	}
+	if want := []string{"STATE"}; !slices.Equal(sentCodes(t, mockMQTT), want) {
+		t.Errorf("IR codes = %v, want %v", sentCodes(t, mockMQTT), want)
+	}
+
+	// Nothing is sent with an already cancelled context
+	mockMQTT = &mocks.MockMQTT{Connected: true}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := SendFrames(ctx, mockMQTT, testIRTopic, frames); !errors.Is(err, context.Canceled) || errors.Is(err, ErrPartiallySent) {
+		t.Errorf("Expected context.Canceled before any transmission, got %v", err)
+	}
+	if len(mockMQTT.Published) != 0 {
+		t.Errorf("Expected no publish, got %d", len(mockMQTT.Published))
+	}
+}
+
+func TestSendIRCode_Sequence(t *testing.T) {
+	mockDB := &mocks.MockDatabase{
+		Codes:    map[string]string{"1109:cool:21:low": "COOL21"},
+		OffCodes: map[string]string{"1109": "OFF"},
+	}
+	seq := Sequence{Repeat: 2, After: []Frame{{Code: "SWING"}}}
+
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{"cool", []string{"COOL21", "COOL21", "SWING"}},
+		{"off", []string{"OFF", "OFF"}}, // No toggles while off
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			mockMQTT := &mocks.MockMQTT{Connected: true}
+			acState := state.NewACState()
+			acState.SetMode(tt.mode)
+			acState.SetTemperature(21.0)
+			acState.SetFanMode("low")
+
+			if _, err := SendIRCode(context.Background(), mockDB, mockMQTT, "1109", testIRTopic, acState, seq); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if codes := sentCodes(t, mockMQTT); !slices.Equal(codes, tt.want) {
+				t.Errorf("IR codes = %v, want %v", codes, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSequences(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"default": {"repeat": 2, "delay_ms": 300}, "1109": {"after": [{"ir_code": "QQ==", "delay_ms": 500}]}}`, false},
+		{"negative repeat", `{"default": {"repeat": -1}}`, true},
+		{"negative frame delay", `{"1109": {"after": [{"ir_code": "QQ==", "delay_ms": -5}]}}`, true},
+		{"empty code", `{"1109": {"after": [{"delay_ms": 500}]}}`, true},
+		{"invalid code", `{"1109": {"after": [{"ir_code": "!!"}]}}`, true},
+		{"invalid JSON", `{"default": `, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sequence.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			sequences, err := LoadSequences(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadSequences() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if s := SequenceFor(sequences, "1109"); len(s.After) != 1 || s.Repeat != 0 {
+				t.Errorf("SequenceFor(1109) = %+v, want the model's own sequence", s)
+			}
+			if s := SequenceFor(sequences, "1116"); s.Repeat != 2 || s.DelayMS != 300 {
+				t.Errorf("SequenceFor(1116) = %+v, want the default sequence", s)
+			}
+		})
+	}
+
+	if _, err := LoadSequences(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -1,15 +1,25 @@
 package interfaces
 
-import "context"
+import (
+	"context"
+
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
+)
 
 // IRDatabase defines database operations for IR code lookup
 // This interface allows for testing without a real database connection
 type IRDatabase interface {
-	// LookupCode retrieves the IR code for a specific AC state
-	LookupCode(ctx context.Context, modelID, mode string, temperature int, fanSpeed string) (string, error)
+	// LookupCode retrieves the IR code for a specific AC state and explains which stored code matched
+	LookupCode(ctx context.Context, modelID, mode string, temperature float64, fanSpeed string) (string, lookup.Result, error)
 
 	// LookupOffCode retrieves the IR code to turn off the AC
-	LookupOffCode(ctx context.Context, modelID string) (string, error)
+	LookupOffCode(ctx context.Context, modelID string) (string, lookup.Result, error)
+
+	// LookupOnCode retrieves the dedicated IR code to turn on the AC (see integration.PowerOn)
+	LookupOnCode(ctx context.Context, modelID string) (string, lookup.Result, error)
+
+	// LookupPrecision returns the temperature step of a model's codes (e.g., 1 or 0.5)
+	LookupPrecision(ctx context.Context, modelID string) (float64, error)
 }
 
 // MQTTPublisher defines MQTT publishing operations
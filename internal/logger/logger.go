@@ -1,10 +1,16 @@
 package logger
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 // LogLevel represents the logging level
@@ -17,9 +23,28 @@ const (
 	ERROR
 )
 
+// Format selects how log lines are written
+type Format int
+
+const (
+	FormatText Format = iota // Human-readable with emoji (default)
+	FormatJSON               // One JSON object per line, for log shippers (Loki, etc.)
+)
+
+// Common structured field names
+const (
+	FieldDeviceID      = "device_id"
+	FieldModelID       = "model_id"
+	FieldTopic         = "topic"
+	FieldCorrelationID = "correlation_id"
+	FieldDuration      = "duration_ms" // time.Duration values are written as milliseconds in JSON
+)
+
 var (
-	currentLevel = INFO // Default to INFO
-	levelNames   = map[LogLevel]string{
+	currentLevel  = INFO       // Default to INFO
+	currentFormat = FormatText // Default to human-readable output
+	writeMu       sync.Mutex   // Serializes JSON lines (log.Printf already locks for text)
+	levelNames    = map[LogLevel]string{
 		DEBUG: "DEBUG",
 		INFO:  "INFO",
 		WARN:  "WARN",
@@ -55,41 +80,207 @@ func SetLevelFromString(levelStr string) {
 	}
 }
 
+// SetFormat sets the global output format
+func SetFormat(format Format) {
+	currentFormat = format
+}
+
+// SetFormatFromString sets the output format from a string (text, json)
+func SetFormatFromString(formatStr string) {
+	switch strings.ToLower(formatStr) {
+	case "text", "":
+		currentFormat = FormatText
+	case "json":
+		currentFormat = FormatJSON
+	default:
+		log.Printf("Unknown log format: %s, defaulting to text", formatStr)
+		currentFormat = FormatText
+	}
+}
+
+// IsText reports whether human-readable output is enabled
+// Decorative console output (separators, banners) should be skipped otherwise
+func IsText() bool {
+	return currentFormat == FormatText
+}
+
 // Debug logs a debug message
 func Debug(format string, v ...interface{}) {
-	logMessage(DEBUG, format, v...)
+	logMessage(DEBUG, nil, format, v...)
 }
 
 // Info logs an info message
 func Info(format string, v ...interface{}) {
-	logMessage(INFO, format, v...)
+	logMessage(INFO, nil, format, v...)
 }
 
 // Warn logs a warning message
 func Warn(format string, v ...interface{}) {
-	logMessage(WARN, format, v...)
+	logMessage(WARN, nil, format, v...)
 }
 
 // Error logs an error message
 func Error(format string, v ...interface{}) {
-	logMessage(ERROR, format, v...)
+	logMessage(ERROR, nil, format, v...)
+}
+
+// field is a structured key/value attached to a log line
+type field struct {
+	key   string
+	value interface{}
+}
+
+// Entry is a logger carrying structured fields
+// Fields are appended as key=value in text output and as keys in JSON output
+type Entry struct {
+	fields []field
+}
+
+// With returns an entry with the given key/value pairs, e.g., With("device_id", id)
+func With(keyValues ...interface{}) Entry {
+	return Entry{}.With(keyValues...)
+}
+
+// With returns a copy of the entry with additional key/value pairs
+// A key that is already set is overridden
+func (e Entry) With(keyValues ...interface{}) Entry {
+	fields := make([]field, 0, len(e.fields)+len(keyValues)/2)
+	fields = append(fields, e.fields...)
+
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		key := fmt.Sprint(keyValues[i])
+		replaced := false
+		for j := range fields {
+			if fields[j].key == key {
+				fields[j].value = keyValues[i+1]
+				replaced = true
+			}
+		}
+		if !replaced {
+			fields = append(fields, field{key: key, value: keyValues[i+1]})
+		}
+	}
+	return Entry{fields: fields}
+}
+
+// Debug logs a debug message with the entry's fields
+func (e Entry) Debug(format string, v ...interface{}) {
+	logMessage(DEBUG, e.fields, format, v...)
+}
+
+// Info logs an info message with the entry's fields
+func (e Entry) Info(format string, v ...interface{}) {
+	logMessage(INFO, e.fields, format, v...)
+}
+
+// Warn logs a warning message with the entry's fields
+func (e Entry) Warn(format string, v ...interface{}) {
+	logMessage(WARN, e.fields, format, v...)
+}
+
+// Error logs an error message with the entry's fields
+func (e Entry) Error(format string, v ...interface{}) {
+	logMessage(ERROR, e.fields, format, v...)
+}
+
+// contextKey is the context key carrying an Entry
+type contextKey struct{}
+
+// WithContext returns a context whose logger carries the given key/value pairs
+// in addition to those already in ctx (e.g., a correlation ID per command)
+func WithContext(ctx context.Context, keyValues ...interface{}) context.Context {
+	return context.WithValue(ctx, contextKey{}, FromContext(ctx).With(keyValues...))
+}
+
+// FromContext returns the entry stored by WithContext (an empty entry if none)
+func FromContext(ctx context.Context) Entry {
+	if e, ok := ctx.Value(contextKey{}).(Entry); ok {
+		return e
+	}
+	return Entry{}
+}
+
+// CorrelationIDFrom returns the correlation ID stored by WithContext ("" if none)
+func CorrelationIDFrom(ctx context.Context) string {
+	for _, f := range FromContext(ctx).fields {
+		if f.key == FieldCorrelationID {
+			return fmt.Sprint(f.value)
+		}
+	}
+	return ""
+}
+
+// NewCorrelationID returns a short random ID to tie together the log lines of one command
+func NewCorrelationID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
 }
 
 // logMessage logs a message if the level is enabled
-func logMessage(level LogLevel, format string, v ...interface{}) {
+func logMessage(level LogLevel, fields []field, format string, v ...interface{}) {
 	if level < currentLevel {
 		return
 	}
 
+	message := fmt.Sprintf(format, v...)
+	if currentFormat == FormatJSON {
+		writeJSON(level, fields, message)
+		return
+	}
+
 	emoji := levelEmojis[level]
 	levelName := levelNames[level]
-	message := fmt.Sprintf(format, v...)
 
-	log.Printf("%s [%s] %s", emoji, levelName, message)
+	var suffix strings.Builder
+	for _, f := range fields {
+		value := f.value
+		if d, ok := value.(time.Duration); ok {
+			value = d.Round(time.Microsecond)
+		}
+		fmt.Fprintf(&suffix, " %s=%v", f.key, value)
+	}
+
+	log.Printf("%s [%s] %s%s", emoji, levelName, message, suffix.String())
 }
 
-// InitFromEnv initializes the logger from environment variable
+// writeJSON writes one log line as a JSON object
+func writeJSON(level LogLevel, fields []field, message string) {
+	line := make(map[string]interface{}, len(fields)+3)
+	for _, f := range fields {
+		switch value := f.value.(type) {
+		case time.Duration:
+			line[f.key] = float64(value.Microseconds()) / 1000
+		case error:
+			line[f.key] = value.Error()
+		default:
+			line[f.key] = value
+		}
+	}
+	line["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["level"] = strings.ToLower(levelNames[level])
+	line["msg"] = strings.TrimSpace(message)
+
+	payload, err := json.Marshal(line)
+	if err != nil {
+		payload, _ = json.Marshal(map[string]string{
+			"time":  time.Now().UTC().Format(time.RFC3339Nano),
+			"level": "error",
+			"msg":   fmt.Sprintf("failed to encode log line %q: %v", message, err),
+		})
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	log.Writer().Write(append(payload, '\n'))
+}
+
+// InitFromEnv initializes the logger from environment variables (LOG_LEVEL, LOG_FORMAT)
 func InitFromEnv() {
+	SetFormatFromString(os.Getenv("LOG_FORMAT"))
+
 	logLevel := os.Getenv("LOG_LEVEL")
 	if logLevel != "" {
 		SetLevelFromString(logLevel)
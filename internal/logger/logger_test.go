@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// capture redirects log output for the duration of the test
+func capture(t *testing.T, format Format) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	out, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	SetFormat(format)
+	SetLevel(DEBUG)
+	t.Cleanup(func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+		SetFormat(FormatText)
+		SetLevel(INFO)
+	})
+	return &buf
+}
+
+func TestJSONFormat(t *testing.T) {
+	buf := capture(t, FormatJSON)
+
+	ctx := WithContext(context.Background(), FieldCorrelationID, "abc123", FieldDeviceID, "living_room")
+	FromContext(ctx).With(FieldTopic, "zigbee2mqtt/ir-blaster/set", FieldDuration, 1500*time.Microsecond).
+		Info("📡 IR code sent for state: %s", "cool")
+	Warn("plain %d", 42)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Invalid JSON line %q: %v", lines[0], err)
+	}
+	want := map[string]interface{}{
+		"level":          "info",
+		"msg":            "📡 IR code sent for state: cool",
+		"correlation_id": "abc123",
+		"device_id":      "living_room",
+		"topic":          "zigbee2mqtt/ir-blaster/set",
+		"duration_ms":    1.5,
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339Nano, entry["time"].(string)); err != nil {
+		t.Errorf("Invalid time field: %v", entry["time"])
+	}
+
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil || entry["level"] != "warn" || entry["msg"] != "plain 42" {
+		t.Errorf("Unexpected plain line: %s", lines[1])
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	buf := capture(t, FormatText)
+
+	With(FieldDeviceID, "living_room", FieldDuration, 2*time.Millisecond).Error("failed: %s", "timeout")
+
+	if got, want := buf.String(), "❌ [ERROR] failed: timeout device_id=living_room duration_ms=2ms\n"; got != want {
+		t.Errorf("Text output = %q, want %q", got, want)
+	}
+}
+
+func TestWithOverridesAndLevels(t *testing.T) {
+	buf := capture(t, FormatJSON)
+	SetLevel(WARN)
+
+	e := With(FieldDeviceID, "a").With(FieldDeviceID, "b")
+	e.Info("filtered")
+	e.Warn("kept")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a single JSON line, got %q", buf.String())
+	}
+	if entry["device_id"] != "b" || entry["msg"] != "kept" {
+		t.Errorf("Unexpected entry: %v", entry)
+	}
+}
+
+func TestSetFormatFromString(t *testing.T) {
+	capture(t, FormatText)
+
+	for input, want := range map[string]Format{"json": FormatJSON, "JSON": FormatJSON, "text": FormatText, "": FormatText, "xml": FormatText} {
+		SetFormatFromString(input)
+		if currentFormat != want {
+			t.Errorf("SetFormatFromString(%q) = %v, want %v", input, currentFormat, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package lookup
+
+import "fmt"
+
+// Lookup strategies: how a requested state was resolved to a stored IR code (see database.LookupCode)
+const (
+	StrategyExact       = "exact"        // mode + temperature + fan
+	StrategyFan         = "fan"          // Same mode/temperature, different fan speed
+	StrategyModeTemp    = "mode_temp"    // Mode + temperature, any fan speed
+	StrategyModeOnly    = "mode_only"    // Mode only (fan_only, dry)
+	StrategyNearestTemp = "nearest_temp" // Nearby temperature (see database.FallbackPolicy.TemperatureRange)
+	StrategyOff         = "off"          // Dedicated off code
+	StrategyOn          = "on"           // Dedicated on code (see integration.PowerOn)
+	StrategyMiss        = "miss"         // No code found
+)
+
+// Result explains which stored IR code a lookup resolved to
+// Mode, Temperature and FanSpeed are those of the matched row, which may differ
+// from the requested state when a fallback strategy was used
+type Result struct {
+	CodeID      int64    `json:"code_id"`         // ir_codes.id (0 when nothing matched)
+	Mode        string   `json:"mode"`            // Matched mode
+	Temperature *float64 `json:"temperature"`     // Matched temperature (nil for off and mode-only codes)
+	FanSpeed    *string  `json:"fan_speed"`       // Matched fan speed (nil for off and mode-only codes)
+	Swing       *string  `json:"swing,omitempty"` // Matched swing mode (nil when the model has no swing level)
+	Strategy    string   `json:"strategy"`        // Strategy* (exact, fan, mode_temp, mode_only, nearest_temp, off, on, miss)
+}
+
+// Fallback reports whether the matched code differs from an exact match
+func (r Result) Fallback() bool {
+	return r.Strategy != StrategyExact && r.Strategy != StrategyOff && r.Strategy != StrategyOn
+}
+
+// String returns a short description, e.g., "cool 22°C fan=low (fan #42)"
+func (r Result) String() string {
+	if r.CodeID == 0 {
+		return r.Strategy
+	}
+	desc := r.Mode
+	if r.Temperature != nil {
+		desc += fmt.Sprintf(" %g°C", *r.Temperature)
+	}
+	if r.FanSpeed != nil {
+		desc += " fan=" + *r.FanSpeed
+	}
+	if r.Swing != nil {
+		desc += " swing=" + *r.Swing
+	}
+	return fmt.Sprintf("%s (%s #%d)", desc, r.Strategy, r.CodeID)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Default is the registry served on /metrics
+var Default = NewRegistry()
+
+// Application metrics
+var (
+	CommandsTotal = Default.NewCounter("hvac_commands_total",
+		"Commands processed, by result (success, invalid, ignored, error).", "device", "model", "result")
+
+	IRSendsTotal = Default.NewCounter("hvac_ir_sends_total",
+		"IR transmissions, by result (success, failure).", "device", "model", "result")
+
+	LookupsTotal = Default.NewCounter("hvac_lookups_total",
+		"IR code lookups, by the strategy that resolved them.", "device", "model", "strategy")
+
+	LookupDuration = Default.NewHistogram("hvac_lookup_duration_seconds",
+		"IR code lookup latency.", DefaultBuckets, "device", "model")
+
+	MQTTConnected = Default.NewGauge("hvac_mqtt_connected",
+		"Whether the MQTT client is connected to the broker (1 or 0).")
+
+	MQTTReconnectsTotal = Default.NewCounter("hvac_mqtt_reconnects_total",
+		"Successful MQTT reconnections after a lost connection.")
+)
+
+func init() {
+	// Export 0 before the first connection so alerts on a down broker fire from startup
+	MQTTConnected.Set(0)
+}
+
+// deviceKey is the context key carrying the device ID for lookup metrics
+type deviceKey struct{}
+
+// WithDevice tags ctx with the device a lookup is made for
+func WithDevice(ctx context.Context, deviceID string) context.Context {
+	return context.WithValue(ctx, deviceKey{}, deviceID)
+}
+
+// DeviceFrom returns the device ID set by WithDevice ("" if none)
+func DeviceFrom(ctx context.Context) string {
+	deviceID, _ := ctx.Value(deviceKey{}).(string)
+	return deviceID
+}
+
+// ObserveLookup records the strategy and latency of an IR code lookup
+func ObserveLookup(ctx context.Context, modelID, strategy string, duration time.Duration) {
+	deviceID := DeviceFrom(ctx)
+	LookupsTotal.Inc(deviceID, modelID, strategy)
+	LookupDuration.Observe(duration.Seconds(), deviceID, modelID)
+}
+
+// Handler serves the default registry
+func Handler() http.Handler {
+	return Default.Handler()
+}
@@ -0,0 +1,279 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+)
+
+// Registry holds metric families and renders them in the Prometheus text format
+// Hand-written to avoid pulling in the Prometheus client library
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+// family is a metric family that can render itself
+type family interface {
+	write(w io.Writer)
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format (0.0.4)
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+
+	for _, f := range families {
+		f.write(w)
+	}
+}
+
+// Handler serves the registry on /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// vec stores one value per label combination
+type vec[T any] struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	kind   string
+	labels []string
+	values map[string]*T
+	keys   map[string][]string // Label values per key, for rendering
+	create func() *T
+}
+
+func newVec[T any](name, help, kind string, labels []string, create func() *T) *vec[T] {
+	return &vec[T]{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		values: make(map[string]*T),
+		keys:   make(map[string][]string),
+		create: create,
+	}
+}
+
+// get returns the value for the label values, creating it if needed; caller must hold v.mu
+func (v *vec[T]) get(labelValues []string) *T {
+	if len(labelValues) != len(v.labels) {
+		logger.Warn("Metric %s: got %d label values, want %d", v.name, len(labelValues), len(v.labels))
+		labelValues = append(labelValues, make([]string, len(v.labels))...)[:len(v.labels)]
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	value, ok := v.values[key]
+	if !ok {
+		value = v.create()
+		v.values[key] = value
+		v.keys[key] = append([]string(nil), labelValues...)
+	}
+	return value
+}
+
+// sortedKeys returns the label keys in a stable order; caller must hold v.mu
+func (v *vec[T]) sortedKeys() []string {
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *vec[T]) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.kind)
+}
+
+// CounterVec is a monotonically increasing counter per label combination
+type CounterVec struct {
+	*vec[float64]
+}
+
+// NewCounter registers a counter family
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels, func() *float64 { return new(float64) })}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the counter
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta (must not be negative) to the counter
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.get(labelValues) += delta
+}
+
+// Value returns the current value (0 if never set)
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if value, ok := c.values[strings.Join(labelValues, "\xff")]; ok {
+		return *value
+	}
+	return 0
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w)
+	for _, key := range c.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, c.keys[key]), formatValue(*c.values[key]))
+	}
+}
+
+// GaugeVec is a value that can go up and down per label combination
+type GaugeVec struct {
+	*CounterVec
+}
+
+// NewGauge registers a gauge family
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{&CounterVec{newVec(name, help, "gauge", labels, func() *float64 { return new(float64) })}}
+	r.register(g)
+	return g
+}
+
+// Set sets the gauge
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	*g.get(labelValues) = value
+}
+
+// histogram holds the observations of one label combination
+type histogram struct {
+	counts []uint64 // Per bucket, non-cumulative
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec tracks the distribution of observations per label combination
+type HistogramVec struct {
+	*vec[histogram]
+	buckets []float64
+}
+
+// DefaultBuckets suit sub-second latencies such as database lookups (seconds)
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// NewHistogram registers a histogram family with the given upper bounds
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{
+		vec:     newVec(name, help, "histogram", labels, func() *histogram { return &histogram{counts: make([]uint64, len(buckets))} }),
+		buckets: buckets,
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records one observation
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hist := h.get(labelValues)
+	hist.count++
+	hist.sum += value
+	for i, bound := range h.buckets {
+		if value <= bound {
+			hist.counts[i]++
+			break
+		}
+	}
+}
+
+// Count returns the number of observations (0 if never observed)
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hist, ok := h.values[strings.Join(labelValues, "\xff")]; ok {
+		return hist.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+
+	names := append(append([]string(nil), h.labels...), "le")
+	for _, key := range h.sortedKeys() {
+		hist := h.values[key]
+		values := append(append([]string(nil), h.keys[key]...), "")
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hist.counts[i]
+			values[len(values)-1] = formatValue(bound)
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(names, values), cumulative)
+		}
+		values[len(values)-1] = "+Inf"
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(names, values), hist.count)
+
+		labels := formatLabels(h.labels, h.keys[key])
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels, formatValue(hist.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels, hist.count)
+	}
+}
+
+// formatLabels renders {name="value",...}, or nothing without labels
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// labelEscaper escapes the characters the text format does not allow in label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatValue renders a sample value
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	commands := r.NewCounter("test_commands_total", "Commands processed.", "device", "result")
+	connected := r.NewGauge("test_connected", "Connection state.")
+	latency := r.NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 0.01}, "device")
+
+	commands.Inc("living_room", "success")
+	commands.Inc("living_room", "success")
+	commands.Inc(`say "hi"`, "error")
+	connected.Set(1)
+	latency.Observe(0.005, "living_room")
+	latency.Observe(0.05, "living_room")
+	latency.Observe(2, "living_room")
+
+	var b strings.Builder
+	r.WriteText(&b)
+
+	want := `# HELP test_commands_total Commands processed.
+# TYPE test_commands_total counter
+test_commands_total{device="living_room",result="success"} 2
+test_commands_total{device="say \"hi\"",result="error"} 1
+# HELP test_connected Connection state.
+# TYPE test_connected gauge
+test_connected 1
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{device="living_room",le="0.01"} 1
+test_latency_seconds_bucket{device="living_room",le="0.1"} 2
+test_latency_seconds_bucket{device="living_room",le="+Inf"} 3
+test_latency_seconds_sum{device="living_room"} 2.055
+test_latency_seconds_count{device="living_room"} 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("WriteText() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Test.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_total 1\n") {
+		t.Errorf("Unexpected body:\n%s", rec.Body.String())
+	}
+}
+
+func TestMQTTConnectedStartsAtZero(t *testing.T) {
+	var b strings.Builder
+	Default.WriteText(&b)
+
+	if !strings.Contains(b.String(), "\nhvac_mqtt_connected 0\n") {
+		t.Errorf("Expected hvac_mqtt_connected 0 before the first connect:\n%s", b.String())
+	}
+}
@@ -3,6 +3,8 @@ package mocks
 import (
 	"context"
 	"fmt"
+
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
 )
 
 // MockDatabase is a mock implementation of interfaces.IRDatabase for testing
@@ -15,6 +17,13 @@ type MockDatabase struct {
 	// Format: "modelID" -> IR code
 	OffCodes map[string]string
 
+	// OnCodes maps model IDs to on codes
+	// Format: "modelID" -> IR code
+	OnCodes map[string]string
+
+	// Precision is the temperature step of every model (0 = whole degrees)
+	Precision float64
+
 	// Err forces an error response for testing error handling
 	Err error
 
@@ -23,34 +32,60 @@ type MockDatabase struct {
 }
 
 // LookupCode implements interfaces.IRDatabase
-func (m *MockDatabase) LookupCode(ctx context.Context, modelID, mode string, temperature int, fanSpeed string) (string, error) {
-	key := fmt.Sprintf("%s:%s:%d:%s", modelID, mode, temperature, fanSpeed)
+// Codes only hold exact matches, so a found code is reported as an exact match
+func (m *MockDatabase) LookupCode(ctx context.Context, modelID, mode string, temperature float64, fanSpeed string) (string, lookup.Result, error) {
+	key := fmt.Sprintf("%s:%s:%g:%s", modelID, mode, temperature, fanSpeed)
 	m.Calls = append(m.Calls, key)
 
 	if m.Err != nil {
-		return "", m.Err
+		return "", lookup.Result{}, m.Err
 	}
 
 	if code, ok := m.Codes[key]; ok {
-		return code, nil
+		return code, lookup.Result{
+			Mode:        mode,
+			Temperature: &temperature,
+			FanSpeed:    &fanSpeed,
+			Strategy:    lookup.StrategyExact,
+		}, nil
 	}
 
-	return "", fmt.Errorf("code not found for %s", key)
+	return "", lookup.Result{Strategy: lookup.StrategyMiss}, fmt.Errorf("code not found for %s", key)
 }
 
 // LookupOffCode implements interfaces.IRDatabase
-func (m *MockDatabase) LookupOffCode(ctx context.Context, modelID string) (string, error) {
+func (m *MockDatabase) LookupOffCode(ctx context.Context, modelID string) (string, lookup.Result, error) {
 	m.Calls = append(m.Calls, fmt.Sprintf("%s:off", modelID))
 
 	if m.Err != nil {
-		return "", m.Err
+		return "", lookup.Result{}, m.Err
 	}
 
 	if code, ok := m.OffCodes[modelID]; ok {
-		return code, nil
+		return code, lookup.Result{Mode: "off", Strategy: lookup.StrategyOff}, nil
+	}
+
+	return "", lookup.Result{Strategy: lookup.StrategyMiss}, fmt.Errorf("off code not found for model %s", modelID)
+}
+
+// LookupOnCode implements interfaces.IRDatabase
+func (m *MockDatabase) LookupOnCode(ctx context.Context, modelID string) (string, lookup.Result, error) {
+	m.Calls = append(m.Calls, fmt.Sprintf("%s:on", modelID))
+
+	if m.Err != nil {
+		return "", lookup.Result{}, m.Err
 	}
 
-	return "", fmt.Errorf("off code not found for model %s", modelID)
+	if code, ok := m.OnCodes[modelID]; ok {
+		return code, lookup.Result{Mode: "on", Strategy: lookup.StrategyOn}, nil
+	}
+
+	return "", lookup.Result{Strategy: lookup.StrategyMiss}, fmt.Errorf("on code not found for model %s", modelID)
+}
+
+// LookupPrecision implements interfaces.IRDatabase
+func (m *MockDatabase) LookupPrecision(ctx context.Context, modelID string) (float64, error) {
+	return m.Precision, nil
 }
 
 // MockMQTT is a mock implementation of interfaces.MQTTPublisher for testing
@@ -2,9 +2,12 @@ package mqtt
 
 import (
 	"fmt"
+	"net/url"
+	"sync/atomic"
 	"time"
 
 	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/metrics"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
@@ -16,10 +19,17 @@ type Client struct {
 
 // Config holds MQTT connection configuration
 type Config struct {
-	Broker   string // e.g., "tcp://localhost:1883"
+	Broker   string // e.g., "tcp://localhost:1883", "ssl://broker:8883", "wss://broker:443/mqtt"
 	ClientID string
 	Username string
 	Password string
+
+	// TLS settings, applied to ssl://, tls://, mqtts:// and wss:// brokers
+	CACertFile         string // PEM bundle used to verify the broker (empty = system roots)
+	ClientCertFile     string // PEM client certificate for mutual TLS
+	ClientKeyFile      string // PEM private key matching ClientCertFile
+	ServerName         string // Overrides the host name used for certificate verification
+	InsecureSkipVerify bool   // Skip broker certificate verification (testing only)
 }
 
 // MessageHandler is a callback function for incoming MQTT messages
@@ -27,10 +37,30 @@ type MessageHandler func(topic string, payload []byte)
 
 // NewClient creates a new MQTT client with the given configuration
 func NewClient(config Config) (*Client, error) {
+	brokerURL, err := url.Parse(config.Broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker URL %q: %w", config.Broker, err)
+	}
+
 	opts := mqtt.NewClientOptions()
 	opts.AddBroker(config.Broker)
 	opts.SetClientID(config.ClientID)
 
+	switch {
+	case isSecureScheme(brokerURL.Scheme):
+		tlsConfig, err := newTLSConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		opts.SetTLSConfig(tlsConfig)
+	case isPlainScheme(brokerURL.Scheme):
+		if config.hasTLSOptions() {
+			logger.Warn("MQTT: TLS options are ignored for %s:// broker (use ssl:// or wss://)", brokerURL.Scheme)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q (valid: tcp, mqtt, ws, ssl, tls, mqtts, wss)", brokerURL.Scheme)
+	}
+
 	if config.Username != "" {
 		opts.SetUsername(config.Username)
 		opts.SetPassword(config.Password)
@@ -44,12 +74,18 @@ func NewClient(config Config) (*Client, error) {
 	opts.SetMaxReconnectInterval(5 * time.Second)
 
 	// Connection handlers
+	var connectedBefore atomic.Bool
 	opts.SetOnConnectHandler(func(c mqtt.Client) {
 		logger.Info("MQTT: Connected to broker")
+		metrics.MQTTConnected.Set(1)
+		if connectedBefore.Swap(true) {
+			metrics.MQTTReconnectsTotal.Inc()
+		}
 	})
 
 	opts.SetConnectionLostHandler(func(c mqtt.Client, err error) {
 		logger.Error("MQTT: Connection lost: %v", err)
+		metrics.MQTTConnected.Set(0)
 	})
 
 	opts.SetReconnectingHandler(func(c mqtt.Client, opts *mqtt.ClientOptions) {
@@ -71,6 +107,9 @@ func (c *Client) Connect() error {
 		return fmt.Errorf("connection timeout")
 	}
 	if err := token.Error(); err != nil {
+		if reason := describeTLSError(err); reason != "" {
+			return fmt.Errorf("connection failed: %s: %w", reason, err)
+		}
 		return fmt.Errorf("connection failed: %w", err)
 	}
 	return nil
@@ -79,12 +118,14 @@ func (c *Client) Connect() error {
 // Disconnect closes the connection to the MQTT broker
 func (c *Client) Disconnect() {
 	c.client.Disconnect(250)
+	metrics.MQTTConnected.Set(0)
 	logger.Info("MQTT: Disconnected from broker")
 }
 
 // Publish sends a message to a topic with delivery tracking
 func (c *Client) Publish(topic string, qos byte, retained bool, payload interface{}) error {
-	logger.Debug("MQTT Publish: topic=%s qos=%d retained=%v", topic, qos, retained)
+	log := logger.With(logger.FieldTopic, topic)
+	log.Debug("MQTT Publish: qos=%d retained=%v", qos, retained)
 
 	token := c.client.Publish(topic, qos, retained, payload)
 
@@ -106,6 +147,7 @@ func (c *Client) Publish(topic string, qos byte, retained bool, payload interfac
 // Subscribe subscribes to a topic with a message handler
 func (c *Client) Subscribe(topic string, qos byte, handler MessageHandler) error {
 	callback := func(client mqtt.Client, msg mqtt.Message) {
+		logger.With(logger.FieldTopic, msg.Topic()).Debug("MQTT message received (%d bytes)", len(msg.Payload()))
 		handler(msg.Topic(), msg.Payload())
 	}
 
@@ -0,0 +1,97 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// isSecureScheme reports whether the broker scheme runs over TLS
+func isSecureScheme(scheme string) bool {
+	switch scheme {
+	case "ssl", "tls", "mqtts", "wss":
+		return true
+	}
+	return false
+}
+
+// isPlainScheme reports whether the broker scheme is unencrypted
+func isPlainScheme(scheme string) bool {
+	switch scheme {
+	case "tcp", "mqtt", "ws":
+		return true
+	}
+	return false
+}
+
+// hasTLSOptions returns true if any TLS setting was provided
+func (c Config) hasTLSOptions() bool {
+	return c.CACertFile != "" || c.ClientCertFile != "" || c.ClientKeyFile != "" ||
+		c.ServerName != "" || c.InsecureSkipVerify
+}
+
+// newTLSConfig builds the TLS configuration for a secure broker connection
+func newTLSConfig(config Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	// Private CA bundle (falls back to system roots when empty)
+	if config.CACertFile != "" {
+		pem, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle %s: %w", config.CACertFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s contains no valid PEM certificates", config.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// Client certificate for mutual TLS
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		if config.ClientCertFile == "" || config.ClientKeyFile == "" {
+			return nil, fmt.Errorf("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate %s / key %s: %w",
+				config.ClientCertFile, config.ClientKeyFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// describeTLSError returns a human-readable explanation for TLS handshake failures
+// Returns an empty string if err is not TLS-related
+func describeTLSError(err error) string {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return "TLS: broker certificate signed by unknown authority (check MQTT_TLS_CA)"
+	case errors.As(err, &hostnameErr):
+		return fmt.Sprintf("TLS: broker certificate is not valid for %q (check broker host or MQTT_TLS_SERVER_NAME)", hostnameErr.Host)
+	case errors.As(err, &invalidCert):
+		if invalidCert.Reason == x509.Expired {
+			return "TLS: broker certificate has expired or is not yet valid"
+		}
+		return "TLS: broker certificate is invalid"
+	case errors.As(err, &recordHeaderErr):
+		return "TLS: broker did not answer with TLS (is this a plaintext port?)"
+	case errors.As(err, &alertErr):
+		return fmt.Sprintf("TLS: broker rejected the handshake (%v); a client certificate may be required", alertErr)
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+package mqtt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCert generates a self-signed certificate and key in dir
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "hvac-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir)
+
+	garbage := filepath.Join(dir, "garbage.pem")
+	os.WriteFile(garbage, []byte("not a certificate"), 0o600)
+
+	tests := []struct {
+		name      string
+		config    Config
+		wantErr   string
+		wantRoots bool
+		wantCerts int
+	}{
+		{
+			name:   "System roots",
+			config: Config{},
+		},
+		{
+			name:      "Private CA",
+			config:    Config{CACertFile: certFile},
+			wantRoots: true,
+		},
+		{
+			name:      "Mutual TLS",
+			config:    Config{CACertFile: certFile, ClientCertFile: certFile, ClientKeyFile: keyFile},
+			wantRoots: true,
+			wantCerts: 1,
+		},
+		{
+			name:    "Missing CA file",
+			config:  Config{CACertFile: filepath.Join(dir, "missing.pem")},
+			wantErr: "failed to read CA bundle",
+		},
+		{
+			name:    "CA file without certificates",
+			config:  Config{CACertFile: garbage},
+			wantErr: "no valid PEM certificates",
+		},
+		{
+			name:    "Cert without key",
+			config:  Config{ClientCertFile: certFile},
+			wantErr: "must be set together",
+		},
+		{
+			name:    "Mismatched key",
+			config:  Config{ClientCertFile: certFile, ClientKeyFile: garbage},
+			wantErr: "failed to load client certificate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := newTLSConfig(tt.config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (tlsConfig.RootCAs != nil) != tt.wantRoots {
+				t.Errorf("RootCAs set = %v, want %v", tlsConfig.RootCAs != nil, tt.wantRoots)
+			}
+			if len(tlsConfig.Certificates) != tt.wantCerts {
+				t.Errorf("Certificates = %d, want %d", len(tlsConfig.Certificates), tt.wantCerts)
+			}
+		})
+	}
+}
+
+func TestNewClient_BrokerSchemes(t *testing.T) {
+	certFile, _ := writeTestCert(t, t.TempDir())
+
+	tests := []struct {
+		broker  string
+		config  Config
+		wantErr bool
+	}{
+		{broker: "tcp://localhost:1883"},
+		{broker: "mqtt://localhost:1883"},
+		{broker: "ws://localhost:9001/mqtt"},
+		{broker: "ssl://localhost:8883", config: Config{CACertFile: certFile}},
+		{broker: "mqtts://localhost:8883"},
+		{broker: "wss://localhost:443/mqtt", config: Config{ServerName: "broker.local"}},
+		{broker: "ssl://localhost:8883", config: Config{CACertFile: "/nonexistent/ca.pem"}, wantErr: true},
+		{broker: "http://localhost:1883", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.broker, func(t *testing.T) {
+			tt.config.Broker = tt.broker
+			tt.config.ClientID = "tls-test"
+			_, err := NewClient(tt.config)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDescribeTLSError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Unknown authority", &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}, "unknown authority"},
+		{"Hostname mismatch", fmt.Errorf("dial: %w", x509.HostnameError{Host: "broker.local", Certificate: &x509.Certificate{}}), "broker.local"},
+		{"Expired", x509.CertificateInvalidError{Reason: x509.Expired}, "expired"},
+		{"Plaintext port", tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, "plaintext"},
+		{"Handshake alert", tls.AlertError(116), "client certificate"},
+		{"Not TLS", fmt.Errorf("connection refused"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeTLSError(tt.err)
+			if tt.want == "" {
+				if got != "" {
+					t.Errorf("Expected no description, got %q", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("describeTLSError() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
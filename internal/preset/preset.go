@@ -0,0 +1,92 @@
+package preset
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/diogoaguiar/hvac-manager/internal/homeassistant"
+)
+
+// None is the HA preset value meaning "no preset active"
+const None = "none"
+
+// Preset maps a named preset to a bundle of state fields
+// Nil fields are left unchanged; Code, if set, is sent instead of looking up
+// the state code (for models with a dedicated eco/turbo/sleep button)
+type Preset struct {
+	Name        string   `json:"name"`
+	Mode        *string  `json:"mode,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	FanMode     *string  `json:"fan_mode,omitempty"`
+	Code        string   `json:"ir_code,omitempty"` // Base64-encoded Tuya code
+}
+
+// Load reads presets from a JSON file containing an array of Preset
+// There are no built-in presets: HA only offers preset modes when a file is loaded.
+func Load(path string) ([]Preset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presets file: %w", err)
+	}
+
+	var presets []Preset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("failed to parse presets file %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range presets {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("duplicate preset: %s", p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	return presets, nil
+}
+
+// Validate checks that a preset is named, does something, and uses valid values
+func (p Preset) Validate() error {
+	if p.Name == "" || p.Name == None {
+		return fmt.Errorf("invalid preset name: %q", p.Name)
+	}
+	if p.Mode == nil && p.Temperature == nil && p.FanMode == nil && p.Code == "" {
+		return fmt.Errorf("preset %s must set mode, temperature, fan_mode or ir_code", p.Name)
+	}
+	if err := p.Command().Validate(); err != nil {
+		return fmt.Errorf("preset %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// Command returns the state fields of the preset as a command
+func (p Preset) Command() *homeassistant.ClimateCommand {
+	return &homeassistant.ClimateCommand{
+		Temperature: p.Temperature,
+		Mode:        p.Mode,
+		FanMode:     p.FanMode,
+	}
+}
+
+// Find returns the preset with the given name
+func Find(presets []Preset, name string) (Preset, bool) {
+	for _, p := range presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// Names returns the preset names in order, as advertised in HA discovery
+func Names(presets []Preset) []string {
+	names := make([]string, 0, len(presets))
+	for _, p := range presets {
+		names = append(names, p.Name)
+	}
+	return names
+}
@@ -0,0 +1,56 @@
+package preset
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{"Valid", `[{"name": "eco", "temperature": 20, "fan_mode": "low"}, {"name": "turbo", "ir_code": "C/MgAQUB"}]`, 2, false},
+		{"Empty name", `[{"temperature": 20}]`, 0, true},
+		{"Reserved name", `[{"name": "none", "temperature": 20}]`, 0, true},
+		{"No fields", `[{"name": "eco"}]`, 0, true},
+		{"Invalid mode", `[{"name": "eco", "mode": "turbo"}]`, 0, true},
+		{"Duplicate", `[{"name": "eco", "temperature": 20}, {"name": "eco", "temperature": 21}]`, 0, true},
+		{"Not JSON", `eco=20`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "presets.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			presets, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(presets) != tt.want {
+				t.Errorf("Load() returned %d presets, want %d", len(presets), tt.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	off := "off"
+	presets := []Preset{{Name: "eco", Temperature: new(float64)}, {Name: "away", Mode: &off}}
+
+	if p, ok := Find(presets, "away"); !ok || p.Mode == nil || *p.Mode != "off" {
+		t.Errorf("Find(away) = %+v, %v", p, ok)
+	}
+	if _, ok := Find(presets, "party"); ok {
+		t.Error("Find(party) should not match")
+	}
+	if got := Names(presets); len(got) != 2 || got[0] != "eco" {
+		t.Errorf("Names() = %v", got)
+	}
+}
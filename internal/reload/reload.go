@@ -0,0 +1,226 @@
+package reload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/interfaces"
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
+)
+
+// DefaultInterval is how often the code directory is scanned for changed files
+const DefaultInterval = 5 * time.Second
+
+// Store imports SmartIR files (implemented by *database.DB)
+// ReplaceModel must be all-or-nothing, and reject files with skipped entries, so a bad file never touches the stored codes.
+type Store interface {
+	ListCodes(ctx context.Context, modelID string) ([]database.IRCode, error)
+	ReplaceModel(ctx context.Context, modelID, filePath string) error
+}
+
+// Event reports the outcome of one reload
+type Event struct {
+	ModelID   string `json:"model_id"`
+	File      string `json:"file"`
+	Added     int    `json:"added"`
+	Updated   int    `json:"updated"`
+	Removed   int    `json:"removed"`
+	Unchanged int    `json:"unchanged"`
+	Error     string `json:"error,omitempty"` // Set when the file was rejected and the stored codes kept
+	Time      string `json:"time"`            // RFC 3339 timestamp
+}
+
+// Watcher re-imports SmartIR files of a directory when they are added or edited
+// Files are compared by size and modification time; a changed file is only imported
+// once it looks the same on two consecutive scans, so files still being written are skipped.
+type Watcher struct {
+	store  Store
+	mqtt   interfaces.MQTTPublisher // Optional, events are only logged when nil
+	topics topics.Builder
+	dir    string
+
+	mu     sync.Mutex
+	files  map[string]*fileState // By file name
+	seeded bool
+}
+
+// fileState tracks one model file between scans
+type fileState struct {
+	seen   signature // Last scan
+	loaded signature // Last import attempt (or the first scan)
+}
+
+// signature identifies a version of a file
+type signature struct {
+	size    int64
+	modTime time.Time
+}
+
+// New creates a watcher for the SmartIR files in dir
+func New(store Store, mqtt interfaces.MQTTPublisher, topics topics.Builder, dir string) *Watcher {
+	return &Watcher{
+		store:  store,
+		mqtt:   mqtt,
+		topics: topics,
+		dir:    dir,
+		files:  make(map[string]*fileState),
+	}
+}
+
+// Run scans the directory every interval until ctx is cancelled
+// Files present at the first scan are assumed to be loaded already.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	w.Check(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check(ctx)
+		}
+	}
+}
+
+// Check scans the directory once and reloads every settled changed file
+func (w *Watcher) Check(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		logger.Error("Failed to scan IR code directory %s: %v", w.dir, err)
+		return
+	}
+
+	present := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		modelID, ok := database.ModelIDFromFilename(name)
+		if entry.IsDir() || !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed since ReadDir
+		}
+		present[name] = true
+		sig := signature{size: info.Size(), modTime: info.ModTime()}
+
+		state, ok := w.files[name]
+		switch {
+		case !w.seeded:
+			w.files[name] = &fileState{seen: sig, loaded: sig}
+		case !ok:
+			logger.Debug("New IR code file %s, waiting for it to settle", name)
+			w.files[name] = &fileState{seen: sig}
+		case sig == state.loaded:
+			state.seen = sig
+		case sig != state.seen:
+			state.seen = sig // Still changing
+		default:
+			state.loaded = sig // Not retried until the file changes again
+			w.reload(ctx, modelID, filepath.Join(w.dir, name))
+		}
+	}
+
+	for name := range w.files {
+		if !present[name] {
+			logger.Info("IR code file %s removed, keeping its stored codes", name)
+			delete(w.files, name)
+		}
+	}
+	w.seeded = true
+}
+
+// reload imports one model file and reports what changed
+func (w *Watcher) reload(ctx context.Context, modelID, path string) {
+	event := Event{ModelID: modelID, File: filepath.Base(path), Time: time.Now().Format(time.RFC3339)}
+	defer func() { w.publish(event) }()
+
+	before, err := w.store.ListCodes(ctx, modelID)
+	if err != nil {
+		event.Error = err.Error()
+		logger.Error("❌ Failed to read IR codes of model %s: %v", modelID, err)
+		return
+	}
+
+	if err := w.store.ReplaceModel(ctx, modelID, path); err != nil {
+		event.Error = err.Error()
+		logger.Warn("⚠️  Rejected %s, keeping the stored codes of model %s: %v", event.File, modelID, err)
+		return
+	}
+
+	after, err := w.store.ListCodes(ctx, modelID)
+	if err != nil {
+		event.Error = err.Error()
+		logger.Error("❌ Failed to read IR codes of model %s: %v", modelID, err)
+		return
+	}
+
+	event.Added, event.Updated, event.Removed, event.Unchanged = diff(before, after)
+	logger.Info("🔄 Reloaded model %s from %s: %d added, %d updated, %d removed, %d unchanged",
+		modelID, event.File, event.Added, event.Updated, event.Removed, event.Unchanged)
+}
+
+// publish sends an event to the code events topic
+func (w *Watcher) publish(event Event) {
+	if w.mqtt == nil {
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("Failed to encode reload event: %v", err)
+		return
+	}
+	if err := w.mqtt.Publish(w.topics.CodeEvents(), 1, false, payload); err != nil {
+		logger.Warn("Failed to publish reload event: %v", err)
+	}
+}
+
+// diff counts the codes of after that are new, changed or identical in before, and those of before that are gone
+func diff(before, after []database.IRCode) (added, updated, removed, unchanged int) {
+	old := make(map[string]string, len(before))
+	for _, code := range before {
+		old[stateKey(code)] = code.IRCode
+	}
+
+	for _, code := range after {
+		previous, ok := old[stateKey(code)]
+		switch {
+		case !ok:
+			added++
+		case previous != code.IRCode:
+			updated++
+		default:
+			unchanged++
+		}
+		delete(old, stateKey(code))
+	}
+	return added, updated, len(old), unchanged
+}
+
+// stateKey identifies the AC state of a code
+func stateKey(code database.IRCode) string {
+	temp, fan, swing := "-", "-", "-"
+	if code.Temperature != nil {
+		temp = fmt.Sprint(*code.Temperature)
+	}
+	if code.FanSpeed != nil {
+		fan = *code.FanSpeed
+	}
+	if code.Swing != nil {
+		swing = *code.Swing
+	}
+	return code.Mode + "/" + temp + "/" + fan + "/" + swing
+}
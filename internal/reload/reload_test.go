@@ -0,0 +1,208 @@
+package reload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/mocks"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
+)
+
+const modelJSON = `{
+	"manufacturer": "Test",
+	"supportedModels": ["T1"],
+	"commandsEncoding": "Raw",
+	"supportedController": "MQTT",
+	"minTemperature": 18,
+	"maxTemperature": 30,
+	"precision": 1,
+	"operationModes": ["cool"],
+	"fanModes": ["low"],
+	"commands": {
+		"off": "OFF",
+		"cool": {"low": {"22": %q%s}}
+	}
+}`
+
+func setupDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(context.Background()); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
+	}
+	return db
+}
+
+// writeModel writes a model file
+// Each write gets a later modification time so changes are visible within one second
+func writeModel(t *testing.T, path, content string, version int) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	mtime := time.Now().Add(time.Duration(version) * time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+}
+
+// model returns a model file with the given cool/low/22 code and extra cool/low entries
+func model(cool22, extra string) string {
+	return fmt.Sprintf(modelJSON, cool22, extra)
+}
+
+func lookup(t *testing.T, db *database.DB, temp float64) string {
+	t.Helper()
+	code, _, err := db.LookupCode(context.Background(), "9000", "cool", temp, "low")
+	if err != nil {
+		return ""
+	}
+	return code
+}
+
+// lastEvent returns the last event published to the code events topic
+func lastEvent(t *testing.T, m *mocks.MockMQTT) (Event, bool) {
+	t.Helper()
+	for i := len(m.Published) - 1; i >= 0; i-- {
+		if m.Published[i].Topic != "homeassistant/climate/codes/events" {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(m.Published[i].Payload.([]byte), &e); err != nil {
+			t.Fatalf("Invalid event payload: %v", err)
+		}
+		return e, true
+	}
+	return Event{}, false
+}
+
+// TestWatcher_Reload tests that an edited file is re-imported once it has settled
+func TestWatcher_Reload(t *testing.T) {
+	ctx := context.Background()
+	db := setupDB(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "9000.json")
+
+	writeModel(t, path, model("COOL22", `, "23": "COOL23", "25": "COOL25"`), 0)
+	if err := db.LoadFromJSON(ctx, "9000", path); err != nil {
+		t.Fatalf("LoadFromJSON failed: %v", err)
+	}
+
+	mqtt := &mocks.MockMQTT{Connected: true}
+	w := New(db, mqtt, topics.Default(), dir)
+	w.Check(ctx) // Baseline: nothing reloaded
+	if len(mqtt.Published) != 0 {
+		t.Fatalf("Expected no event for the initial scan, got %d", len(mqtt.Published))
+	}
+
+	writeModel(t, path, model("COOL22-NEW", `, "23": "COOL23", "24": "COOL24"`), 1)
+	w.Check(ctx) // Change seen, waiting for it to settle
+	if got := lookup(t, db, 22); got != "COOL22" {
+		t.Fatalf("Expected COOL22 before the file settled, got %q", got)
+	}
+
+	w.Check(ctx)
+	if got := lookup(t, db, 22); got != "COOL22-NEW" {
+		t.Errorf("Expected COOL22-NEW after reload, got %q", got)
+	}
+	if got := lookup(t, db, 24); got != "COOL24" {
+		t.Errorf("Expected COOL24 after reload, got %q", got)
+	}
+
+	if got := lookup(t, db, 25); got != "" {
+		t.Errorf("Expected COOL25 to be removed, got %q", got)
+	}
+
+	event, ok := lastEvent(t, mqtt)
+	if !ok {
+		t.Fatal("Expected a reload event")
+	}
+	want := Event{ModelID: "9000", File: "9000.json", Added: 1, Updated: 1, Removed: 1, Unchanged: 2, Time: event.Time}
+	if event != want {
+		t.Errorf("Event = %+v, want %+v", event, want)
+	}
+
+	// An unchanged file is not imported again
+	published := len(mqtt.Published)
+	w.Check(ctx)
+	if len(mqtt.Published) != published {
+		t.Error("Expected no event for an unchanged file")
+	}
+}
+
+// TestWatcher_RejectsPartialFile tests that a file cut short or with a bad entry keeps the stored codes
+func TestWatcher_RejectsPartialFile(t *testing.T) {
+	ctx := context.Background()
+	db := setupDB(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "9000.json")
+
+	content := model("COOL22", "")
+	writeModel(t, path, content, 0)
+	if err := db.LoadFromJSON(ctx, "9000", path); err != nil {
+		t.Fatalf("LoadFromJSON failed: %v", err)
+	}
+
+	mqtt := &mocks.MockMQTT{Connected: true}
+	w := New(db, mqtt, topics.Default(), dir)
+	w.Check(ctx)
+
+	for i, partial := range []string{
+		content[:len(content)/2],
+		`{"commandsEncoding": "Raw", "supportedController": "MQTT"}`,
+		model("COOL22-NEW", `, "23": 23`), // Well-formed, but 23 is not a code
+	} {
+		writeModel(t, path, partial, i+1)
+		w.Check(ctx)
+		w.Check(ctx)
+
+		event, ok := lastEvent(t, mqtt)
+		if !ok || event.Error == "" {
+			t.Errorf("Expected an error event for %q, got %+v", partial, event)
+		}
+		if got := lookup(t, db, 22); got != "COOL22" {
+			t.Errorf("Expected COOL22 to be kept, got %q", got)
+		}
+	}
+
+	// Fixing the file imports it
+	writeModel(t, path, model("COOL22-FIXED", ""), 4)
+	w.Check(ctx)
+	w.Check(ctx)
+	if got := lookup(t, db, 22); got != "COOL22-FIXED" {
+		t.Errorf("Expected COOL22-FIXED, got %q", got)
+	}
+}
+
+// TestWatcher_NewFile tests that a file added after startup is imported
+func TestWatcher_NewFile(t *testing.T) {
+	ctx := context.Background()
+	db := setupDB(t)
+	dir := t.TempDir()
+
+	w := New(db, nil, topics.Default(), dir)
+	w.Check(ctx)
+
+	// Not a model file
+	writeModel(t, filepath.Join(dir, "notes.txt"), "hello", 0)
+	writeModel(t, filepath.Join(dir, "9000_tuya.json"), model("COOL22", ""), 0)
+	w.Check(ctx)
+	if got := lookup(t, db, 22); got != "" {
+		t.Fatalf("Expected no import before the file settled, got %q", got)
+	}
+
+	w.Check(ctx)
+	if got := lookup(t, db, 22); got != "COOL22" {
+		t.Errorf("Expected COOL22 after import, got %q", got)
+	}
+}
@@ -0,0 +1,184 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/homeassistant"
+)
+
+// dayNames maps the short weekday names used in schedules to time.Weekday
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// dayAliases expands common day groups
+var dayAliases = map[string]string{
+	"daily":    "mon,tue,wed,thu,fri,sat,sun",
+	"nightly":  "mon,tue,wed,thu,fri,sat,sun",
+	"everyday": "mon,tue,wed,thu,fri,sat,sun",
+	"weekdays": "mon,tue,wed,thu,fri",
+	"weekends": "sat,sun",
+}
+
+// Days is a set of weekdays
+type Days [7]bool
+
+// ParseDays parses a day specification into a set of weekdays
+// Accepts aliases ("weekdays", "weekends", "daily"), names ("mon,wed,fri")
+// and ranges ("mon-fri"); names may be abbreviated or in full
+func ParseDays(spec string) (Days, error) {
+	var days Days
+
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if alias, ok := dayAliases[spec]; ok {
+		spec = alias
+	}
+	if spec == "" {
+		return days, fmt.Errorf("no days given")
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if alias, ok := dayAliases[part]; ok {
+			for _, name := range strings.Split(alias, ",") {
+				day, _ := parseDay(name)
+				days[day] = true
+			}
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := parseDay(from)
+		if err != nil {
+			return days, err
+		}
+		end := start
+		if isRange {
+			if end, err = parseDay(to); err != nil {
+				return days, err
+			}
+		}
+
+		// Ranges may wrap around the week, e.g., "fri-mon"
+		for d := start; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == end {
+				break
+			}
+		}
+	}
+
+	return days, nil
+}
+
+// parseDay parses a single weekday name ("mon" or "monday")
+func parseDay(name string) (time.Weekday, error) {
+	name = strings.TrimSpace(name)
+	if len(name) >= 3 {
+		for i, day := range dayNames {
+			if strings.HasPrefix(strings.ToLower(time.Weekday(i).String()), name) && strings.HasPrefix(name, day) {
+				return time.Weekday(i), nil
+			}
+		}
+	}
+	return time.Sunday, fmt.Errorf("invalid day: %q", name)
+}
+
+// String returns the canonical form, e.g., "mon,tue,wed,thu,fri"
+func (d Days) String() string {
+	var names []string
+	// Monday first, Sunday last
+	for i := 1; i <= 7; i++ {
+		if d[i%7] {
+			names = append(names, dayNames[i%7])
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseTime parses a local time of day "HH:MM" into minutes since midnight
+func ParseTime(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Normalize validates a slot and rewrites days and time in canonical form
+func Normalize(s *database.Schedule) error {
+	days, err := ParseDays(s.Days)
+	if err != nil {
+		return err
+	}
+	minutes, err := ParseTime(s.Time)
+	if err != nil {
+		return err
+	}
+
+	if s.Mode == nil && s.Temperature == nil && s.FanMode == nil {
+		return fmt.Errorf("schedule slot must set at least one of mode, temperature or fan_mode")
+	}
+
+	if err := Command(*s).Validate(); err != nil {
+		return err
+	}
+
+	s.Days = days.String()
+	s.Time = fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+	return nil
+}
+
+// Command converts a slot into the command applied when it fires
+func Command(s database.Schedule) *homeassistant.ClimateCommand {
+	return &homeassistant.ClimateCommand{
+		Temperature: s.Temperature,
+		Mode:        s.Mode,
+		FanMode:     s.FanMode,
+	}
+}
+
+// Due returns true if an enabled slot has an occurrence in (from, to]
+// Times are evaluated in the location of to
+func Due(s database.Schedule, from, to time.Time) bool {
+	_, ok := LastOccurrence(s, from, to)
+	return ok
+}
+
+// LastOccurrence returns the latest occurrence of an enabled slot in (from, to]
+// Times are evaluated in the location of to
+func LastOccurrence(s database.Schedule, from, to time.Time) (time.Time, bool) {
+	if !s.Enabled || !to.After(from) {
+		return time.Time{}, false
+	}
+	days, err := ParseDays(s.Days)
+	if err != nil {
+		return time.Time{}, false
+	}
+	minutes, err := ParseTime(s.Time)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// Look back at most one week (e.g., after the host was suspended)
+	if to.Sub(from) > 7*24*time.Hour {
+		from = to.Add(-7 * 24 * time.Hour)
+	}
+
+	loc := to.Location()
+	from = from.In(loc)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	var last time.Time
+	found := false
+	for !day.After(to) {
+		if days[day.Weekday()] {
+			at := time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, loc)
+			if at.After(from) && !at.After(to) {
+				last, found = at, true
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return last, found
+}
@@ -0,0 +1,132 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+)
+
+func strPtr(s string) *string     { return &s }
+func floatPtr(f float64) *float64 { return &f }
+
+func TestParseDays(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{"weekdays", "mon,tue,wed,thu,fri", false},
+		{"Weekends", "sat,sun", false},
+		{"daily", "mon,tue,wed,thu,fri,sat,sun", false},
+		{"nightly", "mon,tue,wed,thu,fri,sat,sun", false},
+		{"mon,wed,fri", "mon,wed,fri", false},
+		{"monday, friday", "mon,fri", false},
+		{"mon-fri", "mon,tue,wed,thu,fri", false},
+		{"fri-mon", "mon,fri,sat,sun", false},
+		{"weekends,mon", "mon,sat,sun", false},
+		{"", "", true},
+		{"funday", "", true},
+		{"mo", "", true},
+		{"mon-xyz", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			days, err := ParseDays(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDays(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && days.String() != tt.want {
+				t.Errorf("ParseDays(%q) = %q, want %q", tt.spec, days.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"07:00", 420, false},
+		{"23:59", 1439, false},
+		{"7:05", 425, false},
+		{"24:00", 0, true},
+		{"noon", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseTime(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTime(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	slot := database.Schedule{Days: "weekdays", Time: "07:00", Mode: strPtr("heat"), Temperature: floatPtr(21)}
+	if err := Normalize(&slot); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if slot.Days != "mon,tue,wed,thu,fri" {
+		t.Errorf("Days = %q, want canonical form", slot.Days)
+	}
+
+	invalid := []database.Schedule{
+		{Days: "weekdays", Time: "07:00"},                            // No command
+		{Days: "weekdays", Time: "07:00", Mode: strPtr("turbo")},     // Bad mode
+		{Days: "weekdays", Time: "07:00", Temperature: floatPtr(40)}, // Out of range
+		{Days: "weekdays", Time: "07:00", FanMode: strPtr("max")},    // Bad fan mode
+		{Days: "someday", Time: "07:00", Mode: strPtr("off")},        // Bad days
+		{Days: "weekdays", Time: "7 o'clock", Mode: strPtr("off")},   // Bad time
+	}
+	for i, s := range invalid {
+		if err := Normalize(&s); err == nil {
+			t.Errorf("Case %d: expected error for %+v", i, s)
+		}
+	}
+}
+
+func TestDue(t *testing.T) {
+	// Wednesday 2024-01-10
+	wed0659 := time.Date(2024, 1, 10, 6, 59, 30, 0, time.UTC)
+	wed0700 := time.Date(2024, 1, 10, 7, 0, 0, 0, time.UTC)
+	wed0701 := time.Date(2024, 1, 10, 7, 0, 30, 0, time.UTC)
+	sat0659 := time.Date(2024, 1, 13, 6, 59, 30, 0, time.UTC)
+	sat0701 := time.Date(2024, 1, 13, 7, 0, 30, 0, time.UTC)
+
+	weekdays := database.Schedule{Days: "mon,tue,wed,thu,fri", Time: "07:00", Enabled: true}
+	disabled := weekdays
+	disabled.Enabled = false
+	midnight := database.Schedule{Days: "thu", Time: "00:00", Enabled: true}
+
+	tests := []struct {
+		name     string
+		slot     database.Schedule
+		from, to time.Time
+		want     bool
+	}{
+		{"Window contains slot", weekdays, wed0659, wed0701, true},
+		{"Window ends at slot", weekdays, wed0659, wed0700, true},
+		{"Window starts at slot", weekdays, wed0700, wed0701, false},
+		{"Wrong day", weekdays, sat0659, sat0701, false},
+		{"Disabled", disabled, wed0659, wed0701, false},
+		{"Across midnight", midnight, wed0659.Add(17 * time.Hour), wed0659.Add(18 * time.Hour), true},
+		{"Empty window", weekdays, wed0701, wed0701, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Due(tt.slot, tt.from, tt.to); got != tt.want {
+				t.Errorf("Due() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/audit"
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/homeassistant"
+	"github.com/diogoaguiar/hvac-manager/internal/interfaces"
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
+)
+
+// DefaultInterval is how often the scheduler checks for due slots
+const DefaultInterval = 30 * time.Second
+
+// Store persists schedule slots (implemented by *database.DB)
+type Store interface {
+	ListSchedules(ctx context.Context, deviceID string) ([]database.Schedule, error)
+	SaveSchedule(ctx context.Context, s *database.Schedule) error
+	DeleteSchedule(ctx context.Context, deviceID string, id int64) error
+}
+
+// Target receives the commands of due slots (implemented by *device.Device)
+// Commands go through the same path as HA commands, so a slot simply
+// overrides whatever was set manually until then
+type Target interface {
+	ID() string
+	Apply(ctx context.Context, cmd *homeassistant.ClimateCommand) error
+}
+
+// ErrInvalidEdit is wrapped by ApplyEdit errors caused by the edit itself
+// (as opposed to storage failures), e.g., to answer HTTP 400 instead of 500
+var ErrInvalidEdit = errors.New("invalid schedule change")
+
+// Edit is a schedule change received over MQTT or HTTP
+// Without an ID a new slot is added; with Delete the slot is removed
+type Edit struct {
+	database.Schedule
+	Delete bool `json:"delete,omitempty"`
+}
+
+// Scheduler fires weekly schedule slots for a set of devices
+type Scheduler struct {
+	mu sync.Mutex
+
+	store   Store
+	mqtt    interfaces.MQTTPublisher
+	topics  topics.Builder
+	targets map[string]Target
+	last    time.Time // End of the previously checked window
+}
+
+// New creates a scheduler for the given devices
+func New(store Store, mqtt interfaces.MQTTPublisher, t topics.Builder, targets ...Target) *Scheduler {
+	s := &Scheduler{
+		store:   store,
+		mqtt:    mqtt,
+		topics:  t,
+		targets: make(map[string]Target),
+	}
+	for _, target := range targets {
+		s.targets[target.ID()] = target
+	}
+	return s
+}
+
+// Run checks for due slots every interval until ctx is cancelled
+// Slots that passed while the service was down are not replayed
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	s.mu.Lock()
+	s.last = time.Now()
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Check(ctx, now)
+		}
+	}
+}
+
+// Check applies every slot due since the previous check
+// After a long gap (e.g., the host was suspended) slots are applied in the order
+// they fell due, so the device ends up in the state of the most recent one.
+func (s *Scheduler) Check(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	from := s.last
+	s.last = now
+	s.mu.Unlock()
+
+	if from.IsZero() {
+		return
+	}
+
+	for id, target := range s.targets {
+		slots, err := s.store.ListSchedules(ctx, id)
+		if err != nil {
+			logger.Error("Failed to load schedule for %s: %v", id, err)
+			continue
+		}
+
+		// Slots are listed by ID; sort the due ones by their last occurrence
+		type dueSlot struct {
+			slot database.Schedule
+			at   time.Time
+		}
+		var due []dueSlot
+		for _, slot := range slots {
+			if at, ok := LastOccurrence(slot, from, now); ok {
+				due = append(due, dueSlot{slot: slot, at: at})
+			}
+		}
+		sort.SliceStable(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+
+		for _, d := range due {
+			slot := d.slot
+			slotCtx := logger.WithContext(audit.WithSource(ctx, audit.SourceSchedule),
+				logger.FieldCorrelationID, logger.NewCorrelationID())
+			log := logger.FromContext(slotCtx)
+			log.Info("⏰ Schedule slot %d for %s (%s %s)", slot.ID, id, slot.Days, slot.Time)
+			if err := target.Apply(slotCtx, Command(slot)); err != nil {
+				log.Error("❌ Schedule slot %d failed: %v", slot.ID, err)
+			}
+		}
+	}
+}
+
+// List returns the schedule of a device
+func (s *Scheduler) List(ctx context.Context, deviceID string) ([]database.Schedule, error) {
+	if _, ok := s.targets[deviceID]; !ok {
+		return nil, fmt.Errorf("unknown device: %s", deviceID)
+	}
+	slots, err := s.store.ListSchedules(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if slots == nil {
+		slots = []database.Schedule{}
+	}
+	return slots, nil
+}
+
+// ApplyEdit adds, updates or deletes a slot and republishes the schedule
+// Returns the saved slot (nil when deleted)
+func (s *Scheduler) ApplyEdit(ctx context.Context, deviceID string, edit Edit) (*database.Schedule, error) {
+	if _, ok := s.targets[deviceID]; !ok {
+		return nil, fmt.Errorf("unknown device: %s", deviceID)
+	}
+
+	if edit.Delete {
+		if edit.ID == 0 {
+			return nil, fmt.Errorf("%w: delete requires a slot id", ErrInvalidEdit)
+		}
+		if err := s.store.DeleteSchedule(ctx, deviceID, edit.ID); err != nil {
+			return nil, err
+		}
+		logger.Info("🗑️  Deleted schedule slot %d for %s", edit.ID, deviceID)
+		s.publishOrLog(ctx, deviceID)
+		return nil, nil
+	}
+
+	slot := edit.Schedule
+	slot.DeviceID = deviceID
+	if err := Normalize(&slot); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEdit, err)
+	}
+	if err := s.store.SaveSchedule(ctx, &slot); err != nil {
+		return nil, err
+	}
+
+	logger.Info("📅 Saved schedule slot %d for %s: %s %s", slot.ID, deviceID, slot.Days, slot.Time)
+	s.publishOrLog(ctx, deviceID)
+	return &slot, nil
+}
+
+// HandleEdit processes a schedule change received over MQTT
+// Payload is an Edit as JSON; "enabled" defaults to true when omitted
+func (s *Scheduler) HandleEdit(deviceID string, payload []byte) {
+	logger.Info("📥 Received schedule change for %s: %s", deviceID, string(payload))
+
+	edit := Edit{Schedule: database.Schedule{Enabled: true}}
+	if err := json.Unmarshal(payload, &edit); err != nil {
+		logger.Error("Invalid schedule payload: %v", err)
+		return
+	}
+
+	if _, err := s.ApplyEdit(context.Background(), deviceID, edit); err != nil {
+		logger.Error("❌ Schedule change failed: %v", err)
+	}
+}
+
+// Publish publishes the device's schedule as a retained JSON array
+func (s *Scheduler) Publish(ctx context.Context, deviceID string) error {
+	slots, err := s.List(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(slots)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+
+	if err := s.mqtt.Publish(s.topics.Schedule(deviceID), 1, true, payload); err != nil {
+		return fmt.Errorf("failed to publish schedule: %w", err)
+	}
+	return nil
+}
+
+// publishOrLog publishes the schedule and logs failures
+func (s *Scheduler) publishOrLog(ctx context.Context, deviceID string) {
+	if err := s.Publish(ctx, deviceID); err != nil {
+		logger.Error("Failed to publish schedule: %v", err)
+	}
+}
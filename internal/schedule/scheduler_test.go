@@ -0,0 +1,150 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/homeassistant"
+	"github.com/diogoaguiar/hvac-manager/internal/mocks"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
+)
+
+// fakeTarget records the commands it receives
+type fakeTarget struct {
+	id       string
+	commands []*homeassistant.ClimateCommand
+}
+
+func (f *fakeTarget) ID() string { return f.id }
+
+func (f *fakeTarget) Apply(ctx context.Context, cmd *homeassistant.ClimateCommand) error {
+	f.commands = append(f.commands, cmd)
+	return nil
+}
+
+func setupScheduler(t *testing.T) (*Scheduler, *fakeTarget, *mocks.MockMQTT) {
+	t.Helper()
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(context.Background()); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
+	}
+
+	target := &fakeTarget{id: "living_room"}
+	mqtt := &mocks.MockMQTT{Connected: true}
+	return New(db, mqtt, topics.Default(), target), target, mqtt
+}
+
+func TestScheduler_HandleEditAndCheck(t *testing.T) {
+	s, target, mqtt := setupScheduler(t)
+	ctx := context.Background()
+
+	s.HandleEdit("living_room", []byte(`{"days": "weekdays", "time": "07:00", "mode": "heat", "temperature": 21}`))
+	s.HandleEdit("living_room", []byte(`{"days": "weekdays", "time": "09:00", "mode": "off"}`))
+
+	slots, err := s.List(ctx, "living_room")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(slots) != 2 || !slots[0].Enabled {
+		t.Fatalf("Expected 2 enabled slots, got %+v", slots)
+	}
+
+	// Schedule is published retained after each change
+	last := mqtt.Published[len(mqtt.Published)-1]
+	if last.Topic != "homeassistant/climate/living_room/schedule" || !last.Retained {
+		t.Errorf("Unexpected publish: %+v", last)
+	}
+	var published []database.Schedule
+	if err := json.Unmarshal(last.Payload.([]byte), &published); err != nil || len(published) != 2 {
+		t.Errorf("Expected 2 published slots, got %s", last.Payload)
+	}
+
+	// Wednesday 06:59:30 → 07:00:30 fires the heat slot only
+	wed := time.Date(2024, 1, 10, 6, 59, 30, 0, time.Local)
+	s.Check(ctx, wed)
+	s.Check(ctx, wed.Add(time.Minute))
+	if len(target.commands) != 1 {
+		t.Fatalf("Expected 1 command, got %d", len(target.commands))
+	}
+	cmd := target.commands[0]
+	if cmd.Mode == nil || *cmd.Mode != "heat" || cmd.Temperature == nil || *cmd.Temperature != 21 || cmd.FanMode != nil {
+		t.Errorf("Unexpected command: %+v", cmd)
+	}
+
+	// Same window again does not fire twice
+	s.Check(ctx, wed.Add(2*time.Minute))
+	if len(target.commands) != 1 {
+		t.Errorf("Slot fired twice: %d commands", len(target.commands))
+	}
+}
+
+// TestScheduler_CheckOrder tests that slots due after a gap are applied in the order they fell due
+func TestScheduler_CheckOrder(t *testing.T) {
+	s, target, _ := setupScheduler(t)
+	ctx := context.Background()
+
+	// Added in the opposite order of their times
+	s.HandleEdit("living_room", []byte(`{"days": "weekdays", "time": "09:00", "mode": "off"}`))
+	s.HandleEdit("living_room", []byte(`{"days": "wed", "time": "07:00", "mode": "heat"}`))
+
+	// Tuesday 08:00 → Wednesday 10:00 passes off (Tue 09:00), heat (Wed 07:00), then off (Wed 09:00)
+	tue := time.Date(2024, 1, 9, 8, 0, 0, 0, time.Local)
+	s.Check(ctx, tue)
+	s.Check(ctx, tue.Add(26*time.Hour))
+
+	var modes []string
+	for _, cmd := range target.commands {
+		modes = append(modes, *cmd.Mode)
+	}
+	if len(modes) != 2 || modes[0] != "heat" || modes[1] != "off" {
+		t.Errorf("Expected heat then off, got %v", modes)
+	}
+}
+
+func TestScheduler_ApplyEdit(t *testing.T) {
+	s, _, _ := setupScheduler(t)
+	ctx := context.Background()
+
+	saved, err := s.ApplyEdit(ctx, "living_room", Edit{Schedule: database.Schedule{
+		Days: "sun", Time: "23:00", FanMode: strPtr("low"), Enabled: true,
+	}})
+	if err != nil {
+		t.Fatalf("ApplyEdit failed: %v", err)
+	}
+
+	// Update in place
+	saved.Time = "22:30"
+	if _, err := s.ApplyEdit(ctx, "living_room", Edit{Schedule: *saved}); err != nil {
+		t.Fatalf("ApplyEdit update failed: %v", err)
+	}
+	slots, _ := s.List(ctx, "living_room")
+	if len(slots) != 1 || slots[0].Time != "22:30" {
+		t.Errorf("Expected updated slot, got %+v", slots)
+	}
+
+	// Invalid edits are rejected
+	if _, err := s.ApplyEdit(ctx, "living_room", Edit{Schedule: database.Schedule{Days: "sun", Time: "23:00"}}); err == nil {
+		t.Error("Expected error for slot without command")
+	}
+	if _, err := s.ApplyEdit(ctx, "kitchen", Edit{Schedule: *saved}); err == nil {
+		t.Error("Expected error for unknown device")
+	}
+	if _, err := s.ApplyEdit(ctx, "living_room", Edit{Delete: true}); err == nil {
+		t.Error("Expected error for delete without id")
+	}
+
+	// Delete
+	if _, err := s.ApplyEdit(ctx, "living_room", Edit{Schedule: database.Schedule{ID: saved.ID}, Delete: true}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if slots, _ := s.List(ctx, "living_room"); len(slots) != 0 {
+		t.Errorf("Expected empty schedule, got %+v", slots)
+	}
+}
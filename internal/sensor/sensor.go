@@ -0,0 +1,139 @@
+package sensor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config binds a device to an external room temperature/humidity sensor
+//
+// Two payload styles are supported:
+//   - JSON objects (e.g., Zigbee2MQTT: {"temperature": 23.4, "humidity": 51})
+//     with values selected by a dot-separated path
+//   - Plain numbers (e.g., HA MQTT sensors: "23.4") when the path is empty
+type Config struct {
+	TemperatureTopic string // Topic carrying the temperature reading
+	TemperaturePath  string // JSON path within the payload, e.g., "temperature" or "sensors.0.temp"
+	HumidityTopic    string // Topic carrying the humidity reading (empty = TemperatureTopic)
+	HumidityPath     string // JSON path for humidity (empty with a shared topic = no humidity)
+}
+
+// Reading holds the values extracted from a single sensor message
+// Fields are nil when the message did not contain them
+type Reading struct {
+	Temperature *float64
+	Humidity    *float64
+	Time        time.Time
+}
+
+// Enabled returns true if a sensor topic is configured
+func (c Config) Enabled() bool {
+	return c.TemperatureTopic != ""
+}
+
+// HasHumidity returns true if a humidity source is configured
+func (c Config) HasHumidity() bool {
+	if c.HumidityTopic != "" && c.HumidityTopic != c.TemperatureTopic {
+		return true
+	}
+	return c.HumidityPath != ""
+}
+
+// Topics returns the distinct MQTT topics to subscribe to
+func (c Config) Topics() []string {
+	if !c.Enabled() {
+		return nil
+	}
+	topics := []string{c.TemperatureTopic}
+	if c.HumidityTopic != "" && c.HumidityTopic != c.TemperatureTopic {
+		topics = append(topics, c.HumidityTopic)
+	}
+	return topics
+}
+
+// Parse extracts temperature and/or humidity from a message received on topic
+// Returns an error if the topic carries a value that cannot be read
+func (c Config) Parse(topic string, payload []byte) (Reading, error) {
+	reading := Reading{Time: time.Now()}
+
+	if topic == c.TemperatureTopic {
+		temp, err := extractNumber(payload, c.TemperaturePath)
+		if err != nil {
+			return reading, fmt.Errorf("failed to read temperature from %s: %w", topic, err)
+		}
+		reading.Temperature = &temp
+	}
+
+	humidityTopic := c.HumidityTopic
+	if humidityTopic == "" {
+		humidityTopic = c.TemperatureTopic
+	}
+	if topic == humidityTopic && c.HasHumidity() {
+		humidity, err := extractNumber(payload, c.HumidityPath)
+		switch {
+		case err == nil:
+			reading.Humidity = &humidity
+		case humidityTopic != c.TemperatureTopic:
+			// Dedicated humidity topic must carry a value
+			return reading, fmt.Errorf("failed to read humidity from %s: %w", topic, err)
+		}
+		// Shared topic: humidity is optional (Z2M may omit it in partial updates)
+	}
+
+	return reading, nil
+}
+
+// extractNumber reads a numeric value from payload at the given dot-separated path
+// An empty path treats the whole payload as the value
+func extractNumber(payload []byte, path string) (float64, error) {
+	if path == "" {
+		return toNumber(strings.TrimSpace(string(payload)))
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return 0, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			next, ok := node[key]
+			if !ok {
+				return 0, fmt.Errorf("key %q not found (path %q)", key, path)
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return 0, fmt.Errorf("invalid array index %q (path %q)", key, path)
+			}
+			value = node[index]
+		default:
+			return 0, fmt.Errorf("cannot descend into %T at %q (path %q)", value, key, path)
+		}
+	}
+
+	return toNumber(value)
+}
+
+// toNumber converts a JSON number or numeric string into a float64
+func toNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.Trim(v, `"`), 64)
+		if err != nil {
+			return 0, fmt.Errorf("value %q is not numeric", v)
+		}
+		return f, nil
+	case nil:
+		return 0, fmt.Errorf("value is null")
+	default:
+		return 0, fmt.Errorf("value of type %T is not numeric", v)
+	}
+}
@@ -0,0 +1,123 @@
+package sensor
+
+import (
+	"testing"
+)
+
+func TestParse_Z2MPayload(t *testing.T) {
+	cfg := Config{
+		TemperatureTopic: "zigbee2mqtt/living_room_sensor",
+		TemperaturePath:  "temperature",
+		HumidityPath:     "humidity",
+	}
+
+	reading, err := cfg.Parse(cfg.TemperatureTopic, []byte(`{"temperature": 23.4, "humidity": 51.2, "battery": 90}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reading.Temperature == nil || *reading.Temperature != 23.4 {
+		t.Errorf("Temperature = %v, want 23.4", reading.Temperature)
+	}
+	if reading.Humidity == nil || *reading.Humidity != 51.2 {
+		t.Errorf("Humidity = %v, want 51.2", reading.Humidity)
+	}
+	if reading.Time.IsZero() {
+		t.Error("Expected reading time to be set")
+	}
+}
+
+func TestParse_MissingHumidityOnSharedTopic(t *testing.T) {
+	cfg := Config{
+		TemperatureTopic: "zigbee2mqtt/sensor",
+		TemperaturePath:  "temperature",
+		HumidityPath:     "humidity",
+	}
+
+	reading, err := cfg.Parse(cfg.TemperatureTopic, []byte(`{"temperature": 20}`))
+	if err != nil {
+		t.Fatalf("Humidity should be optional on a shared topic: %v", err)
+	}
+	if reading.Humidity != nil {
+		t.Errorf("Expected nil humidity, got %v", *reading.Humidity)
+	}
+}
+
+func TestParse_PlainHASensors(t *testing.T) {
+	cfg := Config{
+		TemperatureTopic: "homeassistant/sensor/room_temp/state",
+		HumidityTopic:    "homeassistant/sensor/room_humidity/state",
+	}
+
+	if !cfg.HasHumidity() {
+		t.Error("Dedicated humidity topic should enable humidity")
+	}
+	if got := cfg.Topics(); len(got) != 2 {
+		t.Fatalf("Expected 2 topics, got %v", got)
+	}
+
+	reading, err := cfg.Parse(cfg.TemperatureTopic, []byte("22.5\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reading.Temperature == nil || *reading.Temperature != 22.5 {
+		t.Errorf("Temperature = %v, want 22.5", reading.Temperature)
+	}
+	if reading.Humidity != nil {
+		t.Error("Temperature topic should not carry humidity")
+	}
+
+	reading, err = cfg.Parse(cfg.HumidityTopic, []byte("48"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reading.Humidity == nil || *reading.Humidity != 48 {
+		t.Errorf("Humidity = %v, want 48", reading.Humidity)
+	}
+	if reading.Temperature != nil {
+		t.Error("Humidity topic should not carry temperature")
+	}
+
+	if _, err := cfg.Parse(cfg.HumidityTopic, []byte("unavailable")); err == nil {
+		t.Error("Expected error for non-numeric humidity")
+	}
+}
+
+func TestExtractNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		path    string
+		want    float64
+		wantErr bool
+	}{
+		{"Top-level key", `{"temperature": 21.5}`, "temperature", 21.5, false},
+		{"Nested key", `{"sensors": {"room": {"temp": 19}}}`, "sensors.room.temp", 19, false},
+		{"Array index", `{"sensors": [{"temp": 18}, {"temp": 24}]}`, "sensors.1.temp", 24, false},
+		{"Numeric string", `{"temperature": "22.1"}`, "temperature", 22.1, false},
+		{"Plain number", `23`, "", 23, false},
+		{"Plain quoted number", `"23.5"`, "", 23.5, false},
+		{"Missing key", `{"humidity": 40}`, "temperature", 0, true},
+		{"Null value", `{"temperature": null}`, "temperature", 0, true},
+		{"Bad index", `{"sensors": [1]}`, "sensors.3", 0, true},
+		{"Not JSON", `hello`, "temperature", 0, true},
+		{"Boolean", `{"temperature": true}`, "temperature", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractNumber([]byte(tt.payload), tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("extractNumber() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,6 +12,13 @@ type ACState struct {
 	FanMode     string    `json:"fan_mode"`     // auto, low, medium, high
 	Power       bool      `json:"power"`        // true = on, false = off
 	LastUpdated time.Time `json:"last_updated"` // Timestamp of last state change
+	LastError   string    `json:"last_error"`   // Last IR send failure, cleared on next success
+	Preset      string    `json:"preset"`       // Active preset (e.g., "eco"), empty when none
+
+	// Room readings from an external sensor (nil until the first reading)
+	CurrentTemperature *float64  `json:"current_temperature,omitempty"`
+	CurrentHumidity    *float64  `json:"current_humidity,omitempty"`
+	RoomUpdated        time.Time `json:"room_updated,omitempty"` // Timestamp of last sensor reading
 }
 
 // Valid modes for the AC
@@ -20,6 +27,16 @@ var ValidModes = []string{"off", "cool", "heat", "dry", "fan_only", "auto"}
 // Valid fan modes
 var ValidFanModes = []string{"auto", "low", "medium", "high"}
 
+// HVAC actions reported to Home Assistant (hvac_action)
+const (
+	ActionOff     = "off"
+	ActionIdle    = "idle"
+	ActionCooling = "cooling"
+	ActionHeating = "heating"
+	ActionDrying  = "drying"
+	ActionFan     = "fan"
+)
+
 // NewACState creates a new AC state with default values
 func NewACState() *ACState {
 	return &ACState{
@@ -38,6 +55,7 @@ func (s *ACState) SetTemperature(temp float64) error {
 		return fmt.Errorf("temperature %.1f out of range (16-30°C)", temp)
 	}
 	s.Temperature = temp
+	s.Preset = "" // Changing an individual field leaves the preset
 	s.LastUpdated = time.Now()
 	return nil
 }
@@ -48,6 +66,7 @@ func (s *ACState) SetMode(mode string) error {
 		return fmt.Errorf("invalid mode: %s (valid: %v)", mode, ValidModes)
 	}
 	s.Mode = mode
+	s.Preset = "" // Changing an individual field leaves the preset
 	s.Power = mode != "off"
 	s.LastUpdated = time.Now()
 	return nil
@@ -59,10 +78,67 @@ func (s *ACState) SetFanMode(fanMode string) error {
 		return fmt.Errorf("invalid fan mode: %s (valid: %v)", fanMode, ValidFanModes)
 	}
 	s.FanMode = fanMode
+	s.Preset = "" // Changing an individual field leaves the preset
 	s.LastUpdated = time.Now()
 	return nil
 }
 
+// SetPreset marks a preset as active after its fields were applied (empty clears it)
+// Must be called after the setters, which clear the active preset
+func (s *ACState) SetPreset(name string) {
+	s.Preset = name
+	s.LastUpdated = time.Now()
+}
+
+// SetError records a failed IR send without changing the AC settings
+func (s *ACState) SetError(err error) {
+	if err == nil {
+		s.LastError = ""
+		return
+	}
+	s.LastError = err.Error()
+}
+
+// ClearError clears the last error after a successful IR send
+func (s *ACState) ClearError() {
+	s.LastError = ""
+}
+
+// SetRoomReading records values from the room sensor
+// nil values leave the previous reading untouched; LastUpdated is not changed
+// because the AC settings did not change
+func (s *ACState) SetRoomReading(temperature, humidity *float64, at time.Time) {
+	if temperature != nil {
+		t := *temperature
+		s.CurrentTemperature = &t
+	}
+	if humidity != nil {
+		h := *humidity
+		s.CurrentHumidity = &h
+	}
+	s.RoomUpdated = at
+}
+
+// Action derives the current HVAC action from the mode
+// IR units give no feedback, so this assumes the unit is doing what it was told
+func (s *ACState) Action() string {
+	switch s.Mode {
+	case "off":
+		return ActionOff
+	case "cool":
+		return ActionCooling
+	case "heat":
+		return ActionHeating
+	case "dry":
+		return ActionDrying
+	case "fan_only":
+		return ActionFan
+	default:
+		// auto: unknown whether the unit is heating or cooling
+		return ActionIdle
+	}
+}
+
 // isValidMode checks if the mode is in the valid list
 func isValidMode(mode string) bool {
 	for _, valid := range ValidModes {
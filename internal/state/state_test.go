@@ -1,7 +1,9 @@
 package state
 
 import (
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestNewACState(t *testing.T) {
@@ -232,3 +234,126 @@ func TestPowerStateCorrelation(t *testing.T) {
 		t.Error("Power should be true when mode is 'heat'")
 	}
 }
+
+func TestAction(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"off", ActionOff},
+		{"cool", ActionCooling},
+		{"heat", ActionHeating},
+		{"dry", ActionDrying},
+		{"fan_only", ActionFan},
+		{"auto", ActionIdle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			s := NewACState()
+			if err := s.SetMode(tt.mode); err != nil {
+				t.Fatalf("SetMode failed: %v", err)
+			}
+			if got := s.Action(); got != tt.want {
+				t.Errorf("Action() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorLifecycle(t *testing.T) {
+	s := NewACState()
+
+	if s.LastError != "" {
+		t.Errorf("New state should have no error, got %q", s.LastError)
+	}
+
+	s.SetError(errors.New("MQTT client not connected"))
+	if s.LastError != "MQTT client not connected" {
+		t.Errorf("LastError = %q, want %q", s.LastError, "MQTT client not connected")
+	}
+
+	s.ClearError()
+	if s.LastError != "" {
+		t.Errorf("LastError should be cleared, got %q", s.LastError)
+	}
+
+	s.SetError(errors.New("boom"))
+	s.SetError(nil)
+	if s.LastError != "" {
+		t.Errorf("SetError(nil) should clear the error, got %q", s.LastError)
+	}
+}
+
+func TestPresetClearedByFieldChange(t *testing.T) {
+	setters := map[string]func(s *ACState) error{
+		"temperature": func(s *ACState) error { return s.SetTemperature(24) },
+		"mode":        func(s *ACState) error { return s.SetMode("heat") },
+		"fan mode":    func(s *ACState) error { return s.SetFanMode("high") },
+	}
+
+	for name, set := range setters {
+		t.Run(name, func(t *testing.T) {
+			s := NewACState()
+			s.SetPreset("eco")
+			if s.Preset != "eco" {
+				t.Fatalf("Preset = %q, want eco", s.Preset)
+			}
+			if err := set(s); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if s.Preset != "" {
+				t.Errorf("Preset should be cleared after changing %s, got %q", name, s.Preset)
+			}
+		})
+	}
+
+	// Invalid values do not touch the preset
+	s := NewACState()
+	s.SetPreset("sleep")
+	if err := s.SetTemperature(50); err == nil {
+		t.Fatal("Expected error")
+	}
+	if s.Preset != "sleep" {
+		t.Errorf("Preset should survive a rejected change, got %q", s.Preset)
+	}
+}
+
+func TestSetRoomReading(t *testing.T) {
+	s := NewACState()
+	oldUpdated := s.LastUpdated
+
+	temp := 24.5
+	humidity := 55.0
+	now := time.Now()
+	s.SetRoomReading(&temp, &humidity, now)
+
+	if s.CurrentTemperature == nil || *s.CurrentTemperature != 24.5 {
+		t.Errorf("CurrentTemperature = %v, want 24.5", s.CurrentTemperature)
+	}
+	if s.CurrentHumidity == nil || *s.CurrentHumidity != 55.0 {
+		t.Errorf("CurrentHumidity = %v, want 55.0", s.CurrentHumidity)
+	}
+	if !s.RoomUpdated.Equal(now) {
+		t.Errorf("RoomUpdated = %v, want %v", s.RoomUpdated, now)
+	}
+	if !s.LastUpdated.Equal(oldUpdated) {
+		t.Error("Room readings should not change LastUpdated")
+	}
+
+	// Copies, not aliases
+	temp = 99
+	if *s.CurrentTemperature != 24.5 {
+		t.Error("CurrentTemperature should not alias the caller's variable")
+	}
+
+	// Temperature-only reading keeps the previous humidity
+	newTemp := 23.0
+	s.SetRoomReading(&newTemp, nil, now)
+	if *s.CurrentTemperature != 23.0 {
+		t.Errorf("CurrentTemperature = %.1f, want 23.0", *s.CurrentTemperature)
+	}
+	if s.CurrentHumidity == nil || *s.CurrentHumidity != 55.0 {
+		t.Error("Humidity should be kept when the reading omits it")
+	}
+}
@@ -0,0 +1,202 @@
+package thermostat
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/state"
+)
+
+// Strategy selects how the controller holds the target temperature
+type Strategy string
+
+const (
+	// StrategyOff leaves temperature control to the AC's own thermostat
+	StrategyOff Strategy = "off"
+
+	// StrategySetpoint nudges the IR setpoint up/down until the room sensor reaches the target
+	StrategySetpoint Strategy = "setpoint"
+
+	// StrategyCycle switches between the active mode and fan_only around the target
+	StrategyCycle Strategy = "cycle"
+)
+
+// Limits of the IR setpoint (matches state.SetTemperature validation)
+const (
+	minSetpoint = 16.0
+	maxSetpoint = 30.0
+)
+
+// Config holds the closed-loop control parameters
+type Config struct {
+	Strategy       Strategy
+	Hysteresis     float64       // Dead band around the target in °C (e.g., 0.5)
+	MinOnTime      time.Duration // Minimum compressor run time before cycling off
+	MinOffTime     time.Duration // Minimum compressor rest time before cycling on
+	AdjustInterval time.Duration // Minimum time between setpoint adjustments
+	MaxOffset      float64       // Maximum setpoint offset from the target in °C
+	Interval       time.Duration // How often the control loop evaluates the room
+	MaxSensorAge   time.Duration // Readings older than this disable control
+}
+
+// DefaultConfig returns conservative defaults suitable for most rooms
+func DefaultConfig() Config {
+	return Config{
+		Strategy:       StrategyOff,
+		Hysteresis:     0.5,
+		MinOnTime:      5 * time.Minute,
+		MinOffTime:     5 * time.Minute,
+		AdjustInterval: 10 * time.Minute,
+		MaxOffset:      3.0,
+		Interval:       30 * time.Second,
+		MaxSensorAge:   15 * time.Minute,
+	}
+}
+
+// ParseStrategy converts a configuration string into a Strategy
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case "", StrategyOff:
+		return StrategyOff, nil
+	case StrategySetpoint, StrategyCycle:
+		return Strategy(s), nil
+	}
+	return StrategyOff, fmt.Errorf("invalid thermostat strategy: %s (valid: off, setpoint, cycle)", s)
+}
+
+// Enabled returns true if closed-loop control is active
+func (c Config) Enabled() bool {
+	return c.Strategy == StrategySetpoint || c.Strategy == StrategyCycle
+}
+
+// Controller computes the IR state needed to hold the HA target temperature
+// It is not safe for concurrent use; the owning device serialises calls
+type Controller struct {
+	cfg Config
+
+	active     bool      // Controller is currently overriding the target state
+	mode       string    // Target mode the controller was activated for
+	running    bool      // Cycle: compressor running (active mode) vs fan_only
+	lastSwitch time.Time // Cycle: time of last on/off transition
+	offset     float64   // Setpoint: degrees beyond the target (positive = more aggressive)
+	lastAdjust time.Time // Setpoint: time of last offset change
+}
+
+// NewController creates a controller with the given configuration
+func NewController(cfg Config) *Controller {
+	return &Controller{cfg: cfg}
+}
+
+// Config returns the controller configuration
+func (c *Controller) Config() Config {
+	return c.cfg
+}
+
+// Reset drops any control history, e.g., after a manual command
+func (c *Controller) Reset() {
+	*c = Controller{cfg: c.cfg}
+}
+
+// Evaluate returns the state that should be sent to the AC to hold target
+// Falls back to target unchanged when control does not apply: strategy off,
+// mode other than cool/heat, or no fresh room reading
+func (c *Controller) Evaluate(target state.ACState, now time.Time) state.ACState {
+	if !c.applies(target, now) {
+		if c.active {
+			c.Reset()
+		}
+		return target
+	}
+
+	// Positive error = room needs more of the active mode
+	room := *target.CurrentTemperature
+	errC := room - target.Temperature
+	if target.Mode == "heat" {
+		errC = target.Temperature - room
+	}
+
+	if !c.active || c.mode != target.Mode {
+		c.Reset()
+		c.active = true
+		c.mode = target.Mode
+		c.running = errC > -c.cfg.Hysteresis
+		c.lastSwitch = now // Activation counts as a transition for min on/off times
+	}
+
+	switch c.cfg.Strategy {
+	case StrategyCycle:
+		return c.evaluateCycle(target, errC, now)
+	default:
+		return c.evaluateSetpoint(target, errC, now)
+	}
+}
+
+// applies reports whether closed-loop control can run for this state
+func (c *Controller) applies(target state.ACState, now time.Time) bool {
+	if !c.cfg.Enabled() {
+		return false
+	}
+	if target.Mode != "cool" && target.Mode != "heat" {
+		return false
+	}
+	if target.CurrentTemperature == nil {
+		return false
+	}
+	if c.cfg.MaxSensorAge > 0 && now.Sub(target.RoomUpdated) > c.cfg.MaxSensorAge {
+		return false
+	}
+	return true
+}
+
+// evaluateCycle switches between the active mode and fan_only with hysteresis
+// and minimum on/off times to protect the compressor
+func (c *Controller) evaluateCycle(target state.ACState, errC float64, now time.Time) state.ACState {
+	elapsed := now.Sub(c.lastSwitch)
+
+	if c.running && errC <= -c.cfg.Hysteresis && elapsed >= c.cfg.MinOnTime {
+		c.running = false
+		c.lastSwitch = now
+	} else if !c.running && errC >= c.cfg.Hysteresis && elapsed >= c.cfg.MinOffTime {
+		c.running = true
+		c.lastSwitch = now
+	}
+
+	effective := target
+	if !c.running {
+		effective.Mode = "fan_only"
+	}
+	return effective
+}
+
+// evaluateSetpoint moves the IR setpoint one degree at a time beyond (or short of)
+// the target until the room sensor is within the hysteresis band
+func (c *Controller) evaluateSetpoint(target state.ACState, errC float64, now time.Time) state.ACState {
+	canAdjust := c.lastAdjust.IsZero() || now.Sub(c.lastAdjust) >= c.cfg.AdjustInterval
+
+	if canAdjust {
+		switch {
+		case errC >= c.cfg.Hysteresis && c.offset < c.cfg.MaxOffset:
+			c.offset = math.Min(c.offset+1, c.cfg.MaxOffset)
+			c.lastAdjust = now
+		case errC <= -c.cfg.Hysteresis && c.offset > -c.cfg.MaxOffset:
+			c.offset = math.Max(c.offset-1, -c.cfg.MaxOffset)
+			c.lastAdjust = now
+		}
+	}
+
+	effective := target
+	if target.Mode == "cool" {
+		effective.Temperature = target.Temperature - c.offset
+	} else {
+		effective.Temperature = target.Temperature + c.offset
+	}
+	effective.Temperature = math.Max(minSetpoint, math.Min(maxSetpoint, effective.Temperature))
+	return effective
+}
+
+// Running reports whether the compressor is meant to be running
+// Always true unless the cycle strategy has switched to fan_only
+func (c *Controller) Running() bool {
+	return !c.active || c.cfg.Strategy != StrategyCycle || c.running
+}
@@ -0,0 +1,213 @@
+package thermostat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/state"
+)
+
+// targetState builds a target with a fresh room reading
+func targetState(mode string, target, room float64, now time.Time) state.ACState {
+	s := state.NewACState()
+	s.SetMode(mode)
+	s.SetTemperature(target)
+	s.SetRoomReading(&room, nil, now)
+	return *s
+}
+
+func cycleConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Strategy = StrategyCycle
+	return cfg
+}
+
+func setpointConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Strategy = StrategySetpoint
+	return cfg
+}
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Strategy
+		wantErr bool
+	}{
+		{"", StrategyOff, false},
+		{"off", StrategyOff, false},
+		{"setpoint", StrategySetpoint, false},
+		{"cycle", StrategyCycle, false},
+		{"pid", StrategyOff, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseStrategy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStrategy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStrategy(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate_NotApplicable(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		cfg    Config
+		target state.ACState
+	}{
+		{"Strategy off", DefaultConfig(), targetState("cool", 22, 26, now)},
+		{"Fan only mode", cycleConfig(), targetState("fan_only", 22, 26, now)},
+		{"Off mode", cycleConfig(), targetState("off", 22, 26, now)},
+		{"Stale reading", cycleConfig(), targetState("cool", 22, 26, now.Add(-time.Hour))},
+		{"No reading", cycleConfig(), *state.NewACState()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewController(tt.cfg)
+			got := c.Evaluate(tt.target, now)
+			if got.Mode != tt.target.Mode || got.Temperature != tt.target.Temperature {
+				t.Errorf("Expected target unchanged, got %s", got.String())
+			}
+			if !c.Running() {
+				t.Error("Inactive controller should report running")
+			}
+		})
+	}
+}
+
+func TestEvaluate_CycleCooling(t *testing.T) {
+	c := NewController(cycleConfig())
+	start := time.Now()
+
+	// Room too warm: keep cooling
+	got := c.Evaluate(targetState("cool", 22, 24, start), start)
+	if got.Mode != "cool" {
+		t.Fatalf("Expected cool while room is warm, got %s", got.Mode)
+	}
+
+	// Room overshoots below target, but min on-time not reached yet
+	t1 := start.Add(time.Minute)
+	got = c.Evaluate(targetState("cool", 22, 21.2, t1), t1)
+	if got.Mode != "cool" {
+		t.Errorf("Expected cool until min on-time, got %s", got.Mode)
+	}
+
+	// Min on-time reached: switch to fan_only
+	t2 := start.Add(6 * time.Minute)
+	got = c.Evaluate(targetState("cool", 22, 21.2, t2), t2)
+	if got.Mode != "fan_only" {
+		t.Fatalf("Expected fan_only below target, got %s", got.Mode)
+	}
+	if c.Running() {
+		t.Error("Compressor should be reported as not running")
+	}
+
+	// Within the hysteresis band: hold fan_only
+	t3 := t2.Add(10 * time.Minute)
+	got = c.Evaluate(targetState("cool", 22, 22.3, t3), t3)
+	if got.Mode != "fan_only" {
+		t.Errorf("Expected fan_only within hysteresis, got %s", got.Mode)
+	}
+
+	// Warm again, but min off-time not reached since the last switch
+	t4 := t2.Add(2 * time.Minute)
+	c2 := *c
+	c2.lastSwitch = t2
+	if got := c2.Evaluate(targetState("cool", 22, 23, t4), t4); got.Mode != "fan_only" {
+		t.Errorf("Expected fan_only until min off-time, got %s", got.Mode)
+	}
+
+	// Min off-time reached: cool again
+	t5 := t3.Add(time.Minute)
+	got = c.Evaluate(targetState("cool", 22, 23, t5), t5)
+	if got.Mode != "cool" {
+		t.Errorf("Expected cool after min off-time, got %s", got.Mode)
+	}
+}
+
+func TestEvaluate_CycleHeating(t *testing.T) {
+	c := NewController(cycleConfig())
+	now := time.Now()
+
+	// Room already above the heating target: start idle
+	got := c.Evaluate(targetState("heat", 21, 22, now), now)
+	if got.Mode != "fan_only" {
+		t.Errorf("Expected fan_only when room is warm enough, got %s", got.Mode)
+	}
+
+	// Room cools down: heat again once the minimum off-time has passed
+	later := now.Add(6 * time.Minute)
+	got = c.Evaluate(targetState("heat", 21, 20, later), later)
+	if got.Mode != "heat" {
+		t.Errorf("Expected heat when room is cold, got %s", got.Mode)
+	}
+}
+
+func TestEvaluate_SetpointCooling(t *testing.T) {
+	c := NewController(setpointConfig())
+	start := time.Now()
+
+	// Room 2°C above target: lower IR setpoint by one degree
+	got := c.Evaluate(targetState("cool", 24, 26, start), start)
+	if got.Temperature != 23 {
+		t.Fatalf("Expected IR setpoint 23, got %.1f", got.Temperature)
+	}
+	if got.Mode != "cool" {
+		t.Errorf("Setpoint strategy should keep the mode, got %s", got.Mode)
+	}
+
+	// Too soon for another adjustment
+	t1 := start.Add(time.Minute)
+	got = c.Evaluate(targetState("cool", 24, 26, t1), t1)
+	if got.Temperature != 23 {
+		t.Errorf("Expected setpoint held at 23, got %.1f", got.Temperature)
+	}
+
+	// Keep adjusting up to MaxOffset
+	at := start
+	for i := 0; i < 5; i++ {
+		at = at.Add(11 * time.Minute)
+		got = c.Evaluate(targetState("cool", 24, 26, at), at)
+	}
+	if got.Temperature != 21 {
+		t.Errorf("Expected setpoint capped at target-MaxOffset (21), got %.1f", got.Temperature)
+	}
+
+	// Room overshoots: back off
+	at = at.Add(11 * time.Minute)
+	got = c.Evaluate(targetState("cool", 24, 23, at), at)
+	if got.Temperature != 22 {
+		t.Errorf("Expected setpoint raised to 22, got %.1f", got.Temperature)
+	}
+}
+
+func TestEvaluate_SetpointClampsToACRange(t *testing.T) {
+	c := NewController(setpointConfig())
+	now := time.Now()
+
+	got := c.Evaluate(targetState("heat", 30, 25, now), now)
+	if got.Temperature != 30 {
+		t.Errorf("Expected setpoint clamped at 30, got %.1f", got.Temperature)
+	}
+}
+
+func TestEvaluate_ModeChangeResets(t *testing.T) {
+	c := NewController(setpointConfig())
+	now := time.Now()
+
+	c.Evaluate(targetState("cool", 24, 26, now), now)
+
+	// Switching to heat starts from a clean offset
+	got := c.Evaluate(targetState("heat", 24, 24, now), now)
+	if got.Temperature != 24 {
+		t.Errorf("Expected fresh offset after mode change, got %.1f", got.Temperature)
+	}
+}
@@ -0,0 +1,354 @@
+package timer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/audit"
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/homeassistant"
+	"github.com/diogoaguiar/hvac-manager/internal/interfaces"
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
+)
+
+// DefaultInterval is how often pending timers are checked and their remaining time published
+const DefaultInterval = 15 * time.Second
+
+// MaxDuration is the longest timer that can be set
+const MaxDuration = 24 * time.Hour
+
+// Store persists pending timers (implemented by *database.DB)
+type Store interface {
+	GetTimer(ctx context.Context, deviceID string) (*database.Timer, error)
+	SaveTimer(ctx context.Context, t *database.Timer) error
+	DeleteTimer(ctx context.Context, deviceID string) error
+}
+
+// Target receives the command when a timer fires (implemented by *device.Device)
+type Target interface {
+	ID() string
+	Apply(ctx context.Context, cmd *homeassistant.ClimateCommand) error
+}
+
+// Request is a timer command received over MQTT or HTTP
+// A plain number of minutes turns the device off after the delay;
+// JSON may also carry the target state to apply instead
+type Request struct {
+	Minutes     float64  `json:"minutes"`
+	Mode        *string  `json:"mode,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	FanMode     *string  `json:"fan_mode,omitempty"`
+	Cancel      bool     `json:"cancel,omitempty"`
+}
+
+// Status is the timer state published for Home Assistant
+type Status struct {
+	Remaining int    `json:"remaining"` // Whole minutes left, rounded up (0 = no timer)
+	FiresAt   string `json:"fires_at"`  // RFC 3339 timestamp, empty without a timer
+	Action    string `json:"action"`    // "off" or "set" (target state), empty without a timer
+}
+
+// ParseRequest parses a timer payload: minutes ("120"), "cancel", or a JSON Request
+// Zero minutes cancels the pending timer
+func ParseRequest(payload []byte) (Request, error) {
+	var req Request
+	text := strings.TrimSpace(string(payload))
+
+	switch {
+	case strings.EqualFold(text, "cancel"):
+		req.Cancel = true
+	case strings.HasPrefix(text, "{"):
+		if err := json.Unmarshal([]byte(text), &req); err != nil {
+			return req, fmt.Errorf("failed to parse timer command: %w", err)
+		}
+	default:
+		minutes, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return req, fmt.Errorf("could not parse timer command: %s", text)
+		}
+		req.Minutes = minutes
+	}
+
+	if req.Minutes < 0 {
+		return req, fmt.Errorf("timer minutes must not be negative: %g", req.Minutes)
+	}
+	if req.Minutes == 0 {
+		req.Cancel = true
+	}
+	return req, nil
+}
+
+// Command returns the command applied when a timer fires
+func Command(t database.Timer) *homeassistant.ClimateCommand {
+	if t.Mode == nil && t.Temperature == nil && t.FanMode == nil {
+		off := "off"
+		return &homeassistant.ClimateCommand{Mode: &off}
+	}
+	return &homeassistant.ClimateCommand{
+		Temperature: t.Temperature,
+		Mode:        t.Mode,
+		FanMode:     t.FanMode,
+	}
+}
+
+// Manager runs the sleep/off-delay timers of a set of devices
+// Pending timers are persisted so they survive restarts
+type Manager struct {
+	mu sync.Mutex
+
+	store     Store
+	mqtt      interfaces.MQTTPublisher
+	topics    topics.Builder
+	targets   map[string]Target
+	pending   map[string]*database.Timer
+	published map[string]int // Last published remaining minutes per device
+}
+
+// New creates a timer manager for the given devices
+func New(store Store, mqtt interfaces.MQTTPublisher, t topics.Builder, targets ...Target) *Manager {
+	m := &Manager{
+		store:     store,
+		mqtt:      mqtt,
+		topics:    t,
+		targets:   make(map[string]Target),
+		pending:   make(map[string]*database.Timer),
+		published: make(map[string]int),
+	}
+	for _, target := range targets {
+		m.targets[target.ID()] = target
+	}
+	return m
+}
+
+// Restore loads pending timers from the store and publishes their status
+// Timers that expired while the service was down fire on the next Check
+func (m *Manager) Restore(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id := range m.targets {
+		t, err := m.store.GetTimer(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to restore timer for %s: %w", id, err)
+		}
+		if t != nil {
+			m.pending[id] = t
+			logger.Info("⏲️  Restored timer for %s: fires at %s", id, t.FiresAt.Format(time.RFC3339))
+		}
+		m.publishOrLog(id, now)
+	}
+	return nil
+}
+
+// Start sets (or replaces) the timer of a device
+func (m *Manager) Start(ctx context.Context, deviceID string, req Request, now time.Time) (*database.Timer, error) {
+	if _, ok := m.targets[deviceID]; !ok {
+		return nil, fmt.Errorf("unknown device: %s", deviceID)
+	}
+
+	delay := time.Duration(req.Minutes * float64(time.Minute))
+	if delay < time.Minute || delay > MaxDuration {
+		return nil, fmt.Errorf("timer must be between 1 minute and %s, got %g minutes", MaxDuration, req.Minutes)
+	}
+
+	t := &database.Timer{
+		DeviceID:    deviceID,
+		FiresAt:     now.Add(delay).Truncate(time.Second),
+		Mode:        req.Mode,
+		Temperature: req.Temperature,
+		FanMode:     req.FanMode,
+	}
+	if err := Command(*t).Validate(); err != nil {
+		return nil, fmt.Errorf("invalid timer target: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.store.SaveTimer(ctx, t); err != nil {
+		return nil, err
+	}
+	m.pending[deviceID] = t
+
+	logger.Info("⏲️  Timer set for %s: %s in %s", deviceID, describe(*t), delay)
+	m.publishOrLog(deviceID, now)
+	return t, nil
+}
+
+// Cancel removes the pending timer of a device (no-op if none is set)
+func (m *Manager) Cancel(ctx context.Context, deviceID string) error {
+	if _, ok := m.targets[deviceID]; !ok {
+		return fmt.Errorf("unknown device: %s", deviceID)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.store.DeleteTimer(ctx, deviceID); err != nil {
+		return err
+	}
+	if _, ok := m.pending[deviceID]; ok {
+		delete(m.pending, deviceID)
+		logger.Info("⏲️  Timer cancelled for %s", deviceID)
+	}
+
+	m.publishOrLog(deviceID, time.Now())
+	return nil
+}
+
+// Status returns the timer status of a device
+func (m *Manager) Status(deviceID string, now time.Time) Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status(deviceID, now)
+}
+
+// status builds the status; caller must hold m.mu
+func (m *Manager) status(deviceID string, now time.Time) Status {
+	t, ok := m.pending[deviceID]
+	if !ok {
+		return Status{}
+	}
+
+	remaining := int(math.Ceil(t.FiresAt.Sub(now).Minutes()))
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Status{
+		Remaining: remaining,
+		FiresAt:   t.FiresAt.Format(time.RFC3339),
+		Action:    describe(*t),
+	}
+}
+
+// Check fires due timers and publishes the remaining time when it changes
+func (m *Manager) Check(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	var due []database.Timer
+	for id, t := range m.pending {
+		if t.FiresAt.After(now) {
+			if m.published[id] != m.status(id, now).Remaining {
+				m.publishOrLog(id, now)
+			}
+			continue
+		}
+
+		// Drop the timer before firing so a failing command is not retried forever
+		if err := m.store.DeleteTimer(ctx, id); err != nil {
+			logger.Error("Failed to delete fired timer for %s: %v", id, err)
+		}
+		delete(m.pending, id)
+		due = append(due, *t)
+		m.publishOrLog(id, now)
+	}
+	m.mu.Unlock()
+
+	// Apply outside the lock: the command path may take a while
+	for _, t := range due {
+		timerCtx := logger.WithContext(audit.WithSource(ctx, audit.SourceTimer),
+			logger.FieldCorrelationID, logger.NewCorrelationID())
+		log := logger.FromContext(timerCtx)
+		log.Info("⏲️  Timer fired for %s: %s", t.DeviceID, describe(t))
+		if err := m.targets[t.DeviceID].Apply(timerCtx, Command(t)); err != nil {
+			log.Error("❌ Timer command failed for %s: %v", t.DeviceID, err)
+		}
+	}
+}
+
+// Run checks pending timers every interval until ctx is cancelled
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.Check(ctx, now)
+		}
+	}
+}
+
+// HandleRequest processes a timer command received over MQTT
+func (m *Manager) HandleRequest(deviceID string, payload []byte) {
+	logger.Info("📥 Received timer command for %s: %s", deviceID, string(payload))
+
+	req, err := ParseRequest(payload)
+	if err != nil {
+		logger.Error("%v", err)
+		return
+	}
+
+	if req.Cancel {
+		err = m.Cancel(context.Background(), deviceID)
+	} else {
+		_, err = m.Start(context.Background(), deviceID, req, time.Now())
+	}
+	if err != nil {
+		logger.Error("❌ Timer command failed: %v", err)
+	}
+}
+
+// PublishDiscovery publishes the HA Number entity showing and setting the timer
+func (m *Manager) PublishDiscovery(deviceID string, deviceName string) error {
+	discovery := homeassistant.NewSleepTimerDiscovery(m.topics, deviceID, deviceName)
+	payload, err := discovery.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal timer discovery: %w", err)
+	}
+
+	topic := discovery.ConfigTopic()
+	if err := m.mqtt.Publish(topic, 2, true, payload); err != nil {
+		return fmt.Errorf("failed to publish timer discovery: %w", err)
+	}
+
+	logger.Info("✅ Published discovery to: %s", topic)
+	return nil
+}
+
+// Publish publishes the timer status of a device (retained)
+func (m *Manager) Publish(deviceID string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.publish(deviceID, now)
+}
+
+// publish publishes the status; caller must hold m.mu
+func (m *Manager) publish(deviceID string, now time.Time) error {
+	status := m.status(deviceID, now)
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to marshal timer status: %w", err)
+	}
+
+	if err := m.mqtt.Publish(m.topics.Timer(deviceID), 1, true, payload); err != nil {
+		return fmt.Errorf("failed to publish timer status: %w", err)
+	}
+	m.published[deviceID] = status.Remaining
+	return nil
+}
+
+// publishOrLog publishes the status and logs failures; caller must hold m.mu
+func (m *Manager) publishOrLog(deviceID string, now time.Time) {
+	if err := m.publish(deviceID, now); err != nil {
+		logger.Error("%v", err)
+	}
+}
+
+// describe returns "off" for off timers and "set" for timers with a target state
+func describe(t database.Timer) string {
+	cmd := Command(t)
+	if cmd.Mode != nil && *cmd.Mode == "off" && cmd.Temperature == nil && cmd.FanMode == nil {
+		return "off"
+	}
+	return "set"
+}
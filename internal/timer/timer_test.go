@@ -0,0 +1,190 @@
+package timer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/homeassistant"
+	"github.com/diogoaguiar/hvac-manager/internal/mocks"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
+)
+
+// fakeTarget records the commands it receives
+type fakeTarget struct {
+	id       string
+	commands []*homeassistant.ClimateCommand
+}
+
+func (f *fakeTarget) ID() string { return f.id }
+
+func (f *fakeTarget) Apply(ctx context.Context, cmd *homeassistant.ClimateCommand) error {
+	f.commands = append(f.commands, cmd)
+	return nil
+}
+
+func setupDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(context.Background()); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
+	}
+	return db
+}
+
+// lastStatus returns the last status published to the timer topic
+func lastStatus(t *testing.T, m *mocks.MockMQTT) Status {
+	t.Helper()
+	for i := len(m.Published) - 1; i >= 0; i-- {
+		if m.Published[i].Topic != "homeassistant/climate/living_room/timer" {
+			continue
+		}
+		var s Status
+		if err := json.Unmarshal(m.Published[i].Payload.([]byte), &s); err != nil {
+			t.Fatalf("Invalid status payload: %v", err)
+		}
+		return s
+	}
+	t.Fatal("No timer status published")
+	return Status{}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		payload    string
+		minutes    float64
+		cancel     bool
+		withTarget bool
+		wantErr    bool
+	}{
+		{"120", 120, false, false, false},
+		{"0", 0, true, false, false},
+		{"cancel", 0, true, false, false},
+		{`{"minutes": 90, "mode": "fan_only"}`, 90, false, true, false},
+		{`{"cancel": true}`, 0, true, false, false},
+		{"-5", 0, false, false, true},
+		{"soon", 0, false, false, true},
+		{`{"minutes": "ten"}`, 0, false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.payload, func(t *testing.T) {
+			req, err := ParseRequest([]byte(tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRequest(%q) error = %v, wantErr %v", tt.payload, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if req.Minutes != tt.minutes || req.Cancel != tt.cancel || (req.Mode != nil) != tt.withTarget {
+				t.Errorf("ParseRequest(%q) = %+v", tt.payload, req)
+			}
+		})
+	}
+}
+
+func TestManager_OffTimer(t *testing.T) {
+	db := setupDB(t)
+	target := &fakeTarget{id: "living_room"}
+	mqtt := &mocks.MockMQTT{Connected: true}
+	m := New(db, mqtt, topics.Default(), target)
+	ctx := context.Background()
+	start := time.Now()
+
+	if _, err := m.Start(ctx, "living_room", Request{Minutes: 120}, start); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if status := lastStatus(t, mqtt); status.Remaining != 120 || status.Action != "off" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	// Remaining time is republished as it counts down
+	m.Check(ctx, start.Add(30*time.Minute))
+	if status := lastStatus(t, mqtt); status.Remaining != 90 {
+		t.Errorf("Expected 90 minutes remaining, got %+v", status)
+	}
+	if len(target.commands) != 0 {
+		t.Fatal("Timer fired early")
+	}
+
+	// Fires the off command once
+	m.Check(ctx, start.Add(121*time.Minute))
+	m.Check(ctx, start.Add(122*time.Minute))
+	if len(target.commands) != 1 {
+		t.Fatalf("Expected 1 command, got %d", len(target.commands))
+	}
+	if cmd := target.commands[0]; cmd.Mode == nil || *cmd.Mode != "off" {
+		t.Errorf("Expected off command, got %+v", cmd)
+	}
+	if status := lastStatus(t, mqtt); status.Remaining != 0 || status.FiresAt != "" {
+		t.Errorf("Expected cleared status, got %+v", status)
+	}
+	if stored, _ := db.GetTimer(ctx, "living_room"); stored != nil {
+		t.Errorf("Fired timer should be removed from the store, got %+v", stored)
+	}
+}
+
+func TestManager_TargetStateAndCancel(t *testing.T) {
+	db := setupDB(t)
+	target := &fakeTarget{id: "living_room"}
+	m := New(db, &mocks.MockMQTT{Connected: true}, topics.Default(), target)
+	ctx := context.Background()
+
+	mode := "fan_only"
+	if _, err := m.Start(ctx, "living_room", Request{Minutes: 30, Mode: &mode}, time.Now()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if status := m.Status("living_room", time.Now()); status.Action != "set" {
+		t.Errorf("Expected set action, got %+v", status)
+	}
+
+	if err := m.Cancel(ctx, "living_room"); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	m.Check(ctx, time.Now().Add(time.Hour))
+	if len(target.commands) != 0 {
+		t.Errorf("Cancelled timer fired: %+v", target.commands)
+	}
+
+	// Invalid requests
+	bad := "turbo"
+	if _, err := m.Start(ctx, "living_room", Request{Minutes: 30, Mode: &bad}, time.Now()); err == nil {
+		t.Error("Expected error for invalid target mode")
+	}
+	if _, err := m.Start(ctx, "living_room", Request{Minutes: 48 * 60}, time.Now()); err == nil {
+		t.Error("Expected error for timer longer than MaxDuration")
+	}
+	if _, err := m.Start(ctx, "living_room", Request{Minutes: 0.1}, time.Now()); err == nil {
+		t.Error("Expected error for timer shorter than a minute")
+	}
+	if _, err := m.Start(ctx, "kitchen", Request{Minutes: 30}, time.Now()); err == nil {
+		t.Error("Expected error for unknown device")
+	}
+}
+
+func TestManager_RestoreAfterRestart(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+
+	// Timer set before the restart, expired while the service was down
+	if err := db.SaveTimer(ctx, &database.Timer{DeviceID: "living_room", FiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("SaveTimer failed: %v", err)
+	}
+
+	target := &fakeTarget{id: "living_room"}
+	m := New(db, &mocks.MockMQTT{Connected: true}, topics.Default(), target)
+	if err := m.Restore(ctx); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	m.Check(ctx, time.Now())
+	if len(target.commands) != 1 {
+		t.Errorf("Expected restored timer to fire, got %d commands", len(target.commands))
+	}
+}
@@ -0,0 +1,157 @@
+package topics
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// DefaultDiscoveryPrefix is Home Assistant's default MQTT discovery prefix
+	DefaultDiscoveryPrefix = "homeassistant"
+
+	// DefaultZ2MBaseTopic is Zigbee2MQTT's default base_topic
+	DefaultZ2MBaseTopic = "zigbee2mqtt"
+
+	// DefaultBaseTopic is the base for our own state/command topics
+	// Kept inside the discovery namespace for backward compatibility
+	DefaultBaseTopic = "homeassistant/climate"
+)
+
+// Builder constructs every MQTT topic used by the service
+// The zero value is valid and uses the default prefixes
+type Builder struct {
+	DiscoveryPrefix string // e.g., "homeassistant"
+	Z2MBaseTopic    string // e.g., "zigbee2mqtt"
+	BaseTopic       string // e.g., "hvac-manager"
+}
+
+// New creates a topic builder, falling back to defaults for empty values
+// Leading and trailing slashes are stripped from each prefix
+func New(discoveryPrefix, z2mBaseTopic, baseTopic string) Builder {
+	return Builder{
+		DiscoveryPrefix: withDefault(discoveryPrefix, DefaultDiscoveryPrefix),
+		Z2MBaseTopic:    withDefault(z2mBaseTopic, DefaultZ2MBaseTopic),
+		BaseTopic:       withDefault(baseTopic, DefaultBaseTopic),
+	}
+}
+
+// Default returns a topic builder with all default prefixes
+func Default() Builder {
+	return New("", "", "")
+}
+
+// withDefault trims slashes from value and returns fallback if nothing is left
+func withDefault(value, fallback string) string {
+	value = strings.Trim(strings.TrimSpace(value), "/")
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// DiscoveryConfig returns the HA discovery config topic for an entity
+// e.g., "homeassistant/climate/living_room/config"
+func (b Builder) DiscoveryConfig(component, objectID string) string {
+	return fmt.Sprintf("%s/%s/%s/config", withDefault(b.DiscoveryPrefix, DefaultDiscoveryPrefix), component, objectID)
+}
+
+// State returns the topic where a device's state is published
+func (b Builder) State(deviceID string) string {
+	return b.device(deviceID, "state")
+}
+
+// Command returns the topic where a device receives commands
+func (b Builder) Command(deviceID string) string {
+	return b.device(deviceID, "set")
+}
+
+// Availability returns the topic for a device's online/offline status
+func (b Builder) Availability(deviceID string) string {
+	return b.device(deviceID, "availability")
+}
+
+// Schedule returns the topic where a device's weekly schedule is published
+func (b Builder) Schedule(deviceID string) string {
+	return b.device(deviceID, "schedule")
+}
+
+// ScheduleSet returns the topic where schedule slots are added, updated or deleted
+func (b Builder) ScheduleSet(deviceID string) string {
+	return b.device(deviceID, "schedule/set")
+}
+
+// Timer returns the topic where a device's sleep timer is published
+func (b Builder) Timer(deviceID string) string {
+	return b.device(deviceID, "timer")
+}
+
+// TimerSet returns the topic where a device's sleep timer is set or cancelled
+func (b Builder) TimerSet(deviceID string) string {
+	return b.device(deviceID, "timer/set")
+}
+
+// CodeEvents returns the topic where IR code reloads are reported
+// e.g., "homeassistant/climate/codes/events"
+func (b Builder) CodeEvents() string {
+	return fmt.Sprintf("%s/codes/events", withDefault(b.BaseTopic, DefaultBaseTopic))
+}
+
+// device builds "<base>/<deviceID>/<action>"
+func (b Builder) device(deviceID, action string) string {
+	return fmt.Sprintf("%s/%s/%s", withDefault(b.BaseTopic, DefaultBaseTopic), deviceID, action)
+}
+
+// Z2MDevice returns the Zigbee2MQTT topic where a device publishes its state
+// Pass "+" to match every device whose friendly name has no "/" (see Z2MAll)
+func (b Builder) Z2MDevice(friendlyName string) string {
+	return fmt.Sprintf("%s/%s", withDefault(b.Z2MBaseTopic, DefaultZ2MBaseTopic), friendlyName)
+}
+
+// Z2MSet returns the Zigbee2MQTT topic used to send commands to a device
+func (b Builder) Z2MSet(friendlyName string) string {
+	return b.Z2MDevice(friendlyName) + "/set"
+}
+
+// Z2MAvailability returns the Zigbee2MQTT availability topic for a device
+func (b Builder) Z2MAvailability(friendlyName string) string {
+	return b.Z2MDevice(friendlyName) + "/availability"
+}
+
+// Z2MAll matches every Zigbee2MQTT topic; filter device topics with Z2MFriendlyName
+func (b Builder) Z2MAll() string {
+	return b.Z2MDevice("#")
+}
+
+// Z2MBridge returns a Zigbee2MQTT bridge topic, e.g., Z2MBridge("devices")
+func (b Builder) Z2MBridge(path string) string {
+	return b.Z2MDevice("bridge/" + path)
+}
+
+// z2mSubTopics name the topics Zigbee2MQTT nests below a device's own, e.g., "<name>/set/state"
+var z2mSubTopics = []string{"set", "get", "availability"}
+
+// Z2MFriendlyName extracts the device name from a Zigbee2MQTT device topic
+// Friendly names may contain "/" (e.g., "living/room_sensor")
+// Returns false if the topic is not below the Zigbee2MQTT base topic, or is a
+// bridge topic or a device's set, get or availability topic
+func (b Builder) Z2MFriendlyName(topic string) (string, bool) {
+	prefix := withDefault(b.Z2MBaseTopic, DefaultZ2MBaseTopic) + "/"
+	if !strings.HasPrefix(topic, prefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(topic, prefix)
+	if name == "" || strings.ContainsAny(name, "+#") {
+		return "", false
+	}
+	parts := strings.Split(name, "/")
+	if parts[0] == "bridge" {
+		return "", false
+	}
+	for _, part := range parts[1:] {
+		if slices.Contains(z2mSubTopics, part) {
+			return "", false
+		}
+	}
+	return name, true
+}
@@ -0,0 +1,101 @@
+package topics
+
+import "testing"
+
+func TestDefaultTopics(t *testing.T) {
+	b := Default()
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"Discovery config", b.DiscoveryConfig("climate", "living_room"), "homeassistant/climate/living_room/config"},
+		{"State", b.State("living_room"), "homeassistant/climate/living_room/state"},
+		{"Command", b.Command("living_room"), "homeassistant/climate/living_room/set"},
+		{"Availability", b.Availability("living_room"), "homeassistant/climate/living_room/availability"},
+		{"Schedule", b.Schedule("living_room"), "homeassistant/climate/living_room/schedule"},
+		{"Schedule set", b.ScheduleSet("living_room"), "homeassistant/climate/living_room/schedule/set"},
+		{"Timer", b.Timer("living_room"), "homeassistant/climate/living_room/timer"},
+		{"Timer set", b.TimerSet("living_room"), "homeassistant/climate/living_room/timer/set"},
+		{"Code events", b.CodeEvents(), "homeassistant/climate/codes/events"},
+		{"Z2M device", b.Z2MDevice("ir-blaster"), "zigbee2mqtt/ir-blaster"},
+		{"Z2M set", b.Z2MSet("ir-blaster"), "zigbee2mqtt/ir-blaster/set"},
+		{"Z2M availability", b.Z2MAvailability("ir-blaster"), "zigbee2mqtt/ir-blaster/availability"},
+		{"Z2M bridge", b.Z2MBridge("devices"), "zigbee2mqtt/bridge/devices"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomTopics(t *testing.T) {
+	b := New("ha-discovery/", "/home/zigbee", "hvac-manager")
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"Discovery config", b.DiscoveryConfig("climate", "bedroom"), "ha-discovery/climate/bedroom/config"},
+		{"State", b.State("bedroom"), "hvac-manager/bedroom/state"},
+		{"Command", b.Command("bedroom"), "hvac-manager/bedroom/set"},
+		{"Availability", b.Availability("bedroom"), "hvac-manager/bedroom/availability"},
+		{"Z2M set", b.Z2MSet("ir"), "home/zigbee/ir/set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZeroValueUsesDefaults(t *testing.T) {
+	var b Builder
+	if got := b.State("x"); got != Default().State("x") {
+		t.Errorf("zero value State() = %q, want %q", got, Default().State("x"))
+	}
+	if got := b.Z2MSet("x"); got != Default().Z2MSet("x") {
+		t.Errorf("zero value Z2MSet() = %q, want %q", got, Default().Z2MSet("x"))
+	}
+}
+
+func TestZ2MFriendlyName(t *testing.T) {
+	b := New("", "home/zigbee", "")
+
+	tests := []struct {
+		topic  string
+		want   string
+		wantOK bool
+	}{
+		{"home/zigbee/ir-blaster", "ir-blaster", true},
+		{"home/zigbee/living/room_sensor", "living/room_sensor", true},
+		{"home/zigbee/living/room_sensor/set", "", false},
+		{"home/zigbee/ir-blaster/set/state", "", false},
+		{"home/zigbee/ir-blaster/get", "", false},
+		{"home/zigbee/living/room_sensor/availability", "", false},
+		{"home/zigbee/bridge/devices", "", false},
+		{"home/zigbee/bridge", "", false},
+		{"home/zigbee/+", "", false},
+		{"home/zigbee/living/#", "", false},
+		{"zigbee2mqtt/ir-blaster", "", false},
+		{"home/zigbee/", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			got, ok := b.Z2MFriendlyName(tt.topic)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Z2MFriendlyName(%q) = (%q, %v), want (%q, %v)", tt.topic, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
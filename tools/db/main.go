@@ -4,11 +4,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/diogoaguiar/hvac-manager/internal/database"
+	"github.com/diogoaguiar/hvac-manager/internal/logger"
+	"github.com/diogoaguiar/hvac-manager/internal/lookup"
 )
 
 func main() {
@@ -22,8 +31,16 @@ func main() {
 	ctx := context.Background()
 
 	switch command {
+	case "search":
+		searchMirror(os.Args[2:]) // No database file
+	case "lint":
+		lintFile(os.Args[2], os.Args[3:]) // No database file
+	case "fetch":
+		fetchModels(ctx, dbPath, os.Args[3:])
 	case "init":
 		initDB(ctx, dbPath)
+	case "migrate":
+		migrateDB(ctx, dbPath, os.Args[3:])
 	case "load":
 		if len(os.Args) < 4 {
 			fmt.Println("Error: load command requires directory path")
@@ -38,8 +55,43 @@ func main() {
 			os.Exit(1)
 		}
 		loadSingleFile(ctx, dbPath, os.Args[3], os.Args[4])
+	case "reload":
+		if len(os.Args) < 5 {
+			fmt.Println("Error: reload command requires model ID and file path")
+			printUsage()
+			os.Exit(1)
+		}
+		reloadModel(ctx, dbPath, os.Args[3], os.Args[4])
+	case "remove":
+		if len(os.Args) < 4 {
+			fmt.Println("Error: remove command requires model ID")
+			printUsage()
+			os.Exit(1)
+		}
+		removeModel(ctx, dbPath, os.Args[3])
 	case "status":
 		statusDB(ctx, dbPath)
+	case "history":
+		if len(os.Args) < 4 {
+			fmt.Println("Error: history command requires device ID")
+			printUsage()
+			os.Exit(1)
+		}
+		historyDB(ctx, dbPath, os.Args[3], os.Args[4:])
+	case "coverage":
+		if len(os.Args) < 4 {
+			fmt.Println("Error: coverage command requires model ID")
+			printUsage()
+			os.Exit(1)
+		}
+		coverageDB(ctx, dbPath, os.Args[3], os.Args[4:])
+	case "lookup":
+		if len(os.Args) < 5 {
+			fmt.Println("Error: lookup command requires model ID and mode")
+			printUsage()
+			os.Exit(1)
+		}
+		lookupDB(ctx, dbPath, os.Args[3], os.Args[4], os.Args[5:])
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
@@ -52,11 +104,21 @@ func printUsage() {
 	fmt.Println("")
 	fmt.Println("Commands:")
 	fmt.Println("  init <db-file>                    - Initialize database schema")
+	fmt.Println("  migrate <db-file> [--dry-run]     - Apply pending schema migrations (or only list them)")
 	fmt.Println("  load <db-file> <dir>              - Load IR codes from directory")
 	fmt.Println("  load-single <db-file> <id> <file> - Load single SmartIR file with model ID")
+	fmt.Println("  reload <db-file> <id> <file>      - Replace a model with a SmartIR file (drops codes missing from it)")
+	fmt.Println("  remove <db-file> <id>             - Delete a model and its IR codes")
 	fmt.Println("  status <db-file>                  - Show database status")
+	fmt.Println("  history <db-file> <device> [-n N] - Show the last N commands of a device (default 20, 0 = all)")
+	fmt.Println("  lookup <db-file> <id> <mode> [temp] [fan] [-policy file] - Show which IR code a state resolves to (fan default auto)")
+	fmt.Println("  coverage <db-file> <id> [-policy file] [--json] - Show which states have an exact code, a fallback or none")
+	fmt.Println("  lint <file> [-strict] [-json]     - Validate a SmartIR file (exit 1 on errors, or on warnings with -strict)")
+	fmt.Println("  search <terms...> [-mirror path]  - Search a SmartIR mirror by manufacturer, model ID or supported model")
+	fmt.Println("  fetch <db-file> <id...> [-mirror path] [-dir dir] - Copy models from a SmartIR mirror to dir and load them")
 	fmt.Println("")
 	fmt.Println("The loader automatically detects and converts Broadlink format to Tuya.")
+	fmt.Println("A mirror is a local SmartIR checkout or tarball (default $SMARTIR_MIRROR); nothing is downloaded.")
 }
 
 func initDB(ctx context.Context, dbPath string) {
@@ -73,6 +135,48 @@ func initDB(ctx context.Context, dbPath string) {
 	fmt.Printf("✓ Database initialized: %s\n", dbPath)
 }
 
+func migrateDB(ctx context.Context, dbPath string, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list pending migrations without applying them")
+	flags.Parse(args)
+
+	db, err := database.New(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	version, err := db.GetSchemaVersion(ctx)
+	if err != nil {
+		log.Fatalf("Failed to check schema version: %v", err)
+	}
+	pending, err := db.PendingMigrations(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list migrations: %v", err)
+	}
+
+	fmt.Printf("Schema version: %d (current: %d)\n", version, database.CurrentSchemaVersion)
+	switch {
+	case version == 0:
+		fmt.Printf("  Uninitialized: schema v%d will be created\n", database.CurrentSchemaVersion)
+	case len(pending) == 0:
+		fmt.Println("✓ Up to date")
+		return
+	}
+	for _, m := range pending {
+		fmt.Printf("  %04d_%s (v%d → v%d)\n", m.Version, m.Name, m.Version-1, m.Version)
+	}
+	if *dryRun {
+		fmt.Println("Dry run: no changes made")
+		return
+	}
+
+	if err := db.Migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	fmt.Printf("✓ Database migrated to v%d\n", database.CurrentSchemaVersion)
+}
+
 func loadDB(ctx context.Context, dbPath, dirPath string) {
 	db, err := database.New(dbPath)
 	if err != nil {
@@ -120,6 +224,54 @@ func loadSingleFile(ctx context.Context, dbPath, modelID, filePath string) {
 	fmt.Printf("✓ Loaded model %s from %s\n", modelID, filePath)
 }
 
+func reloadModel(ctx context.Context, dbPath, modelID, filePath string) {
+	db, err := database.New(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	before, err := db.ListCodes(ctx, modelID)
+	if err != nil {
+		log.Fatalf("Failed to list codes: %v", err)
+	}
+	if err := db.ReplaceModel(ctx, modelID, filePath); err != nil {
+		log.Fatalf("Failed to reload model: %v", err)
+	}
+	after, err := db.ListCodes(ctx, modelID)
+	if err != nil {
+		log.Fatalf("Failed to list codes: %v", err)
+	}
+
+	fmt.Printf("✓ Reloaded model %s from %s (%d codes, previously %d)\n", modelID, filePath, len(after), len(before))
+}
+
+func removeModel(ctx context.Context, dbPath, modelID string) {
+	db, err := database.New(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	codes, err := db.ListCodes(ctx, modelID)
+	if err != nil {
+		log.Fatalf("Failed to list codes: %v", err)
+	}
+	if err := db.DeleteModel(ctx, modelID); err != nil {
+		log.Fatalf("Failed to remove model %s: %v", modelID, err)
+	}
+
+	fmt.Printf("✓ Removed model %s (%d codes)\n", modelID, len(codes))
+}
+
 func statusDB(ctx context.Context, dbPath string) {
 	db, err := database.New(dbPath)
 	if err != nil {
@@ -156,3 +308,383 @@ func statusDB(ctx context.Context, dbPath string) {
 		fmt.Printf("  - %s (%s, %d°C-%d°C)\n", modelID, model.Manufacturer, model.MinTemperature, model.MaxTemperature)
 	}
 }
+
+func historyDB(ctx context.Context, dbPath, deviceID string, args []string) {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	limit := flags.Int("n", 20, "number of records to show (0 = all)")
+	flags.Parse(args)
+
+	db, err := database.New(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	records, err := db.CommandHistory(ctx, deviceID, *limit)
+	if err != nil {
+		log.Fatalf("Failed to load history: %v", err)
+	}
+	if len(records) == 0 {
+		fmt.Printf("No commands recorded for %s\n", deviceID)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tSOURCE\tCOMMAND\tSTATE\tCODE\tSTRATEGY\tRESULT")
+	for _, r := range records {
+		result := "✓"
+		if !r.Success {
+			result = "✗ " + r.Error
+		}
+		state := fmt.Sprintf("%s %.1f°C %s", r.Mode, r.Temperature, r.FanMode)
+		if r.Preset != "" {
+			state += " [" + r.Preset + "]"
+		}
+		code := "-"
+		if r.IRCodeID != nil {
+			code = fmt.Sprintf("#%d", *r.IRCodeID)
+		}
+		strategy := r.Strategy
+		if strategy == "" {
+			strategy = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Time.Local().Format(time.DateTime), r.Source,
+			strings.ReplaceAll(r.Command, "\t", " "), state, code, strategy, result)
+	}
+	w.Flush()
+}
+
+func lookupDB(ctx context.Context, dbPath, modelID, mode string, args []string) {
+	// Positional temp/fan come before flags
+	flags := flag.NewFlagSet("lookup", flag.ExitOnError)
+	policyFile := flags.String("policy", "", "fallback policy file (see FALLBACK_POLICY_FILE)")
+	args = parseFlags(flags, args)
+
+	temp, fan := 0.0, "auto"
+	if mode != "off" {
+		if len(args) < 1 {
+			log.Fatalf("Mode %s requires a temperature", mode)
+		}
+		var err error
+		if temp, err = strconv.ParseFloat(args[0], 64); err != nil {
+			log.Fatalf("Invalid temperature: %q", args[0])
+		}
+		if len(args) > 1 {
+			fan = args[1]
+		}
+	}
+
+	db, err := database.New(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	if *policyFile != "" {
+		policies, err := database.LoadFallbackPolicies(*policyFile)
+		if err != nil {
+			log.Fatalf("Invalid fallback policies: %v", err)
+		}
+		for model, policy := range policies {
+			db.SetFallbackPolicy(model, policy)
+		}
+	}
+
+	var code string
+	var result lookup.Result
+	if mode == "off" {
+		fmt.Printf("Requested: model=%s mode=off\n", modelID)
+		code, result, err = db.LookupOffCode(ctx, modelID)
+	} else {
+		fmt.Printf("Requested: model=%s mode=%s temp=%g°C fan=%s\n", modelID, mode, temp, fan)
+		code, result, err = db.LookupCode(ctx, modelID, mode, temp, fan)
+	}
+	if err != nil {
+		fmt.Printf("Strategy:  %s\n", result.Strategy)
+		log.Fatalf("Lookup failed: %v", err)
+	}
+
+	matched := result.Mode
+	if result.Temperature != nil {
+		matched += fmt.Sprintf(" temp=%g°C", *result.Temperature)
+	}
+	if result.FanSpeed != nil {
+		matched += " fan=" + *result.FanSpeed
+	}
+	if result.Swing != nil {
+		matched += " swing=" + *result.Swing
+	}
+	fmt.Printf("Matched:   mode=%s (row #%d)\n", matched, result.CodeID)
+	fmt.Printf("Strategy:  %s", result.Strategy)
+	if result.Fallback() {
+		fmt.Print(" (fallback)")
+	}
+	fmt.Println()
+	fmt.Printf("IR code:   %s\n", code)
+}
+
+// parseFlags parses the flags following the positional arguments and returns the positional ones
+func parseFlags(flags *flag.FlagSet, args []string) []string {
+	positional := args
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			positional = args[:i]
+			break
+		}
+	}
+	flags.Parse(args[len(positional):])
+	return positional
+}
+
+// openMirror indexes the SmartIR mirror at path (SMARTIR_MIRROR if empty)
+func openMirror(path string) *database.Mirror {
+	if path == "" {
+		path = os.Getenv("SMARTIR_MIRROR")
+	}
+	if path == "" {
+		log.Fatalf("No SmartIR mirror: use -mirror or set SMARTIR_MIRROR to a checkout or tarball")
+	}
+
+	mirror, err := database.OpenMirror(path)
+	if err != nil {
+		log.Fatalf("Failed to open mirror: %v", err)
+	}
+	if mirror.Skipped > 0 {
+		fmt.Printf("(skipped %d unreadable files)\n", mirror.Skipped)
+	}
+	return mirror
+}
+
+func searchMirror(args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	mirrorPath := flags.String("mirror", "", "SmartIR checkout or tarball (default $SMARTIR_MIRROR)")
+	terms := parseFlags(flags, args)
+
+	mirror := openMirror(*mirrorPath)
+	matches := mirror.Search(terms...)
+	if len(matches) == 0 {
+		fmt.Printf("No models match %q in %s (%d indexed)\n", strings.Join(terms, " "), mirror.Root, len(mirror.Entries))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tMANUFACTURER\tSUPPORTED MODELS")
+	for _, entry := range matches {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", entry.ModelID, entry.Manufacturer, strings.Join(entry.SupportedModels, ", "))
+	}
+	w.Flush()
+	fmt.Printf("%d of %d models\n", len(matches), len(mirror.Entries))
+}
+
+func fetchModels(ctx context.Context, dbPath string, args []string) {
+	flags := flag.NewFlagSet("fetch", flag.ExitOnError)
+	mirrorPath := flags.String("mirror", "", "SmartIR checkout or tarball (default $SMARTIR_MIRROR)")
+	dir := flags.String("dir", filepath.Join("docs", "smartir", "reference"), "directory the files are copied to (see CODES_DIR)")
+	modelIDs := parseFlags(flags, args)
+	if len(modelIDs) == 0 {
+		log.Fatalf("fetch requires at least one model ID (find them with: db search <terms>)")
+	}
+
+	mirror := openMirror(*mirrorPath)
+
+	db, err := database.New(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	for _, modelID := range modelIDs {
+		path, err := mirror.Extract(modelID, *dir)
+		if err != nil {
+			log.Fatalf("Failed to fetch model %s: %v", modelID, err)
+		}
+		if err := db.LoadFromJSON(ctx, modelID, path); err != nil {
+			log.Fatalf("Failed to load %s: %v", path, err)
+		}
+		entry, _ := mirror.Entry(modelID)
+		fmt.Printf("✓ Fetched model %s (%s) to %s\n", modelID, entry.Manufacturer, path)
+	}
+}
+
+func lintFile(filePath string, args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	strict := flags.Bool("strict", false, "also fail on warnings")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	parseFlags(flags, args)
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		log.Fatalf("Failed to read file: %v", err)
+	}
+	report := database.ValidateSmartIR(data)
+	failed := report.Errors() > 0 || (*strict && report.Warnings() > 0)
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		printLintReport(filePath, report)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func printLintReport(filePath string, report *database.LintReport) {
+	fmt.Printf("%s: %d codes, %d errors, %d warnings\n", filePath, report.Codes, report.Errors(), report.Warnings())
+
+	if len(report.Issues) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\nSEVERITY\tKIND\tPATH\tMESSAGE")
+		for _, severity := range []string{database.SeverityError, database.SeverityWarning} {
+			for _, issue := range report.Issues {
+				if issue.Severity == severity {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Severity, issue.Kind, issue.Path, issue.Message)
+				}
+			}
+		}
+		w.Flush()
+	}
+
+	if len(report.Missing) > 0 {
+		fmt.Println("\nMissing combinations:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MODE\tFAN\tSWING\tTEMPERATURES")
+		for _, m := range report.Missing {
+			swing := m.Swing
+			if swing == "" {
+				swing = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.Mode, m.FanSpeed, swing, formatTemperatures(m.Temperatures))
+		}
+		w.Flush()
+	}
+
+	if report.Errors() == 0 {
+		fmt.Println("\n✓ No errors")
+	}
+}
+
+// formatTemperatures compacts consecutive whole degrees into ranges, e.g., "16-20, 32"
+func formatTemperatures(temps []float64) string {
+	var parts []string
+	for i := 0; i < len(temps); {
+		j := i
+		for j+1 < len(temps) && temps[j+1] == temps[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%g-%g", temps[i], temps[j]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%g", temps[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// coverageSymbols marks each cell of the coverage grid by lookup strategy
+var coverageSymbols = []struct{ strategy, symbol, meaning string }{
+	{lookup.StrategyExact, "#", "exact code"},
+	{lookup.StrategyFan, "f", "other fan speed"},
+	{lookup.StrategyModeTemp, "t", "same temperature, any fan"},
+	{lookup.StrategyNearestTemp, "n", "nearest temperature"},
+	{lookup.StrategyModeOnly, "m", "any code of the mode"},
+	{lookup.StrategyMiss, ".", "unreachable"},
+}
+
+func coverageDB(ctx context.Context, dbPath, modelID string, args []string) {
+	flags := flag.NewFlagSet("coverage", flag.ExitOnError)
+	policyFile := flags.String("policy", "", "fallback policy file (see FALLBACK_POLICY_FILE)")
+	asJSON := flags.Bool("json", false, "print every cell as JSON")
+	parseFlags(flags, args)
+
+	db, err := database.New(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	if *policyFile != "" {
+		policies, err := database.LoadFallbackPolicies(*policyFile)
+		if err != nil {
+			log.Fatalf("Invalid fallback policies: %v", err)
+		}
+		for model, policy := range policies {
+			db.SetFallbackPolicy(model, policy)
+		}
+	}
+
+	logger.SetLevel(logger.ERROR) // One lookup per cell
+	coverage, err := db.Coverage(ctx, modelID)
+	if err != nil {
+		log.Fatalf("Failed to compute coverage: %v", err)
+	}
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(coverage, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	symbols := make(map[string]string)
+	for _, s := range coverageSymbols {
+		symbols[s.strategy] = s.symbol
+	}
+
+	labelWidth := 0
+	for _, mode := range coverage.Modes {
+		for _, fan := range coverage.FanModes {
+			labelWidth = max(labelWidth, len(mode)+1+len(fan))
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "\t")
+	for _, temp := range coverage.Temperatures {
+		fmt.Fprintf(w, "%g\t", temp)
+	}
+	fmt.Fprintln(w)
+	for _, mode := range coverage.Modes {
+		for _, fan := range coverage.FanModes {
+			fmt.Fprintf(w, "%-*s\t", labelWidth, mode+"/"+fan)
+			for _, temp := range coverage.Temperatures {
+				cell, _ := coverage.Cell(mode, fan, temp)
+				fmt.Fprintf(w, "%s\t", symbols[cell.Result.Strategy])
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	w.Flush()
+
+	fmt.Println()
+	for _, s := range coverageSymbols {
+		fmt.Printf("  %s %s\n", s.symbol, s.meaning)
+	}
+	off := "yes"
+	if !coverage.Off {
+		off = "no (cannot turn off)"
+	}
+	on := "no"
+	if coverage.On {
+		on = "yes"
+	}
+	fmt.Printf("\n%d cells: %d exact, %d fallback, %d unreachable. Off code: %s. On code: %s\n",
+		len(coverage.Cells), coverage.Exact, coverage.Fallback, coverage.Unreachable, off, on)
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/diogoaguiar/hvac-manager/internal/mqtt"
+	"github.com/diogoaguiar/hvac-manager/internal/topics"
 )
 
 // Z2MDevice represents a Zigbee2MQTT device
@@ -48,6 +49,7 @@ func main() {
 	broker := getEnv("MQTT_BROKER", "tcp://localhost:1883")
 	username := getEnv("MQTT_USERNAME", "")
 	password := getEnv("MQTT_PASSWORD", "")
+	topicBuilder := topics.New("", getEnv("Z2M_BASE_TOPIC", topics.DefaultZ2MBaseTopic), "")
 
 	fmt.Printf("📡 Connecting to MQTT broker: %s\n", broker)
 
@@ -57,6 +59,12 @@ func main() {
 		ClientID: "hvac-discovery-tool",
 		Username: username,
 		Password: password,
+
+		CACertFile:         getEnv("MQTT_TLS_CA", ""),
+		ClientCertFile:     getEnv("MQTT_TLS_CERT", ""),
+		ClientKeyFile:      getEnv("MQTT_TLS_KEY", ""),
+		ServerName:         getEnv("MQTT_TLS_SERVER_NAME", ""),
+		InsecureSkipVerify: getEnv("MQTT_TLS_INSECURE", "false") == "true",
 	}
 
 	client, err := mqtt.NewClient(mqttConfig)
@@ -72,14 +80,14 @@ func main() {
 	fmt.Println("✅ Connected to broker")
 	fmt.Println("\n🔎 Scanning for Zigbee2MQTT devices...")
 	fmt.Println("   Listening on topics:")
-	fmt.Println("   - zigbee2mqtt/bridge/devices")
-	fmt.Println("   - zigbee2mqtt/+") // All device topics
+	fmt.Printf("   - %s\n", topicBuilder.Z2MBridge("devices"))
+	fmt.Printf("   - %s\n", topicBuilder.Z2MAll()) // All device topics, including names with "/"
 
 	devices := make(map[string]*Z2MDevice)
 	deviceChan := make(chan bool, 1)
 
 	// Subscribe to bridge devices topic
-	err = client.Subscribe("zigbee2mqtt/bridge/devices", 0, func(topic string, payload []byte) {
+	err = client.Subscribe(topicBuilder.Z2MBridge("devices"), 0, func(topic string, payload []byte) {
 		var deviceList Z2MBridgeDevices
 		if err := json.Unmarshal(payload, &deviceList); err != nil {
 			log.Printf("⚠️  Failed to parse bridge devices: %v", err)
@@ -98,16 +106,10 @@ func main() {
 	}
 
 	// Also listen to individual device topics to catch any active devices
-	err = client.Subscribe("zigbee2mqtt/+", 0, func(topic string, payload []byte) {
-		// Extract device name from topic
-		parts := strings.Split(topic, "/")
-		if len(parts) < 2 {
-			return
-		}
-		deviceName := parts[1]
-
-		// Skip bridge topics
-		if strings.HasPrefix(deviceName, "bridge") {
+	err = client.Subscribe(topicBuilder.Z2MAll(), 0, func(topic string, payload []byte) {
+		// Extract device name from topic, skipping bridge and set/get/availability topics
+		deviceName, ok := topicBuilder.Z2MFriendlyName(topic)
+		if !ok {
 			return
 		}
 
@@ -131,7 +133,7 @@ func main() {
 
 	// Request bridge info
 	fmt.Println("\n📤 Requesting device list from Zigbee2MQTT bridge...")
-	if err := client.Publish("zigbee2mqtt/bridge/request/devices", 0, false, ""); err != nil {
+	if err := client.Publish(topicBuilder.Z2MBridge("request/devices"), 0, false, ""); err != nil {
 		log.Printf("⚠️  Failed to request devices: %v", err)
 	}
 